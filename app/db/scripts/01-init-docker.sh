@@ -54,6 +54,17 @@ psql -v ON_ERROR_STOP=1 --username "$POSTGRES_USER" --dbname "$POSTGRES_DB" <<-E
     GRANT USAGE ON SCHEMA public TO feedback_analysis_app;
 
     -- Note: Table-specific permissions will be granted by migrations after tables are created
+
+    -- ============================================================================
+    -- 4. Create feedback_retention_app user (for retention job)
+    -- ============================================================================
+    CREATE USER $DB_USER_RETENTION_NAME WITH PASSWORD '$DB_USER_RETENTION_PASSWORD';
+
+    -- Grant connection and schema usage
+    GRANT CONNECT ON DATABASE $POSTGRES_DB TO feedback_retention_app;
+    GRANT USAGE ON SCHEMA public TO feedback_retention_app;
+
+    -- Note: Table-specific permissions will be granted by migrations after tables are created
 EOSQL
 
 echo ""
@@ -61,6 +72,7 @@ echo " Database users created successfully:"
 echo "  - migration_user (DDL permissions for schema migrations)"
 echo "  - web_app (will get RW on feedback table)"
 echo "  - feedback_analysis_app (will get RO on feedback, RW on report_runs)"
+echo "  - feedback_retention_app (will get SELECT/DELETE on feedback, RO on report_runs)"
 echo ""
 echo "Next steps:"
 echo "  1. The 'migration' container will run dbmate migrations"
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"fmt"
 	"log/slog"
+	"os"
 
 	"github.com/findmyname666/ddg3/feedback/pkgs/analysis"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/urfave/cli/v3"
 )
 
@@ -12,22 +16,152 @@ func analysisCommand() *cli.Command {
 	// Analysis-specific flags
 	analysisFlags := []cli.Flag{
 		&cli.StringFlag{
-			Name:     "asana-token",
-			Usage:    "Asana API token",
-			Sources:  cli.EnvVars("ASANA_TOKEN"),
-			Required: true,
+			Name:    "asana-token",
+			Usage:   "Asana API token (required)",
+			Sources: cli.EnvVars("ASANA_TOKEN"),
 		},
 		&cli.StringFlag{
-			Name:     "asana-workspace-gid",
-			Usage:    "Asana workspace GID",
-			Sources:  cli.EnvVars("ASANA_WORKSPACE_GID"),
-			Required: true,
+			Name:    "asana-workspace-gid",
+			Usage:   "Asana workspace GID (required)",
+			Sources: cli.EnvVars("ASANA_WORKSPACE_GID"),
 		},
 		&cli.StringFlag{
-			Name:     "asana-project-gid",
-			Usage:    "Asana project GID",
-			Sources:  cli.EnvVars("ASANA_PROJECT_GID"),
-			Required: true,
+			Name:    "asana-project-gid",
+			Usage:   "Asana project GID (required)",
+			Sources: cli.EnvVars("ASANA_PROJECT_GID"),
+		},
+		&cli.StringSliceFlag{
+			Name: "asana-custom-fields",
+			Usage: "Asana number custom fields filled with report values as metric=field_gid " +
+				"(metrics: positive, negative, total, negative_percent)",
+			Sources: cli.EnvVars("ASANA_CUSTOM_FIELDS"),
+		},
+		&cli.StringFlag{
+			Name:    "asana-section-gid",
+			Usage:   "Asana section GID of the project to place the task in (optional)",
+			Sources: cli.EnvVars("ASANA_SECTION_GID"),
+		},
+		&cli.StringSliceFlag{
+			Name:    "asana-assignees",
+			Usage:   "On-call rotation of Asana assignees (user GIDs or emails), one assignee per day (optional)",
+			Sources: cli.EnvVars("ASANA_ASSIGNEES"),
+		},
+		&cli.IntFlag{
+			Name:    "asana-due-days",
+			Usage:   "Number of days after the report date the task is due (0 sets no due date)",
+			Sources: cli.EnvVars("ASANA_DUE_DAYS"),
+		},
+		&cli.StringFlag{
+			Name:    "asana-notes-template",
+			Usage:   "Path to a text/template file rendering the task notes (default: built-in notes)",
+			Sources: cli.EnvVars("ASANA_NOTES_TEMPLATE"),
+		},
+		&cli.StringFlag{
+			Name:    "asana-html-notes-template",
+			Usage:   "Path to an html/template file rendering the rich text task notes (html_notes)",
+			Sources: cli.EnvVars("ASANA_HTML_NOTES_TEMPLATE"),
+		},
+		&cli.BoolFlag{
+			Name:  "render-only",
+			Usage: "Render the task notes of a sample report to stdout and exit without connecting to the database",
+		},
+		&cli.BoolFlag{
+			Name:  "dry-run",
+			Usage: "Print the Asana task of the current window to stdout without calling Asana or writing to the database",
+		},
+		&cli.StringFlag{
+			Name:  "dry-run-format",
+			Usage: "Output format of --dry-run: text or json",
+			Value: "text",
+		},
+		&cli.DurationFlag{
+			Name: "lock-wait",
+			Usage: "How long to wait for another analysis run to finish " +
+				"(0 exits immediately with exit code 75)",
+			Sources: cli.EnvVars("ANALYSIS_LOCK_WAIT"),
+		},
+		&cli.StringFlag{
+			Name:    "asana-attachment-format",
+			Usage:   "Format of the feedback export attached to the Asana task: csv, jsonl, parquet or none",
+			Value:   string(analysis.ExportFormatCSV),
+			Sources: cli.EnvVars("ASANA_ATTACHMENT_FORMAT"),
+		},
+		&cli.StringFlag{
+			Name:    "slack-webhook-url",
+			Usage:   "Slack or Mattermost incoming webhook URL for posting the daily summary (optional)",
+			Sources: cli.EnvVars("SLACK_WEBHOOK_URL"),
+		},
+		&cli.FloatFlag{
+			Name:    "anomaly-threshold",
+			Usage:   "Z-score above which a deviation from the 7/28-day baseline is flagged as anomaly",
+			Value:   analysis.DefaultAnomalyThreshold,
+			Sources: cli.EnvVars("ANOMALY_THRESHOLD"),
+		},
+		&cli.IntFlag{
+			Name:    "sample-size",
+			Usage:   "Number of negative and positive messages quoted in the report (0 disables samples)",
+			Value:   analysis.DefaultSampleSize,
+			Sources: cli.EnvVars("SAMPLE_SIZE"),
+		},
+		&cli.IntFlag{
+			Name:    "top-terms",
+			Usage:   "Number of emerging terms reported per sentiment (0 disables keyword extraction)",
+			Value:   analysis.DefaultTopTerms,
+			Sources: cli.EnvVars("TOP_TERMS"),
+		},
+		&cli.IntFlag{
+			Name:    "keyword-baseline-days",
+			Usage:   "Number of days of feedback the day's terms are compared with",
+			Value:   analysis.DefaultKeywordBaselineDays,
+			Sources: cli.EnvVars("KEYWORD_BASELINE_DAYS"),
+		},
+		&cli.IntFlag{
+			Name:    "top-clusters",
+			Usage:   "Number of near-duplicate message clusters reported (0 disables clustering)",
+			Value:   analysis.DefaultTopClusters,
+			Sources: cli.EnvVars("TOP_CLUSTERS"),
+		},
+		&cli.BoolFlag{
+			Name:    "sentiment-check",
+			Usage:   "Score the message text and report messages whose rating contradicts it",
+			Value:   true,
+			Sources: cli.EnvVars("SENTIMENT_CHECK"),
+		},
+		&cli.BoolFlag{
+			Name:    "persist-text-score",
+			Usage:   "Store the text sentiment score of each message in feedback.text_score",
+			Sources: cli.EnvVars("PERSIST_TEXT_SCORE"),
+		},
+		&cli.StringFlag{
+			Name:    "smtp-host",
+			Usage:   "SMTP server host for the email digest (optional, digest is disabled when empty)",
+			Sources: cli.EnvVars("SMTP_HOST"),
+		},
+		&cli.IntFlag{
+			Name:    "smtp-port",
+			Usage:   "SMTP server port (STARTTLS is required)",
+			Value:   587,
+			Sources: cli.EnvVars("SMTP_PORT"),
+		},
+		&cli.StringFlag{
+			Name:    "smtp-username",
+			Usage:   "SMTP username",
+			Sources: cli.EnvVars("SMTP_USERNAME"),
+		},
+		&cli.StringFlag{
+			Name:    "smtp-password",
+			Usage:   "SMTP password",
+			Sources: cli.EnvVars("SMTP_PASSWORD"),
+		},
+		&cli.StringFlag{
+			Name:    "email-from",
+			Usage:   "Sender address of the email digest (e.g., 'FeedDuck <reports@example.com>')",
+			Sources: cli.EnvVars("EMAIL_FROM"),
+		},
+		&cli.StringSliceFlag{
+			Name:    "email-to",
+			Usage:   "Recipient addresses of the email digest (comma-separated in env var)",
+			Sources: cli.EnvVars("EMAIL_TO"),
 		},
 	}
 
@@ -39,10 +173,136 @@ func analysisCommand() *cli.Command {
 		Usage:  "Run the feedback analysis job",
 		Flags:  analysisFlags,
 		Action: runAnalysis,
+		Commands: []*cli.Command{
+			reconcileCommand(),
+			schedulerCommand(),
+			rollupCommand(),
+		},
+	}
+}
+
+// newAggregator creates an aggregator from the analysis CLI flags, replica is optional
+func newAggregator(cmd *cli.Command, pool, replica *pgxpool.Pool) (*analysis.Aggregator, error) {
+	// Checked here instead of marking the flags as required so that --render-only and --dry-run work without them
+	for _, name := range []string{"asana-token", "asana-workspace-gid", "asana-project-gid"} {
+		if cmd.String(name) == "" && !cmd.Bool("dry-run") {
+			return nil, fmt.Errorf("%s is required", name)
+		}
+	}
+
+	// Validate email digest configuration
+	if cmd.String("smtp-host") != "" && (cmd.String("email-from") == "" || len(cmd.StringSlice("email-to")) == 0) {
+		return nil, fmt.Errorf("email-from and email-to are required when smtp-host is set")
+	}
+
+	if cmd.Int("sample-size") < 0 || cmd.Int("top-terms") < 0 || cmd.Int("top-clusters") < 0 {
+		return nil, fmt.Errorf("sample-size, top-terms and top-clusters must not be negative")
+	}
+
+	if cmd.Bool("persist-text-score") && !cmd.Bool("sentiment-check") {
+		return nil, fmt.Errorf("persist-text-score requires sentiment-check")
+	}
+
+	if cmd.Duration("lock-wait") < 0 {
+		return nil, fmt.Errorf("lock-wait must not be negative")
+	}
+
+	var attachmentFormat analysis.ExportFormat
+
+	if format := cmd.String("asana-attachment-format"); format != "" && format != "none" {
+		parsed, err := analysis.ParseExportFormat(format)
+		if err != nil {
+			return nil, fmt.Errorf("invalid asana-attachment-format: %w", err)
+		}
+
+		attachmentFormat = parsed
+	}
+
+	customFields, err := analysis.ParseCustomFields(cmd.StringSlice("asana-custom-fields"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid asana-custom-fields: %w", err)
+	}
+
+	if cmd.Int("asana-due-days") < 0 {
+		return nil, fmt.Errorf("asana-due-days must not be negative")
+	}
+
+	notesTemplate, err := loadNotesTemplate(cmd)
+	if err != nil {
+		return nil, err
 	}
+
+	return analysis.NewAggregator(analysis.Config{
+		Pool:              pool,
+		ReplicaPool:       replica,
+		MaxReplicaLag:     cmd.Duration("db-replica-max-lag"),
+		AsanaToken:        cmd.String("asana-token"),
+		AsanaWorkspaceGID: cmd.String("asana-workspace-gid"),
+		AsanaProjectGID:   cmd.String("asana-project-gid"),
+		AsanaTask: analysis.AsanaTaskOptions{
+			CustomFields:  customFields,
+			SectionGID:    cmd.String("asana-section-gid"),
+			Assignees:     cmd.StringSlice("asana-assignees"),
+			DueDays:       cmd.Int("asana-due-days"),
+			NotesTemplate: notesTemplate,
+		},
+		SlackWebhookURL:     cmd.String("slack-webhook-url"),
+		AnomalyThreshold:    cmd.Float("anomaly-threshold"),
+		SampleSize:          cmd.Int("sample-size"),
+		TopTerms:            cmd.Int("top-terms"),
+		KeywordBaselineDays: cmd.Int("keyword-baseline-days"),
+		TopClusters:         cmd.Int("top-clusters"),
+		SentimentCheck:      cmd.Bool("sentiment-check"),
+		PersistTextScores:   cmd.Bool("persist-text-score"),
+		AttachmentFormat:    attachmentFormat,
+		LockWait:            cmd.Duration("lock-wait"),
+		SMTP: analysis.SMTPConfig{
+			Host:     cmd.String("smtp-host"),
+			Port:     cmd.Int("smtp-port"),
+			Username: cmd.String("smtp-username"),
+			Password: cmd.String("smtp-password"),
+			From:     cmd.String("email-from"),
+			To:       cmd.StringSlice("email-to"),
+		},
+	}), nil
+}
+
+// loadNotesTemplate loads and validates the configured notes template, nil means the built-in notes
+func loadNotesTemplate(cmd *cli.Command) (*analysis.NotesTemplate, error) {
+	textPath := cmd.String("asana-notes-template")
+	htmlPath := cmd.String("asana-html-notes-template")
+
+	switch {
+	case textPath != "" && htmlPath != "":
+		return nil, fmt.Errorf("asana-notes-template and asana-html-notes-template are mutually exclusive")
+	case textPath != "":
+		return analysis.LoadNotesTemplate(textPath)
+	case htmlPath != "":
+		return analysis.LoadHTMLNotesTemplate(htmlPath)
+	default:
+		return nil, nil
+	}
+}
+
+// renderNotesPreview prints the task notes of a sample report
+func renderNotesPreview(cmd *cli.Command) error {
+	notesTemplate, err := loadNotesTemplate(cmd)
+	if err != nil {
+		return err
+	}
+
+	return analysis.RenderNotesPreview(os.Stdout, notesTemplate)
 }
 
 func runAnalysis(ctx context.Context, cmd *cli.Command) error {
+	if cmd.Bool("render-only") {
+		return renderNotesPreview(cmd)
+	}
+
+	if cmd.Bool("dry-run") {
+		return runDryRun(ctx, cmd)
+	}
+
 	slog.Info("Starting feedback analysis job...")
 
 	// Get database pool
@@ -52,18 +312,36 @@ func runAnalysis(ctx context.Context, cmd *cli.Command) error {
 	}
 	defer pool.Close()
 
+	replica, err := getDBReplicaPool(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	if replica != nil {
+		defer replica.Close()
+	}
+
 	// Create aggregator
-	aggregator := analysis.NewAggregator(analysis.Config{
-		Pool:              pool,
-		AsanaToken:        cmd.String("asana-token"),
-		AsanaWorkspaceGID: cmd.String("asana-workspace-gid"),
-		AsanaProjectGID:   cmd.String("asana-project-gid"),
-	})
+	aggregator, err := newAggregator(cmd, pool, replica)
+	if err != nil {
+		return err
+	}
 
 	slog.Info("Analysis job configuration",
 		"db_user", cmd.String("db-user"),
 		"asana_workspace", cmd.String("asana-workspace-gid"),
-		"asana_project", cmd.String("asana-project-gid"))
+		"asana_project", cmd.String("asana-project-gid"),
+		"asana_section", cmd.String("asana-section-gid"),
+		"asana_custom_fields", len(cmd.StringSlice("asana-custom-fields")),
+		"asana_assignees", len(cmd.StringSlice("asana-assignees")),
+		"slack_enabled", cmd.String("slack-webhook-url") != "",
+		"email_recipients", len(cmd.StringSlice("email-to")),
+		"sample_size", cmd.Int("sample-size"),
+		"top_terms", cmd.Int("top-terms"),
+		"top_clusters", cmd.Int("top-clusters"),
+		"attachment_format", cmd.String("asana-attachment-format"),
+		"notes_template", cmp.Or(cmd.String("asana-notes-template"), cmd.String("asana-html-notes-template"), "built-in"),
+		"sentiment_check", cmd.Bool("sentiment-check"))
 
 	// Run aggregation
 	if err := aggregator.Run(ctx); err != nil {
@@ -74,3 +352,37 @@ func runAnalysis(ctx context.Context, cmd *cli.Command) error {
 
 	return nil
 }
+
+// runDryRun prints the Asana task the analysis job would create for the current window.
+// Logs are written to stderr to keep stdout parseable.
+func runDryRun(ctx context.Context, cmd *cli.Command) error {
+	setupLogging(os.Stderr, cmd.Bool("debug"))
+
+	format, err := analysis.ParseDryRunFormat(cmd.String("dry-run-format"))
+	if err != nil {
+		return fmt.Errorf("invalid dry-run-format: %w", err)
+	}
+
+	// Get database pool
+	pool, err := getDBPool(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	replica, err := getDBReplicaPool(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	if replica != nil {
+		defer replica.Close()
+	}
+
+	aggregator, err := newAggregator(cmd, pool, replica)
+	if err != nil {
+		return err
+	}
+
+	return aggregator.DryRun(ctx, os.Stdout, format)
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/analysis"
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/urfave/cli/v3"
+)
+
+var exportCommandDescription = `Export feedback or report runs for analysts.
+
+Rows are streamed from the database to the output as they're read, so exports
+of any size run in constant memory. All rows come from a single read-only
+snapshot. The output file is written to a temporary file next to it and
+renamed once the export is complete, a failed export leaves no partial file.
+
+Feedback is exported with id, created_at, sentiment and message, report runs
+(--reports) with all columns of report_runs. --from and --to select the
+created_at range of feedback and the report_date range of report runs.
+
+Parquet files are compressed with zstd and can't be gzipped.
+
+Examples:
+  # Feedback of March 2026 as CSV
+  feedback export --from=2026-03-01 --to=2026-04-01 --out=feedback-2026-03.csv
+
+  # All feedback since the start of the year as gzipped JSONL to stdout
+  feedback export --from=2026-01-01 --format=jsonl --gzip > feedback.jsonl.gz
+
+  # Report runs of 2025 as Parquet
+  feedback export --reports --from=2025-01-01 --to=2026-01-01 --format=parquet --out=reports-2025.parquet
+`
+
+func exportCommand() *cli.Command {
+	// Export-specific flags
+	exportFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:     "from",
+			Usage:    "Start of the exported range (inclusive), date (2006-01-02) or RFC 3339 time",
+			Required: true,
+		},
+		&cli.StringFlag{
+			Name:  "to",
+			Usage: "End of the exported range (exclusive), date (2006-01-02) or RFC 3339 time, defaults to now",
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "Output format (csv, jsonl, parquet)",
+			Value: string(analysis.ExportFormatCSV),
+		},
+		&cli.StringFlag{
+			Name:  "out",
+			Usage: "Output file, - for stdout",
+			Value: "-",
+		},
+		&cli.BoolFlag{
+			Name:  "gzip",
+			Usage: "Compress the output with gzip (csv and jsonl only)",
+		},
+		&cli.BoolFlag{
+			Name:  "reports",
+			Usage: "Export report runs instead of feedback",
+		},
+	}
+
+	// Combine shared database flags with export-specific flags
+	exportFlags = append(dbFlags("feedback_analysis_app", "dev_analysis_password"), exportFlags...)
+
+	return &cli.Command{
+		Name:        "export",
+		Usage:       "Export feedback or report runs as CSV, JSONL or Parquet",
+		Description: exportCommandDescription,
+		Flags:       exportFlags,
+		Action:      runExport,
+	}
+}
+
+// runExport streams the selected rows to the output, logs are written to stderr to keep stdout for the export
+func runExport(ctx context.Context, cmd *cli.Command) error {
+	setupLogging(os.Stderr, cmd.Bool("debug"))
+
+	format, err := analysis.ParseExportFormat(cmd.String("format"))
+	if err != nil {
+		return fmt.Errorf("invalid format: %w", err)
+	}
+
+	if cmd.Bool("gzip") && format == analysis.ExportFormatParquet {
+		return fmt.Errorf("--gzip can't be used with parquet, parquet files are compressed with zstd")
+	}
+
+	from, err := parseExportTime(cmd.String("from"))
+	if err != nil {
+		return fmt.Errorf("invalid from: %w", err)
+	}
+
+	to := time.Now().UTC()
+	if value := cmd.String("to"); value != "" {
+		if to, err = parseExportTime(value); err != nil {
+			return fmt.Errorf("invalid to: %w", err)
+		}
+	}
+
+	if !from.Before(to) {
+		return fmt.Errorf("from must be before to")
+	}
+
+	// Get database pool
+	pool, err := getDBPool(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	// A single snapshot keeps the export consistent, read-only guards against any modification
+	tx, err := pool.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			slog.Warn("Failed to rollback transaction", "error", err)
+		}
+	}()
+
+	out, err := openExportOutput(cmd.String("out"), cmd.Bool("gzip"))
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Starting export...",
+		"reports", cmd.Bool("reports"),
+		"from", from.Format(time.RFC3339),
+		"to", to.Format(time.RFC3339),
+		"format", format,
+		"gzip", cmd.Bool("gzip"),
+		"out", cmd.String("out"))
+
+	queries := db.New(tx)
+
+	var rows int64
+
+	if cmd.Bool("reports") {
+		rows, err = exportReportRuns(ctx, queries, out, format, from, to)
+	} else {
+		rows, err = exportFeedback(ctx, queries, out, format, from, to)
+	}
+
+	if err != nil {
+		out.Abort()
+
+		return err
+	}
+
+	if err := out.Close(); err != nil {
+		out.Abort()
+
+		return err
+	}
+
+	slog.Info("Export completed", "rows", rows, "out", cmd.String("out"))
+
+	return nil
+}
+
+// exportFeedback writes the feedback created in [from, to) and returns the number of rows
+func exportFeedback(
+	ctx context.Context,
+	queries *db.Queries,
+	w io.Writer,
+	format analysis.ExportFormat,
+	from, to time.Time,
+) (int64, error) {
+	writer, err := analysis.NewFeedbackWriter(w, format)
+	if err != nil {
+		return 0, err
+	}
+
+	var rows int64
+
+	err = queries.StreamExportFeedback(ctx, db.ExportFeedbackParams{
+		RangeStart: pgtype.Timestamptz{Time: from, Valid: true},
+		RangeEnd:   pgtype.Timestamptz{Time: to, Valid: true},
+	}, func(feedback *db.ExportFeedbackRow) error {
+		rows++
+
+		return writer.Write(feedback)
+	})
+	if err != nil {
+		return rows, fmt.Errorf("failed to export feedback: %w", err)
+	}
+
+	return rows, writer.Close()
+}
+
+// exportReportRuns writes the report runs with a report date in [from, to) and returns the number of rows
+func exportReportRuns(
+	ctx context.Context,
+	queries *db.Queries,
+	w io.Writer,
+	format analysis.ExportFormat,
+	from, to time.Time,
+) (int64, error) {
+	writer, err := analysis.NewReportRunWriter(w, format)
+	if err != nil {
+		return 0, err
+	}
+
+	var rows int64
+
+	err = queries.StreamExportReportRuns(ctx, db.ExportReportRunsParams{
+		RangeStart: pgtype.Date{Time: from, Valid: true},
+		RangeEnd:   pgtype.Date{Time: to, Valid: true},
+	}, func(run *db.ReportRun) error {
+		rows++
+
+		return writer.Write(run)
+	})
+	if err != nil {
+		return rows, fmt.Errorf("failed to export report runs: %w", err)
+	}
+
+	return rows, writer.Close()
+}
+
+// parseExportTime parses a date (2006-01-02, midnight UTC) or an RFC 3339 timestamp
+func parseExportTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a date (2006-01-02) or RFC 3339 time, got %q", value)
+	}
+
+	return t.UTC(), nil
+}
+
+// exportOutput is the buffered and optionally gzipped destination of an export.
+// Files are written to a temporary file that Close renames to the final path.
+type exportOutput struct {
+	w    io.Writer
+	buf  *bufio.Writer
+	gzip *gzip.Writer
+	file *os.File
+	path string
+}
+
+// openExportOutput opens the output, path - is stdout
+func openExportOutput(path string, compress bool) (*exportOutput, error) {
+	out := &exportOutput{path: path}
+
+	var dst io.Writer = os.Stdout
+
+	if path != "-" {
+		// Exports contain feedback messages, CreateTemp creates the file readable by the owner only (0600)
+		file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create output file: %w", err)
+		}
+
+		out.file = file
+		dst = file
+	}
+
+	out.buf = bufio.NewWriterSize(dst, 64*1024)
+	out.w = out.buf
+
+	if compress {
+		out.gzip = gzip.NewWriter(out.buf)
+		out.w = out.gzip
+	}
+
+	return out, nil
+}
+
+// Write writes to the output
+func (o *exportOutput) Write(p []byte) (int, error) {
+	return o.w.Write(p)
+}
+
+// Close flushes the output and moves the file to its final path
+func (o *exportOutput) Close() error {
+	if o.gzip != nil {
+		if err := o.gzip.Close(); err != nil {
+			return fmt.Errorf("failed to finish gzip stream: %w", err)
+		}
+	}
+
+	if err := o.buf.Flush(); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+
+	if o.file == nil {
+		return nil
+	}
+
+	if err := o.file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync output file: %w", err)
+	}
+
+	if err := o.file.Close(); err != nil {
+		return fmt.Errorf("failed to close output file: %w", err)
+	}
+
+	if err := os.Rename(o.file.Name(), o.path); err != nil {
+		return fmt.Errorf("failed to move output file to %s: %w", o.path, err)
+	}
+
+	return nil
+}
+
+// Abort removes the temporary file of a failed export
+func (o *exportOutput) Abort() {
+	if o.file == nil {
+		return
+	}
+
+	// The file may already be closed by Close
+	_ = o.file.Close()
+
+	if err := os.Remove(o.file.Name()); err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("Failed to remove temporary output file", "path", o.file.Name(), "error", err)
+	}
+}
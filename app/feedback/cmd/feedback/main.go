@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 	"os"
 
+	"github.com/findmyname666/ddg3/feedback/pkgs/analysis"
 	"github.com/urfave/cli/v3"
 )
 
+// exitCodeRunInProgress is returned when another analysis run holds the report lock (EX_TEMPFAIL)
+const exitCodeRunInProgress = 75
+
 func main() {
 	cmd := &cli.Command{
 		Name:  "feedback",
@@ -21,18 +26,35 @@ func main() {
 			},
 		},
 		Before: func(ctx context.Context, cmd *cli.Command) (context.Context, error) {
-			setupLogging(cmd.Bool("debug"))
+			setupLogging(os.Stdout, cmd.Bool("debug"))
 			return ctx, nil
 		},
 		Commands: []*cli.Command{
 			webCommand(),
 			analysisCommand(),
 			migrateCommand(),
+			retentionCommand(),
+			partitionsCommand(),
+			searchCommand(),
+			exportCommand(),
 		},
 	}
 
 	if err := cmd.Run(context.Background(), os.Args); err != nil {
-		slog.Error("an irrecoverable error occurred", "err", err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
+
+// exitCode logs the error of the command and returns the exit code of the process
+func exitCode(err error) int {
+	// Not a failure, the other run creates the report
+	if errors.Is(err, analysis.ErrRunInProgress) {
+		slog.Warn("Exiting, another run is creating the report", "err", err)
+
+		return exitCodeRunInProgress
+	}
+
+	slog.Error("an irrecoverable error occurred", "err", err)
+
+	return 1
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/analysis"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{
+			name: "run in progress",
+			err:  analysis.ErrRunInProgress,
+			want: exitCodeRunInProgress,
+		},
+		{
+			name: "wrapped run in progress",
+			err:  fmt.Errorf("scheduled run: %w", analysis.ErrRunInProgress),
+			want: exitCodeRunInProgress,
+		},
+		{
+			name: "other error",
+			err:  errors.New("connection refused"),
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exitCode(tt.err); got != tt.want {
+				t.Errorf("Expected exit code %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
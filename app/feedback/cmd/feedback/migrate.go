@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"net/url"
 	"path/filepath"
 
 	"github.com/amacneil/dbmate/v2/pkg/dbmate"
@@ -33,6 +32,10 @@ Examples:
   export DB_PASSWORD=secure_password
   feedback migrate
 
+  # Run migrations with a database URL and the password in a file
+  export DATABASE_URL="postgres://migration_user@prod-db.example.com:5432/feedback?sslmode=verify-full"
+  feedback migrate --db-password-file=/run/secrets/db_password
+
   # Specify custom migrations directory
   feedback migrate --migrations-dir=/path/to/migrations
 `
@@ -69,25 +72,14 @@ func migrateCommand() *cli.Command {
 func runMigrate(ctx context.Context, cmd *cli.Command) error {
 	slog.Info("Starting database migration...")
 
-	// Build database URL with properly encoded credentials
-	// URL-encode username and password to handle special characters
-	dbUser := url.QueryEscape(cmd.String("db-user"))
-	dbPassword := url.QueryEscape(cmd.String("db-password"))
-
-	urlStr := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
-		dbUser,
-		dbPassword,
-		cmd.String("db-host"),
-		cmd.Int("db-port"),
-		cmd.String("db-database"),
-		cmd.String("db-sslmode"),
-	)
-
-	dbURL, err := url.Parse(urlStr)
+	// Build database URL with properly encoded credentials, same precedence as the pool of other commands
+	dbURL, err := dbConfig(cmd).ConnURL()
 	if err != nil {
-		return fmt.Errorf("failed to parse database URL: %w", err)
+		return err
 	}
 
+	slog.Info("Connecting to database...", "url", dbURL.Redacted())
+
 	// Create dbmate instance
 	db := dbmate.New(dbURL)
 
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/partition"
+	"github.com/findmyname666/ddg3/feedback/pkgs/retention"
+	"github.com/urfave/cli/v3"
+)
+
+var maintainCommandDescription = `Create future partitions of the feedback table and drop expired ones.
+
+The feedback table is range-partitioned by month on created_at, partitions are
+named feedback_yYYYYmMM and cover UTC months. Inserts fail when no partition
+covers the current time, run this command at least monthly (e.g., daily).
+
+This command will:
+  1. Create the partitions of the current and the next --premake months
+  2. With --older-than, detach partitions that ended before now minus the
+     retention period (concurrently, without blocking queries) and drop them,
+     also expired partitions detached by an earlier run but not dropped
+  3. With --keep-detached, keep the detached partitions as standalone tables
+     instead, e.g., to archive them. Later runs don't drop them either
+
+Partitions containing feedback from periods without a completed report run are
+kept and the command exits with an error.
+
+The command must run as the owner of the feedback table (migration_user).
+
+Examples:
+  # Create missing partitions
+  feedback partitions maintain
+
+  # Show what would be detached after 180 days
+  feedback partitions maintain --older-than 180d --dry-run
+
+  # Drop partitions older than 180 days
+  feedback partitions maintain --older-than 180d
+
+  # Detach partitions older than 180 days for archiving
+  feedback partitions maintain --older-than 180d --keep-detached
+`
+
+func partitionsCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "partitions",
+		Usage: "Manage the partitions of the feedback table",
+		Flags: dbFlags("migration_user", "dev_migration_password"),
+		Commands: []*cli.Command{
+			{
+				Name:        "maintain",
+				Usage:       "Create future partitions and drop expired ones",
+				Description: maintainCommandDescription,
+				Flags: []cli.Flag{
+					&cli.IntFlag{
+						Name:    "premake",
+						Usage:   "Number of future months with a partition",
+						Value:   partition.DefaultPremake,
+						Sources: cli.EnvVars("PARTITIONS_PREMAKE"),
+					},
+					&cli.StringFlag{
+						Name:    "older-than",
+						Usage:   "Retention period in days (e.g., 180d) or as a duration, empty keeps all partitions",
+						Value:   "",
+						Sources: cli.EnvVars("PARTITIONS_OLDER_THAN"),
+					},
+					&cli.BoolFlag{
+						Name:    "keep-detached",
+						Usage:   "Only detach expired partitions and keep them as standalone tables, e.g., for archiving",
+						Value:   false,
+						Sources: cli.EnvVars("PARTITIONS_KEEP_DETACHED"),
+					},
+					&cli.DurationFlag{
+						Name:    "lock-timeout",
+						Usage:   "Maximum time a statement waits for its lock on the feedback table",
+						Value:   partition.DefaultLockTimeout,
+						Sources: cli.EnvVars("PARTITIONS_LOCK_TIMEOUT"),
+					},
+					&cli.BoolFlag{
+						Name:    "dry-run",
+						Usage:   "Only log the planned changes",
+						Value:   false,
+						Sources: cli.EnvVars("PARTITIONS_DRY_RUN"),
+					},
+				},
+				Action: runPartitionsMaintain,
+			},
+		},
+	}
+}
+
+func runPartitionsMaintain(ctx context.Context, cmd *cli.Command) error {
+	slog.Info("Starting partition maintenance...")
+
+	premake := cmd.Int("premake")
+	if premake < 1 || premake > 24 {
+		return fmt.Errorf("premake must be between 1 and 24, got %d", premake)
+	}
+
+	cfg := partition.Config{
+		Premake:      premake,
+		KeepDetached: cmd.Bool("keep-detached"),
+		LockTimeout:  cmd.Duration("lock-timeout"),
+		DryRun:       cmd.Bool("dry-run"),
+	}
+
+	if olderThan := cmd.String("older-than"); olderThan != "" {
+		d, err := retention.ParseOlderThan(olderThan)
+		if err != nil {
+			return err
+		}
+
+		cfg.OlderThan = d
+	}
+
+	// Get database pool
+	pool, err := getDBPool(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	cfg.Pool = pool
+
+	maintainer, err := partition.New(cfg)
+	if err != nil {
+		return err
+	}
+
+	result, err := maintainer.Maintain(ctx)
+	if result != nil {
+		slog.Info("Partition maintenance finished",
+			"dry_run", cfg.DryRun,
+			"created", result.Created,
+			"detached", result.Detached,
+			"dropped", result.Dropped,
+			"kept", result.Kept)
+	}
+
+	return err
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/analysis"
+	"github.com/urfave/cli/v3"
+)
+
+var reconcileCommandDescription = `Verify Asana tasks referenced by report runs.
+
+This command will:
+  1. Load report runs from the last --days days
+  2. Fetch each referenced task from the Asana API
+  3. Report runs with feedback but no task, deleted tasks, and tasks whose
+     name or counts don't match the report
+  4. With --fix, recreate missing tasks and update mismatched ones
+
+The command exits with an error if unresolved issues remain.
+
+Examples:
+  # Report inconsistencies from the last 30 days
+  feedback analysis reconcile
+
+  # Recreate or update tasks from the last 90 days
+  feedback analysis reconcile --days=90 --fix
+`
+
+func reconcileCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "reconcile",
+		Usage:       "Verify Asana tasks against report runs",
+		Description: reconcileCommandDescription,
+		Flags: []cli.Flag{
+			&cli.IntFlag{
+				Name:    "days",
+				Usage:   "Number of days to verify, counting back from today",
+				Value:   30,
+				Sources: cli.EnvVars("RECONCILE_DAYS"),
+			},
+			&cli.BoolFlag{
+				Name:    "fix",
+				Usage:   "Recreate missing Asana tasks and rename mismatched ones",
+				Value:   false,
+				Sources: cli.EnvVars("RECONCILE_FIX"),
+			},
+		},
+		Action: runReconcile,
+	}
+}
+
+func runReconcile(ctx context.Context, cmd *cli.Command) error {
+	slog.Info("Starting report reconciliation...")
+
+	days := cmd.Int("days")
+	if days < 1 {
+		return fmt.Errorf("days must be at least 1, got %d", days)
+	}
+
+	// Get database pool
+	pool, err := getDBPool(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	replica, err := getDBReplicaPool(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	if replica != nil {
+		defer replica.Close()
+	}
+
+	aggregator, err := newAggregator(cmd, pool, replica)
+	if err != nil {
+		return err
+	}
+
+	since := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -days)
+
+	result, err := aggregator.Reconcile(ctx, analysis.ReconcileOptions{
+		Since: since,
+		Fix:   cmd.Bool("fix"),
+	})
+	if err != nil {
+		return err
+	}
+
+	if unresolved := result.Unresolved(); unresolved > 0 {
+		return fmt.Errorf("found %d unresolved report inconsistencies, rerun with --fix to resolve them", unresolved)
+	}
+
+	return nil
+}
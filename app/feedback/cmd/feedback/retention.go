@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/retention"
+	"github.com/urfave/cli/v3"
+)
+
+var retentionCommandDescription = `Delete feedback older than the retention period.
+
+This command will:
+  1. Compute the cutoff as now minus --older-than
+  2. Check that every period before the cutoff has a completed report run
+  3. Delete feedback created before the cutoff in batches of --batch-size rows
+
+Feedback from periods without a completed report run is never deleted, the
+cutoff is moved back to the first unreported period and the command exits with
+an error after deleting the older feedback.
+
+Examples:
+  # Show how much feedback would be deleted
+  feedback retention --older-than 180d --dry-run
+
+  # Delete feedback older than 180 days
+  feedback retention --older-than 180d
+
+  # Use smaller batches with a longer pause on a busy database
+  feedback retention --older-than 180d --batch-size 200 --batch-pause 1s
+`
+
+func retentionCommand() *cli.Command {
+	// Retention-specific flags
+	retentionFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:    "older-than",
+			Usage:   "Retention period in days (e.g., 180d) or as a duration (e.g., 36h)",
+			Value:   "180d",
+			Sources: cli.EnvVars("RETENTION_OLDER_THAN"),
+		},
+		&cli.IntFlag{
+			Name:    "batch-size",
+			Usage:   "Maximum number of rows deleted per statement",
+			Value:   retention.DefaultBatchSize,
+			Sources: cli.EnvVars("RETENTION_BATCH_SIZE"),
+		},
+		&cli.DurationFlag{
+			Name:    "batch-pause",
+			Usage:   "Pause between two batches (e.g., 100ms)",
+			Value:   100 * time.Millisecond,
+			Sources: cli.EnvVars("RETENTION_BATCH_PAUSE"),
+		},
+		&cli.BoolFlag{
+			Name:    "dry-run",
+			Usage:   "Only count the feedback that would be deleted",
+			Value:   false,
+			Sources: cli.EnvVars("RETENTION_DRY_RUN"),
+		},
+	}
+
+	// Combine shared database flags with retention-specific flags
+	retentionFlags = append(dbFlags("feedback_retention_app", "dev_retention_password"), retentionFlags...)
+
+	return &cli.Command{
+		Name:        "retention",
+		Usage:       "Delete feedback older than the retention period",
+		Description: retentionCommandDescription,
+		Flags:       retentionFlags,
+		Action:      runRetention,
+	}
+}
+
+func runRetention(ctx context.Context, cmd *cli.Command) error {
+	slog.Info("Starting feedback retention...")
+
+	olderThan, err := retention.ParseOlderThan(cmd.String("older-than"))
+	if err != nil {
+		return err
+	}
+
+	batchSize := cmd.Int("batch-size")
+	if batchSize < 1 || batchSize > 100000 {
+		return fmt.Errorf("batch-size must be between 1 and 100000, got %d", batchSize)
+	}
+
+	// Get database pool
+	pool, err := getDBPool(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	job, err := retention.New(retention.Config{
+		Pool:       pool,
+		OlderThan:  olderThan,
+		BatchSize:  int32(batchSize), // #nosec G115 - validated above
+		BatchPause: cmd.Duration("batch-pause"),
+		DryRun:     cmd.Bool("dry-run"),
+	})
+	if err != nil {
+		return err
+	}
+
+	result, err := job.Run(ctx)
+	if result != nil {
+		slog.Info("Feedback retention finished",
+			"dry_run", cmd.Bool("dry-run"),
+			"cutoff", result.EffectiveCutoff.Format(time.RFC3339),
+			"eligible", result.Total(),
+			"positive_count", result.PositiveCount,
+			"negative_count", result.NegativeCount,
+			"deleted", result.Deleted,
+			"batches", result.Batches,
+			"unreported_periods", len(result.Unreported))
+	}
+
+	return err
+}
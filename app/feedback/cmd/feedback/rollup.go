@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/rollup"
+	"github.com/urfave/cli/v3"
+)
+
+var rollupCommandDescription = `Maintain the hourly feedback counts in feedback_hourly_stats.
+
+The rollup is maintained incrementally from a high-watermark: every run
+recomputes the hours between the watermark and the last hour that ended at
+least --settle ago, then advances the watermark. Rerunning is safe. The
+analysis job runs the rollup before every report and reads the counts from it.
+
+With --verify, the rollup of the last --days days is compared hour by hour with
+the raw feedback. The command exits with an error if they differ, --fix
+recomputes the mismatched hours instead. Hours up to the one of the oldest raw
+feedback and hours without any raw feedback are skipped, their counts are kept
+after retention or a dropped partition deleted the feedback.
+
+Examples:
+  # Roll up all settled hours
+  feedback analysis rollup
+
+  # Cross-check the last 7 days against the raw feedback
+  feedback analysis rollup --verify
+
+  # Recompute hours of the last 30 days that differ
+  feedback analysis rollup --verify --days=30 --fix
+`
+
+func rollupCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "rollup",
+		Usage:       "Maintain or verify the hourly feedback rollup",
+		Description: rollupCommandDescription,
+		Flags: []cli.Flag{
+			&cli.DurationFlag{
+				Name:    "settle",
+				Usage:   "How long after the end of an hour it's rolled up",
+				Value:   rollup.DefaultSettle,
+				Sources: cli.EnvVars("ROLLUP_SETTLE"),
+			},
+			&cli.BoolFlag{
+				Name:    "verify",
+				Usage:   "Compare the rollup with the raw feedback instead of rolling up",
+				Value:   false,
+				Sources: cli.EnvVars("ROLLUP_VERIFY"),
+			},
+			&cli.IntFlag{
+				Name:    "days",
+				Usage:   "Number of days to verify, counting back from now",
+				Value:   7,
+				Sources: cli.EnvVars("ROLLUP_VERIFY_DAYS"),
+			},
+			&cli.BoolFlag{
+				Name:    "fix",
+				Usage:   "Recompute hours that don't match the raw feedback (with --verify)",
+				Value:   false,
+				Sources: cli.EnvVars("ROLLUP_FIX"),
+			},
+		},
+		Action: runRollup,
+	}
+}
+
+func runRollup(ctx context.Context, cmd *cli.Command) error {
+	days := cmd.Int("days")
+	if days < 1 {
+		return fmt.Errorf("days must be at least 1, got %d", days)
+	}
+
+	if cmd.Bool("fix") && !cmd.Bool("verify") {
+		return fmt.Errorf("--fix requires --verify")
+	}
+
+	// Get database pool
+	pool, err := getDBPool(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	r := rollup.New(rollup.Config{
+		Pool:   pool,
+		Settle: cmd.Duration("settle"),
+	})
+
+	if !cmd.Bool("verify") {
+		slog.Info("Starting hourly rollup...")
+
+		_, err := r.Run(ctx)
+
+		return err
+	}
+
+	slog.Info("Starting hourly rollup verification...")
+
+	now := time.Now().UTC()
+	_, err = r.Verify(ctx, now.AddDate(0, 0, -days), now, cmd.Bool("fix"))
+
+	return err
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/analysis"
+	"github.com/findmyname666/ddg3/feedback/pkgs/scheduler"
+	"github.com/urfave/cli/v3"
+)
+
+var schedulerCommandDescription = `Run the analysis job on a cron schedule as a long-lived process.
+
+The schedule is a five-field cron expression (minute, hour, day of month,
+month, day of week) evaluated in UTC, or one of @hourly, @daily, @weekly,
+@monthly and @yearly. Each run is delayed by a random duration up to --jitter.
+
+Liveness is served on /health and the last run and last success on /status.
+On SIGTERM or SIGINT no new run is started, a running job gets up to
+--shutdown-timeout to finish.
+
+Examples:
+  # Run every day at 01:00 UTC
+  feedback analysis scheduler --schedule "0 1 * * *"
+
+  # Spread the start of multiple deployments over 10 minutes
+  feedback analysis scheduler --schedule "@daily" --jitter 10m
+`
+
+func schedulerCommand() *cli.Command {
+	return &cli.Command{
+		Name:        "scheduler",
+		Usage:       "Run the analysis job on a cron schedule",
+		Description: schedulerCommandDescription,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "schedule",
+				Usage:   "Cron expression of the runs in UTC (e.g., '0 1 * * *' or '@daily')",
+				Value:   "0 1 * * *",
+				Sources: cli.EnvVars("SCHEDULE"),
+			},
+			&cli.DurationFlag{
+				Name:    "jitter",
+				Usage:   "Maximum random delay added to every run (e.g., 5m)",
+				Value:   0,
+				Sources: cli.EnvVars("SCHEDULE_JITTER"),
+			},
+			&cli.StringFlag{
+				Name:    "status-addr",
+				Usage:   "Listen address of the /health and /status endpoints (empty disables them)",
+				Value:   ":8081",
+				Sources: cli.EnvVars("SCHEDULER_STATUS_ADDR"),
+			},
+			&cli.DurationFlag{
+				Name:    "shutdown-timeout",
+				Usage:   "How long a running job may continue after SIGTERM",
+				Value:   scheduler.DefaultShutdownTimeout,
+				Sources: cli.EnvVars("SCHEDULER_SHUTDOWN_TIMEOUT"),
+			},
+		},
+		Action: runScheduler,
+	}
+}
+
+func runScheduler(ctx context.Context, cmd *cli.Command) error {
+	slog.Info("Starting analysis scheduler...")
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	schedule, err := scheduler.ParseSchedule(cmd.String("schedule"))
+	if err != nil {
+		return err
+	}
+
+	if cmd.Duration("jitter") < 0 {
+		return fmt.Errorf("jitter must not be negative")
+	}
+
+	// Get database pool
+	pool, err := getDBPool(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	replica, err := getDBReplicaPool(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	if replica != nil {
+		defer replica.Close()
+	}
+
+	aggregator, err := newAggregator(cmd, pool, replica)
+	if err != nil {
+		return err
+	}
+
+	sched := scheduler.New(scheduler.Config{
+		Schedule:        schedule,
+		Job:             analysisJob(aggregator),
+		Jitter:          cmd.Duration("jitter"),
+		ShutdownTimeout: cmd.Duration("shutdown-timeout"),
+	})
+
+	slog.Info("Scheduler configuration",
+		"schedule", schedule.String(),
+		"jitter", cmd.Duration("jitter"),
+		"status_addr", cmd.String("status-addr"),
+		"shutdown_timeout", cmd.Duration("shutdown-timeout"),
+		"db_user", cmd.String("db-user"))
+
+	var server *http.Server
+
+	serverErr := make(chan error, 1)
+
+	if addr := cmd.String("status-addr"); addr != "" {
+		server = &http.Server{
+			Addr:         addr,
+			Handler:      sched.Handler(),
+			ReadTimeout:  15 * time.Second,
+			WriteTimeout: 15 * time.Second,
+			IdleTimeout:  60 * time.Second,
+		}
+
+		go func() {
+			slog.Info("Starting status server", "addr", addr)
+
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serverErr <- err
+				// Stop the scheduler, the process would look healthy without the status server otherwise
+				stop()
+			}
+		}()
+	}
+
+	runErr := sched.Run(ctx)
+
+	if server != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("Failed to shut down status server", "error", err)
+		}
+	}
+
+	select {
+	case err := <-serverErr:
+		return fmt.Errorf("status server failed: %w", err)
+	default:
+	}
+
+	return runErr
+}
+
+// analysisJob runs the aggregation, a run skipped because another instance creates the report isn't a failure
+func analysisJob(aggregator *analysis.Aggregator) scheduler.Job {
+	return func(ctx context.Context) error {
+		err := aggregator.Run(ctx)
+		if errors.Is(err, analysis.ErrRunInProgress) {
+			slog.Info("Skipping run, another instance is creating the report", "error", err)
+
+			return nil
+		}
+
+		return err
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/search"
+	"github.com/urfave/cli/v3"
+)
+
+var searchCommandDescription = `Full-text search over the feedback messages, best matches first.
+
+The query uses the web search syntax: words match all their forms
+(bookmark, bookmarks), "quoted phrases" match in order, OR matches either side
+and -word excludes messages containing it. Matched words are wrapped in ** in
+the printed snippets.
+
+Messages are indexed with the english configuration. Searching with another
+--config (e.g., german or simple) parses every message in the date range, use
+--from and --to to keep it fast.
+
+Examples:
+  # Negative feedback about bookmarks
+  feedback search --sentiment=negative bookmarks
+
+  # A phrase in the feedback of March 2026 as JSON
+  feedback search --from=2026-03-01 --to=2026-04-01 --format=json '"sync bookmarks" -firefox'
+
+  # German feedback of the last week
+  feedback search --config=german --from=2026-03-24 Lesezeichen
+`
+
+func searchCommand() *cli.Command {
+	// Search-specific flags
+	searchFlags := []cli.Flag{
+		&cli.StringFlag{
+			Name:  "sentiment",
+			Usage: "Only positive or negative feedback",
+		},
+		&cli.StringFlag{
+			Name:  "from",
+			Usage: "Only feedback created at or after this date (2006-01-02) or RFC 3339 time",
+		},
+		&cli.StringFlag{
+			Name:  "to",
+			Usage: "Only feedback created before this date (2006-01-02) or RFC 3339 time",
+		},
+		&cli.IntFlag{
+			Name:  "limit",
+			Usage: fmt.Sprintf("Maximum number of results (1-%d)", search.MaxLimit),
+			Value: search.DefaultLimit,
+		},
+		&cli.StringFlag{
+			Name:    "config",
+			Usage:   "Text search configuration (language) of the query, e.g. english, german, simple",
+			Value:   search.DefaultConfig,
+			Sources: cli.EnvVars("SEARCH_CONFIG"),
+		},
+		&cli.StringFlag{
+			Name:  "format",
+			Usage: "Output format (text, json)",
+			Value: "text",
+		},
+	}
+
+	// Combine shared database flags with search-specific flags
+	searchFlags = append(dbFlags("feedback_analysis_app", "dev_analysis_password"), searchFlags...)
+
+	return &cli.Command{
+		Name:        "search",
+		Usage:       "Full-text search over feedback messages",
+		ArgsUsage:   "QUERY",
+		Description: searchCommandDescription,
+		Flags:       searchFlags,
+		Action:      runSearch,
+	}
+}
+
+// runSearch prints the search results to stdout, logs are written to stderr to keep stdout parseable
+func runSearch(ctx context.Context, cmd *cli.Command) error {
+	setupLogging(os.Stderr, cmd.Bool("debug"))
+
+	if cmd.NArg() != 1 {
+		return fmt.Errorf("expected exactly one query argument, got %d", cmd.NArg())
+	}
+
+	format := cmd.String("format")
+	if format != "text" && format != "json" {
+		return fmt.Errorf("invalid format %q, must be text or json", format)
+	}
+
+	from, err := search.ParseTime(cmd.String("from"))
+	if err != nil {
+		return fmt.Errorf("invalid from: %w", err)
+	}
+
+	to, err := search.ParseTime(cmd.String("to"))
+	if err != nil {
+		return fmt.Errorf("invalid to: %w", err)
+	}
+
+	// Get database pool
+	pool, err := getDBPool(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	results, err := search.New(pool, cmd.String("config")).Search(ctx, search.Params{
+		Query:     cmd.Args().First(),
+		Sentiment: cmd.String("sentiment"),
+		From:      from,
+		To:        to,
+		Limit:     cmd.Int("limit"),
+	})
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(results)
+	}
+
+	return printSearchResults(os.Stdout, results)
+}
+
+// printSearchResults writes one header line and the snippet of every result
+func printSearchResults(w io.Writer, results []search.Result) error {
+	if len(results) == 0 {
+		_, err := fmt.Fprintln(w, "No matching feedback")
+
+		return err
+	}
+
+	for _, result := range results {
+		if _, err := fmt.Fprintf(w, "#%d  %s  %s  rank %.3f\n    %s\n\n",
+			result.ID,
+			result.CreatedAt.Format(time.DateTime),
+			result.Sentiment,
+			result.Rank,
+			// Keep multi-line messages on one line
+			strings.Join(strings.Fields(result.Snippet), " "),
+		); err != nil {
+			return fmt.Errorf("failed to write search results: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 
+	"github.com/findmyname666/ddg3/feedback/pkgs/search"
 	"github.com/findmyname666/ddg3/feedback/pkgs/web"
 	"github.com/urfave/cli/v3"
 )
@@ -40,6 +41,23 @@ func webCommand() *cli.Command {
 			Value:   5000,
 			Sources: cli.EnvVars("MAX_MESSAGE_LENGTH"),
 		},
+		&cli.IntFlag{
+			Name:    "stream-max-subscribers",
+			Usage:   "Maximum number of concurrent /api/v1/stream subscribers, 0 disables the live stream",
+			Value:   web.DefaultStreamMaxSubscribers,
+			Sources: cli.EnvVars("STREAM_MAX_SUBSCRIBERS"),
+		},
+		&cli.StringFlag{
+			Name:    "search-token",
+			Usage:   "Bearer token required by /api/v1/search, the search is disabled when empty",
+			Sources: cli.EnvVars("SEARCH_TOKEN"),
+		},
+		&cli.StringFlag{
+			Name:    "search-config",
+			Usage:   "Default text search configuration (language) of /api/v1/search, e.g. english, german, simple",
+			Value:   search.DefaultConfig,
+			Sources: cli.EnvVars("SEARCH_CONFIG"),
+		},
 	}
 
 	// Combine shared database flags with web-specific flags
@@ -65,6 +83,10 @@ func runWeb(ctx context.Context, cmd *cli.Command) error {
 		)
 	}
 
+	if cmd.Int("stream-max-subscribers") < 0 {
+		return fmt.Errorf("stream-max-subscribers must not be negative")
+	}
+
 	// Get database pool
 	pool, err := getDBPool(ctx, cmd)
 	if err != nil {
@@ -79,6 +101,10 @@ func runWeb(ctx context.Context, cmd *cli.Command) error {
 		Pool:             pool,
 		StaticPath:       cmd.String("static-path"),
 		MaxMessageLength: maxMessageLength,
+
+		StreamMaxSubscribers: cmd.Int("stream-max-subscribers"),
+		SearchToken:          cmd.String("search-token"),
+		SearchConfig:         cmd.String("search-config"),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create web server: %w", err)
@@ -89,6 +115,9 @@ func runWeb(ctx context.Context, cmd *cli.Command) error {
 		"port", cmd.Int("port"),
 		"static_path", cmd.String("static-path"),
 		"max_message_length", cmd.Int("max-message-length"),
+		"stream_max_subscribers", cmd.Int("stream-max-subscribers"),
+		"search_enabled", cmd.String("search-token") != "",
+		"search_config", cmd.String("search-config"),
 		"db_user", cmd.String("db-user"))
 
 	return server.Start(ctx)
@@ -0,0 +1,25 @@
+-- migrate:up
+
+-- Track the state of a report run to make report creation crash-safe
+-- pending: the run is recorded, but the Asana task hasn't been created yet
+-- completed: the Asana task was created (or skipped because there was no feedback)
+CREATE TYPE report_status AS ENUM ('pending', 'completed');
+
+-- Existing rows were written after the Asana task was created, so they are completed
+ALTER TABLE report_runs
+    ADD COLUMN status report_status NOT NULL DEFAULT 'completed',
+    ADD COLUMN completed_at TIMESTAMP WITH TIME ZONE;
+
+UPDATE report_runs SET completed_at = created_at;
+
+-- New rows start as pending and are completed once the Asana task exists
+ALTER TABLE report_runs ALTER COLUMN status SET DEFAULT 'pending';
+
+-- Create partial index for efficient lookup of runs that need to be resumed
+CREATE INDEX IF NOT EXISTS idx_report_runs_pending ON report_runs(report_date) WHERE status = 'pending';
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_report_runs_pending;
+ALTER TABLE report_runs DROP COLUMN IF EXISTS completed_at;
+ALTER TABLE report_runs DROP COLUMN IF EXISTS status;
+DROP TYPE IF EXISTS report_status;
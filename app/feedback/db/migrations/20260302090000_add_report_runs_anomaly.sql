@@ -0,0 +1,12 @@
+-- migrate:up
+
+-- Mark report runs whose negative feedback rate or volume deviates from the trailing baseline
+-- is_anomaly: true if at least one statistically significant deviation was detected
+-- anomaly_details: human readable description of the detected deviations
+ALTER TABLE report_runs
+    ADD COLUMN is_anomaly BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN anomaly_details TEXT;
+
+-- migrate:down
+ALTER TABLE report_runs DROP COLUMN IF EXISTS anomaly_details;
+ALTER TABLE report_runs DROP COLUMN IF EXISTS is_anomaly;
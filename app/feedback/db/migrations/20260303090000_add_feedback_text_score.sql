@@ -0,0 +1,13 @@
+-- migrate:up
+
+-- Store the lexicon-based sentiment score of the feedback message
+-- text_score: normalized score in [-1, 1] computed by the analysis job, NULL if not scored
+ALTER TABLE feedback ADD COLUMN text_score REAL;
+
+-- Allow the analysis job to store text scores
+-- Why column-level: The analysis job must still never modify user submissions (sentiment, message)
+GRANT UPDATE (text_score) ON TABLE feedback TO feedback_analysis_app;
+
+-- migrate:down
+REVOKE UPDATE (text_score) ON TABLE feedback FROM feedback_analysis_app;
+ALTER TABLE feedback DROP COLUMN IF EXISTS text_score;
@@ -0,0 +1,41 @@
+-- migrate:up
+
+-- Grant permissions to the retention job user
+-- Note: The user (feedback_retention_app) is created by the Docker init script
+-- Reason: Creating users requires CREATEROLE privilege, which migration_user doesn't have
+-- Existing databases: Create the user manually before running this migration, see app/db/README.md
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'feedback_retention_app') THEN
+        RAISE WARNING 'Role feedback_retention_app does not exist, skipping retention grants';
+        RETURN;
+    END IF;
+
+    -- Start with zero permissions (principle of least privilege)
+    REVOKE ALL ON ALL TABLES IN SCHEMA public FROM feedback_retention_app;
+
+    -- SELECT: Find the oldest feedback and count records per period
+    -- DELETE: Remove feedback older than the retention period
+    -- Why no INSERT/UPDATE: The retention job must never modify user submissions
+    GRANT SELECT, DELETE ON TABLE feedback TO feedback_retention_app;
+
+    -- SELECT: Check that a period was reported before its feedback is deleted
+    -- Why read-only: Report runs are owned by the analysis job
+    GRANT SELECT ON TABLE report_runs TO feedback_retention_app;
+END
+$$;
+
+-- No sequence grant needed for feedback_retention_app
+-- Reason: Deleting rows doesn't use feedback_id_seq
+
+-- migrate:down
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'feedback_retention_app') THEN
+        REVOKE ALL ON ALL TABLES IN SCHEMA public FROM feedback_retention_app;
+    END IF;
+END
+$$;
+
+-- Note: The user is not dropped here
+-- Reason: User management is handled by the Docker init script
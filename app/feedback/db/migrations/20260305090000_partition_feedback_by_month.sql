@@ -0,0 +1,132 @@
+-- migrate:up
+
+-- Convert feedback to a table range-partitioned by month on created_at
+-- Why: Retention can drop whole partitions instead of deleting rows, and time range scans
+-- (daily aggregation, exports) only touch the partitions of the requested range
+-- Partition names: feedback_yYYYYmMM, bounds are UTC month starts [start, next month start)
+-- New partitions are created ahead of time by `feedback partitions maintain`
+--
+-- Note: PostgreSQL can't convert a table in place, the data is copied into a new partitioned table
+-- Note: The primary key must include the partition key, so PostgreSQL doesn't enforce a unique id.
+-- New rows get their id from the identity sequence, rows inserted with OVERRIDING SYSTEM VALUE
+-- (like the copy below) must keep ids unique themselves
+
+-- Move the existing table and its objects out of the way
+ALTER TABLE feedback RENAME TO feedback_unpartitioned;
+ALTER TABLE feedback_unpartitioned RENAME CONSTRAINT feedback_pkey TO feedback_unpartitioned_pkey;
+ALTER SEQUENCE feedback_id_seq RENAME TO feedback_unpartitioned_id_seq;
+DROP INDEX IF EXISTS idx_feedback_created_at;
+DROP INDEX IF EXISTS idx_feedback_sentiment;
+
+-- Create the partitioned table, the identity sequence is named feedback_id_seq again
+CREATE TABLE feedback (
+    id INT GENERATED ALWAYS AS IDENTITY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    sentiment sentiment_type NOT NULL,
+    message TEXT,
+    text_score REAL,
+    CONSTRAINT feedback_pkey PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+-- Create monthly partitions from the oldest feedback up to 3 months ahead
+-- Why ahead: Inserts fail if no partition covers NOW(), the maintain command keeps this buffer
+DO $$
+DECLARE
+    month_start TIMESTAMP;
+    last_month TIMESTAMP := date_trunc('month', NOW() AT TIME ZONE 'UTC') + INTERVAL '3 months';
+BEGIN
+    SELECT date_trunc('month', COALESCE(MIN(created_at), NOW()) AT TIME ZONE 'UTC')
+    INTO month_start
+    FROM feedback_unpartitioned;
+
+    WHILE month_start <= last_month LOOP
+        EXECUTE format(
+            'CREATE TABLE %I PARTITION OF feedback FOR VALUES FROM (%L) TO (%L)',
+            'feedback_' || to_char(month_start, '"y"YYYY"m"MM'),
+            month_start AT TIME ZONE 'UTC',
+            (month_start + INTERVAL '1 month') AT TIME ZONE 'UTC'
+        );
+        month_start := month_start + INTERVAL '1 month';
+    END LOOP;
+END
+$$;
+
+-- Copy the data, keeping the IDs
+INSERT INTO feedback (id, created_at, sentiment, message, text_score)
+OVERRIDING SYSTEM VALUE
+SELECT id, created_at, sentiment, message, text_score FROM feedback_unpartitioned;
+
+-- Continue the IDs after the copied rows
+SELECT setval(
+    'feedback_id_seq',
+    COALESCE((SELECT MAX(id) FROM feedback_unpartitioned), 0) + 1,
+    false
+);
+
+DROP TABLE feedback_unpartitioned;
+
+-- Recreate the indexes on the partitioned table, PostgreSQL creates them on every partition
+CREATE INDEX IF NOT EXISTS idx_feedback_created_at ON feedback(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_feedback_sentiment ON feedback(sentiment);
+
+-- Restore the permissions of the application users, they were granted on the dropped table
+-- Note: Permissions are checked on the parent table, partitions need no grants as long as
+-- they are only accessed through feedback
+GRANT SELECT, INSERT, UPDATE, DELETE ON TABLE feedback TO web_app;
+GRANT USAGE, SELECT ON SEQUENCE feedback_id_seq TO web_app;
+GRANT SELECT ON TABLE feedback TO feedback_analysis_app;
+GRANT UPDATE (text_score) ON TABLE feedback TO feedback_analysis_app;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'feedback_retention_app') THEN
+        GRANT SELECT, DELETE ON TABLE feedback TO feedback_retention_app;
+    END IF;
+END
+$$;
+
+-- migrate:down
+
+-- Convert feedback back to a single table, partitions detached by the maintain command are not restored
+ALTER TABLE feedback RENAME TO feedback_partitioned;
+ALTER TABLE feedback_partitioned RENAME CONSTRAINT feedback_pkey TO feedback_partitioned_pkey;
+ALTER SEQUENCE feedback_id_seq RENAME TO feedback_partitioned_id_seq;
+DROP INDEX IF EXISTS idx_feedback_created_at;
+DROP INDEX IF EXISTS idx_feedback_sentiment;
+
+CREATE TABLE feedback (
+    id INT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    sentiment sentiment_type NOT NULL,
+    message TEXT,
+    text_score REAL
+);
+
+INSERT INTO feedback (id, created_at, sentiment, message, text_score)
+OVERRIDING SYSTEM VALUE
+SELECT id, created_at, sentiment, message, text_score FROM feedback_partitioned;
+
+SELECT setval(
+    'feedback_id_seq',
+    COALESCE((SELECT MAX(id) FROM feedback_partitioned), 0) + 1,
+    false
+);
+
+-- Dropping the parent drops all attached partitions
+DROP TABLE feedback_partitioned;
+
+CREATE INDEX IF NOT EXISTS idx_feedback_created_at ON feedback(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_feedback_sentiment ON feedback(sentiment);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON TABLE feedback TO web_app;
+GRANT USAGE, SELECT ON SEQUENCE feedback_id_seq TO web_app;
+GRANT SELECT ON TABLE feedback TO feedback_analysis_app;
+GRANT UPDATE (text_score) ON TABLE feedback TO feedback_analysis_app;
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'feedback_retention_app') THEN
+        GRANT SELECT, DELETE ON TABLE feedback TO feedback_retention_app;
+    END IF;
+END
+$$;
@@ -0,0 +1,38 @@
+-- migrate:up
+
+-- Hourly rollup of the feedback counts
+-- Purpose: Reports and dashboards sum at most 24 rows per day instead of scanning raw feedback
+-- hour: UTC start of the hour, covers [hour, hour + 1 hour)
+-- Maintained by `feedback analysis rollup` (and the analysis job before every report), see
+-- feedback_rollup_watermarks for the covered range
+-- Note: Counts are kept when raw feedback is deleted by retention
+CREATE TABLE IF NOT EXISTS feedback_hourly_stats (
+    hour TIMESTAMP WITH TIME ZONE PRIMARY KEY,
+    positive_count INTEGER NOT NULL DEFAULT 0,
+    negative_count INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT feedback_hourly_stats_positive_count_check CHECK (positive_count >= 0),
+    CONSTRAINT feedback_hourly_stats_negative_count_check CHECK (negative_count >= 0),
+    CONSTRAINT feedback_hourly_stats_hour_check CHECK (hour = date_trunc('hour', hour, 'UTC'))
+);
+
+-- High-watermarks of incrementally maintained rollups
+-- name: rollup table name
+-- watermark: all hours before the watermark are rolled up, the next run continues from here
+CREATE TABLE IF NOT EXISTS feedback_rollup_watermarks (
+    name TEXT PRIMARY KEY,
+    watermark TIMESTAMP WITH TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Allow the analysis job to maintain and read the rollup
+-- SELECT, INSERT, UPDATE: Upsert hourly counts and advance the watermark
+-- DELETE: Recompute a range of hours (hours without feedback have no row)
+GRANT SELECT, INSERT, UPDATE, DELETE ON TABLE feedback_hourly_stats TO feedback_analysis_app;
+GRANT SELECT, INSERT, UPDATE ON TABLE feedback_rollup_watermarks TO feedback_analysis_app;
+
+-- migrate:down
+REVOKE ALL ON TABLE feedback_rollup_watermarks FROM feedback_analysis_app;
+REVOKE ALL ON TABLE feedback_hourly_stats FROM feedback_analysis_app;
+DROP TABLE IF EXISTS feedback_rollup_watermarks;
+DROP TABLE IF EXISTS feedback_hourly_stats;
@@ -0,0 +1,21 @@
+-- migrate:up
+
+-- Notify listeners about every new feedback row
+-- The web application LISTENs on the feedback_inserted channel and streams the events to the live wallboard
+-- Payload: {"id": 42, "sentiment": "positive"}, the message is never sent, payloads are limited to 8000 bytes
+-- Why a trigger: Notifications are sent on commit only, rolled back inserts are never announced
+CREATE FUNCTION feedback_notify_insert() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('feedback_inserted', json_build_object('id', NEW.id, 'sentiment', NEW.sentiment)::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Why on the partitioned table: The trigger is cloned to all existing and future partitions
+CREATE TRIGGER feedback_notify_insert
+    AFTER INSERT ON feedback
+    FOR EACH ROW EXECUTE FUNCTION feedback_notify_insert();
+
+-- migrate:down
+DROP TRIGGER IF EXISTS feedback_notify_insert ON feedback;
+DROP FUNCTION IF EXISTS feedback_notify_insert();
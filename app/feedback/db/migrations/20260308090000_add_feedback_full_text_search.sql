@@ -0,0 +1,17 @@
+-- migrate:up
+
+-- Full-text search over feedback messages
+-- message_search: lexemes of the message parsed with the english configuration, kept up to date by PostgreSQL
+-- Why english: Most feedback is written in English, searches with other configurations (e.g., german)
+-- parse the message on the fly, see the search queries in db/queries/search.sql
+-- Why STORED: Ranking reads the vector of every match, parsing the message again per query is expensive
+-- Note: Adding the column rewrites all partitions of the feedback table
+ALTER TABLE feedback ADD COLUMN message_search tsvector
+    GENERATED ALWAYS AS (to_tsvector('english'::regconfig, COALESCE(message, ''))) STORED;
+
+-- GIN index for @@ matches, created on every partition
+CREATE INDEX idx_feedback_message_search ON feedback USING GIN (message_search);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_feedback_message_search;
+ALTER TABLE feedback DROP COLUMN IF EXISTS message_search;
@@ -0,0 +1,10 @@
+-- migrate:up
+
+-- Index lookups by id alone (GetFeedback, UpdateFeedbackTextScores)
+-- Why: The primary key (id, created_at) can't be used without created_at, every partition was scanned
+-- Note: The index isn't unique, a unique index on a partitioned table must include the partition key.
+-- id only comes from the identity sequence unless rows are inserted with OVERRIDING SYSTEM VALUE
+CREATE INDEX idx_feedback_id ON feedback (id);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_feedback_id;
@@ -0,0 +1,25 @@
+-- name: ExportFeedback :many
+-- Retrieves all feedback records within a time range, oldest first.
+-- Used by the export command through StreamExportFeedback, which scans the same columns.
+-- Parameters: range_start (inclusive), range_end (exclusive)
+--
+-- Deprecated: Loads all rows into memory, use StreamExportFeedback. The query is only
+-- generated for its SQL and row type.
+SELECT id, created_at, sentiment, message, text_score FROM feedback
+WHERE created_at >= sqlc.arg(range_start) AND created_at < sqlc.arg(range_end)
+ORDER BY created_at ASC, id ASC;
+
+-- name: ExportReportRuns :many
+-- Retrieves all report runs whose report date is within a date range, oldest first.
+-- Used by the export command through StreamExportReportRuns, which scans the same columns.
+-- The columns are listed explicitly, a column added to report_runs doesn't change the scanned row.
+-- Parameters: range_start (inclusive), range_end (exclusive)
+--
+-- Deprecated: Loads all rows into memory, use StreamExportReportRuns. The query is only
+-- generated for its SQL.
+SELECT
+    report_date, window_start, window_end, positive_count, negative_count, asana_task_gid,
+    created_at, status, completed_at, is_anomaly, anomaly_details
+FROM report_runs
+WHERE report_date >= sqlc.arg(range_start) AND report_date < sqlc.arg(range_end)
+ORDER BY report_date ASC;
@@ -1,17 +1,20 @@
+-- The feedback queries list their columns instead of using *, pgx can't scan the
+-- message_search tsvector column and it's only needed by the search queries
+
 -- name: CreateFeedback :one
 INSERT INTO feedback (
     sentiment,
     message
 ) VALUES (
     $1, $2
-) RETURNING *;
+) RETURNING id, created_at, sentiment, message, text_score;
 
 -- name: GetFeedback :one
-SELECT * FROM feedback
+SELECT id, created_at, sentiment, message, text_score FROM feedback
 WHERE id = $1;
 
 -- name: ListFeedback :many
-SELECT * FROM feedback
+SELECT id, created_at, sentiment, message, text_score FROM feedback
 ORDER BY created_at DESC
 LIMIT $1 OFFSET $2;
 
@@ -23,10 +26,42 @@ FROM feedback
 WHERE created_at >= $1 AND created_at < $2;
 
 -- name: GetFeedbackInTimeRange :many
-SELECT * FROM feedback
+SELECT id, created_at, sentiment, message, text_score FROM feedback
 WHERE created_at >= $1 AND created_at < $2
 ORDER BY created_at DESC;
 
--- name: DeleteOldFeedback :exec
+-- name: DeleteOldFeedback :execrows
+-- Deletes a batch of feedback records created before a cutoff, oldest first.
+-- Used by the retention command, bounded batches keep row locks and transactions short.
+-- Parameters: $1 = cutoff (exclusive), $2 = maximum number of rows
+-- The outer created_at condition lets PostgreSQL prune the partitions after the cutoff
 DELETE FROM feedback
-WHERE created_at < $1;
+WHERE feedback.created_at < sqlc.arg(cutoff)
+  AND (feedback.id, feedback.created_at) IN (
+    SELECT old.id, old.created_at FROM feedback AS old
+    WHERE old.created_at < sqlc.arg(cutoff)
+    ORDER BY old.created_at ASC
+    LIMIT sqlc.arg(batch_size)
+);
+
+-- name: GetOldestFeedbackTime :one
+-- Retrieves the creation time of the oldest feedback record, NULL if there is no feedback.
+-- Used by the retention command to find the first period to check for report runs,
+-- and by the rollup verification to skip hours whose feedback was deleted.
+SELECT MIN(created_at)::timestamptz AS created_at FROM feedback;
+
+-- name: ListFeedbackMessagesInTimeRange :many
+-- Retrieves non-empty feedback messages within a time window for text analysis.
+-- Parameters: $1 = window start (inclusive), $2 = window end (exclusive), $3 = maximum number of rows
+SELECT id, sentiment, message FROM feedback
+WHERE created_at >= $1 AND created_at < $2
+  AND message IS NOT NULL AND message <> ''
+ORDER BY id ASC
+LIMIT $3;
+
+-- name: UpdateFeedbackTextScores :exec
+-- Stores the text sentiment scores computed by the analysis job.
+-- Parameters: ids and scores are arrays of the same length
+UPDATE feedback SET text_score = scores.score
+FROM (SELECT unnest(@ids::int[]) AS id, unnest(@scores::real[]) AS score) AS scores
+WHERE feedback.id = scores.id;
@@ -0,0 +1,74 @@
+-- name: GetRollupWatermarkForUpdate :one
+-- Retrieves the high-watermark of a rollup and locks it until the end of the transaction.
+-- Used to serialize concurrent rollup runs.
+-- Parameter: $1 = rollup name
+SELECT watermark FROM feedback_rollup_watermarks
+WHERE name = $1
+FOR UPDATE;
+
+-- name: GetRollupWatermark :one
+-- Retrieves the high-watermark of a rollup, all hours before it are rolled up.
+-- Parameter: $1 = rollup name
+SELECT watermark FROM feedback_rollup_watermarks
+WHERE name = $1;
+
+-- name: UpsertRollupWatermark :exec
+-- Stores the high-watermark of a rollup, it never moves backwards.
+-- Parameters: $1 = rollup name, $2 = watermark
+INSERT INTO feedback_rollup_watermarks (name, watermark)
+VALUES ($1, $2)
+ON CONFLICT (name) DO UPDATE
+SET watermark = GREATEST(feedback_rollup_watermarks.watermark, EXCLUDED.watermark),
+    updated_at = NOW();
+
+-- name: DeleteFeedbackHourlyStats :exec
+-- Deletes the hourly counts within a time range before they are recomputed.
+-- Parameters: $1 = first hour (inclusive), $2 = last hour (exclusive)
+DELETE FROM feedback_hourly_stats
+WHERE hour >= $1 AND hour < $2;
+
+-- name: RollupFeedbackHourlyStats :execrows
+-- Computes the hourly counts of the raw feedback within a time range and stores them.
+-- Idempotent, existing hours are overwritten. Returns the number of hours with feedback.
+-- Parameters: range start and end, both must be full UTC hours
+INSERT INTO feedback_hourly_stats (hour, positive_count, negative_count)
+SELECT
+    date_trunc('hour', created_at, 'UTC') AS hour,
+    COUNT(*) FILTER (WHERE sentiment = 'positive') AS positive_count,
+    COUNT(*) FILTER (WHERE sentiment = 'negative') AS negative_count
+FROM feedback
+WHERE created_at >= sqlc.arg(range_start) AND created_at < sqlc.arg(range_end)
+GROUP BY 1
+ON CONFLICT (hour) DO UPDATE
+SET positive_count = EXCLUDED.positive_count,
+    negative_count = EXCLUDED.negative_count,
+    updated_at = NOW();
+
+-- name: SumFeedbackHourlyStats :one
+-- Sums the hourly counts within a time range, the rollup equivalent of CountFeedbackBySentiment.
+-- Parameters: $1 = first hour (inclusive), $2 = last hour (exclusive)
+SELECT
+    COALESCE(SUM(positive_count), 0)::bigint AS positive_count,
+    COALESCE(SUM(negative_count), 0)::bigint AS negative_count
+FROM feedback_hourly_stats
+WHERE hour >= $1 AND hour < $2;
+
+-- name: ListFeedbackHourlyStats :many
+-- Retrieves the hourly counts within a time range, oldest first.
+-- Parameters: $1 = first hour (inclusive), $2 = last hour (exclusive)
+SELECT hour, positive_count, negative_count FROM feedback_hourly_stats
+WHERE hour >= $1 AND hour < $2
+ORDER BY hour ASC;
+
+-- name: CountFeedbackByHour :many
+-- Counts the raw feedback per UTC hour within a time range, oldest first.
+-- Used to verify the rollup against the raw data.
+-- Parameters: range start and end, both must be full UTC hours
+SELECT
+    date_trunc('hour', created_at, 'UTC')::timestamptz AS hour,
+    COUNT(*) FILTER (WHERE sentiment = 'positive') AS positive_count,
+    COUNT(*) FILTER (WHERE sentiment = 'negative') AS negative_count
+FROM feedback
+WHERE created_at >= sqlc.arg(range_start) AND created_at < sqlc.arg(range_end)
+GROUP BY 1
+ORDER BY 1 ASC;
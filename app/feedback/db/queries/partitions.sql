@@ -0,0 +1,24 @@
+-- name: ListFeedbackPartitions :many
+-- Retrieves the partitions attached to the feedback table, ordered by name.
+-- Used by the partitions maintain command to find missing and expired partitions.
+-- detach_pending is true if a concurrent detach was interrupted and must be finalized.
+SELECT
+    c.relname::text AS name,
+    i.inhdetachpending AS detach_pending
+FROM pg_catalog.pg_inherits AS i
+JOIN pg_catalog.pg_class AS c ON c.oid = i.inhrelid
+WHERE i.inhparent = 'public.feedback'::regclass
+ORDER BY c.relname;
+
+-- name: ListDetachedFeedbackPartitions :many
+-- Retrieves the standalone tables named like feedback partitions, ordered by name.
+-- Used by the partitions maintain command to drop expired partitions that were detached but not
+-- dropped, e.g., because the run was interrupted in between.
+SELECT c.relname::text AS name
+FROM pg_catalog.pg_class AS c
+JOIN pg_catalog.pg_namespace AS n ON n.oid = c.relnamespace
+WHERE n.nspname = 'public'
+  AND c.relkind = 'r'
+  AND NOT c.relispartition
+  AND c.relname LIKE 'feedback\_y%'
+ORDER BY c.relname;
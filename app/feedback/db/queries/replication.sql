@@ -0,0 +1,19 @@
+-- name: GetReplicationLag :one
+-- Retrieves how far a replica is behind the primary in seconds, 0 on a primary, and whether it's
+-- streaming WAL from the primary.
+-- A replica that replayed all received WAL is not behind, even if the primary was idle since the last
+-- replayed transaction. That only holds while it's streaming: a replica whose WAL receiver is
+-- disconnected replayed everything it received but doesn't know what it's missing.
+-- pg_stat_wal_receiver shows the status to members of pg_read_all_stats only, for other users a running
+-- WAL receiver counts as streaming.
+-- Used to route read-only queries to the replica only while it's up to date.
+SELECT
+    (CASE
+        WHEN NOT pg_is_in_recovery() THEN 0
+        WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0
+        ELSE COALESCE(EXTRACT(EPOCH FROM NOW() - pg_last_xact_replay_timestamp()), 0)
+    END)::float8 AS lag_seconds,
+    (NOT pg_is_in_recovery() OR EXISTS (
+        SELECT 1 FROM pg_catalog.pg_stat_wal_receiver
+        WHERE COALESCE(status, 'streaming') = 'streaming'
+    ))::boolean AS streaming;
@@ -22,9 +22,52 @@ SELECT * FROM report_runs
 ORDER BY report_date DESC
 LIMIT $1 OFFSET $2;
 
+-- name: GetReportRunForUpdate :one
+-- Retrieves a report run and locks the row until the end of the transaction.
+-- Used to claim a report date before the Asana task is created.
+-- Parameter: $1 = report_date (DATE)
+SELECT * FROM report_runs
+WHERE report_date = $1
+FOR UPDATE;
+
+-- name: ListPendingReportRuns :many
+-- Retrieves report runs whose Asana task hasn't been created yet, oldest first.
+-- Used to resume runs interrupted between recording the run and creating the task.
+SELECT * FROM report_runs
+WHERE status = 'pending'
+ORDER BY report_date ASC;
+
+-- name: ListReportRunsSince :many
+-- Retrieves report runs on or after a given date, oldest first.
+-- Used to verify Asana tasks referenced by report runs.
+-- Parameter: $1 = report_date (DATE)
+SELECT * FROM report_runs
+WHERE report_date >= $1
+ORDER BY report_date ASC;
+
+-- name: ListReportRunsBetween :many
+-- Retrieves report runs within a date range, oldest first.
+-- Used to compute the trailing baseline for anomaly detection.
+-- Parameters: $1 = first report_date (inclusive), $2 = last report_date (exclusive)
+SELECT * FROM report_runs
+WHERE report_date >= $1 AND report_date < $2
+ORDER BY report_date ASC;
+
+-- name: UpdateReportRunAnomaly :exec
+-- Marks a report run as anomalous (or clears the mark).
+-- Parameters: $1 = report_date (DATE), $2 = is_anomaly, $3 = anomaly_details
+UPDATE report_runs
+SET is_anomaly = $2,
+    anomaly_details = $3
+WHERE report_date = $1;
+
 -- name: UpdateAsanaTaskGid :exec
+-- Stores the Asana task GID and marks the report run as completed.
+-- Parameters: $1 = report_date (DATE), $2 = asana_task_gid (NULL if no task was created)
 UPDATE report_runs
-SET asana_task_gid = $2
+SET asana_task_gid = $2,
+    status = 'completed',
+    completed_at = NOW()
 WHERE report_date = $1;
 
 -- name: ReportRunExists :one
@@ -41,3 +84,24 @@ SELECT EXISTS(
 SELECT * FROM report_runs
 ORDER BY report_date DESC
 LIMIT 1;
+
+-- name: TryReportRunLock :one
+-- Takes the session-level advisory lock of the reports without waiting.
+-- Used to allow only one run to create or resume reports at a time, a run completes
+-- pending reports of any date so the lock isn't scoped to a report date. The lock is
+-- held by the connection until it's released or the session ends.
+SELECT pg_try_advisory_lock(hashtext('report_runs')) AS locked;
+
+-- name: ReleaseReportRunLock :one
+-- Releases the advisory lock taken by TryReportRunLock on the same connection.
+SELECT pg_advisory_unlock(hashtext('report_runs')) AS unlocked;
+
+-- name: ListCompletedReportRunsInTimeRange :many
+-- Retrieves completed report runs whose window overlaps a time range, ordered by window start.
+-- Used by the retention command to find periods that haven't been reported yet.
+-- Parameters: $1 = range start (inclusive), $2 = range end (exclusive)
+SELECT * FROM report_runs
+WHERE status = 'completed'
+  AND window_end > sqlc.arg(range_start)
+  AND window_start < sqlc.arg(range_end)
+ORDER BY window_start ASC;
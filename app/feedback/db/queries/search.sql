@@ -0,0 +1,42 @@
+-- name: SearchFeedback :many
+-- Full-text search over feedback messages using the indexed english vector, best matches first.
+-- The query uses the websearch syntax: words, "quoted phrases", OR and -excluded words.
+-- Matched words are wrapped in ** in the snippet, which holds up to 2 fragments of the message.
+-- Parameters: sentiment, range_start (inclusive) and range_end (exclusive) are optional filters
+SELECT
+    id,
+    created_at,
+    sentiment,
+    ts_rank_cd(message_search, query)::real AS rank,
+    ts_headline('english'::regconfig, COALESCE(message, ''), query,
+        'StartSel=**, StopSel=**, MaxFragments=2, MaxWords=20, MinWords=5, FragmentDelimiter=" ... "')::text AS snippet
+FROM feedback, websearch_to_tsquery('english'::regconfig, sqlc.arg(query)::text) AS query
+WHERE message_search @@ query
+  AND (sqlc.narg(sentiment)::sentiment_type IS NULL OR sentiment = sqlc.narg(sentiment)::sentiment_type)
+  AND (sqlc.narg(range_start)::timestamptz IS NULL OR created_at >= sqlc.narg(range_start)::timestamptz)
+  AND (sqlc.narg(range_end)::timestamptz IS NULL OR created_at < sqlc.narg(range_end)::timestamptz)
+ORDER BY rank DESC, created_at DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: SearchFeedbackWithConfig :many
+-- Full-text search like SearchFeedback with another text search configuration (e.g., german).
+-- The message is parsed on the fly, which can't use the GIN index, narrow the search with the date filters.
+SELECT
+    id,
+    created_at,
+    sentiment,
+    ts_rank_cd(to_tsvector(sqlc.arg(config)::text::regconfig, COALESCE(message, '')), query)::real AS rank,
+    ts_headline(sqlc.arg(config)::text::regconfig, COALESCE(message, ''), query,
+        'StartSel=**, StopSel=**, MaxFragments=2, MaxWords=20, MinWords=5, FragmentDelimiter=" ... "')::text AS snippet
+FROM feedback, websearch_to_tsquery(sqlc.arg(config)::text::regconfig, sqlc.arg(query)::text) AS query
+WHERE to_tsvector(sqlc.arg(config)::text::regconfig, COALESCE(message, '')) @@ query
+  AND (sqlc.narg(sentiment)::sentiment_type IS NULL OR sentiment = sqlc.narg(sentiment)::sentiment_type)
+  AND (sqlc.narg(range_start)::timestamptz IS NULL OR created_at >= sqlc.narg(range_start)::timestamptz)
+  AND (sqlc.narg(range_end)::timestamptz IS NULL OR created_at < sqlc.narg(range_end)::timestamptz)
+ORDER BY rank DESC, created_at DESC
+LIMIT sqlc.arg(max_results);
+
+-- name: TextSearchConfigExists :one
+-- Checks whether a text search configuration (e.g., english, german, simple) is installed.
+-- Used to reject unknown configurations before they are cast to regconfig, which fails the query.
+SELECT (to_regconfig(sqlc.arg(name)::text) IS NOT NULL)::boolean AS config_exists;
@@ -3,87 +3,343 @@ package analysis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"time"
 
 	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/findmyname666/ddg3/feedback/pkgs/rollup"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// reportLockPollInterval is how often a waiting run retries to take the report lock
+const reportLockPollInterval = 5 * time.Second
+
+// ErrRunInProgress is returned by Run when another run holds the report lock
+var ErrRunInProgress = errors.New("another analysis run is in progress")
+
 // Aggregator handles daily feedback aggregation
 type Aggregator struct {
 	pool           *pgxpool.Pool
 	queries        *db.Queries
+	router         *db.Router
+	rollup         *rollup.Rollup
 	asanaToken     string
 	asanaWorkspace string
 	asanaProject   string
+	asanaOptions   AsanaTaskOptions
+
+	slackWebhookURL  string
+	smtp             SMTPConfig
+	anomalyThreshold float64
+	sampleSize       int
+	topTerms         int
+	keywordDays      int
+	sentimentCheck   bool
+	persistScores    bool
+	topClusters      int
+	attachmentFormat ExportFormat
+	lockWait         time.Duration
+
+	// asanaBaseURL overrides the Asana API URL in tests
+	asanaBaseURL string
 }
 
 // Config holds the configuration for the aggregator
 type Config struct {
-	Pool              *pgxpool.Pool
+	Pool *pgxpool.Pool
+
+	// ReplicaPool is an optional read replica, read-only queries are routed to it
+	ReplicaPool *pgxpool.Pool
+	// MaxReplicaLag is the replication lag above which reads fall back to the primary
+	// Defaults to db.DefaultMaxReplicationLag when zero
+	MaxReplicaLag time.Duration
+
 	AsanaToken        string
 	AsanaWorkspaceGID string
 	AsanaProjectGID   string
+
+	// AsanaTask configures custom fields, section, assignee and due date of created tasks
+	AsanaTask AsanaTaskOptions
+
+	// SlackWebhookURL is an optional Slack or Mattermost incoming webhook URL
+	SlackWebhookURL string
+
+	// SMTP configures the optional email digest, disabled when Host is empty
+	SMTP SMTPConfig
+
+	// AnomalyThreshold is the z-score above which a deviation from the baseline is flagged
+	// Defaults to DefaultAnomalyThreshold when zero
+	AnomalyThreshold float64
+
+	// SampleSize is the number of negative and positive messages quoted in the report, 0 disables samples
+	SampleSize int
+
+	// TopTerms is the number of emerging terms reported per sentiment, 0 disables keyword extraction
+	TopTerms int
+	// KeywordBaselineDays is the length of the trailing corpus for keyword extraction
+	// Defaults to DefaultKeywordBaselineDays when zero
+	KeywordBaselineDays int
+
+	// SentimentCheck scores the message text and reports messages whose rating contradicts it
+	SentimentCheck bool
+	// PersistTextScores stores the text score of each message in feedback.text_score
+	PersistTextScores bool
+
+	// TopClusters is the number of near-duplicate clusters reported, 0 disables clustering
+	TopClusters int
+
+	// AttachmentFormat is the format of the feedback export attached to the Asana task, empty disables it
+	AttachmentFormat ExportFormat
+
+	// LockWait is how long Run waits for another run to finish, 0 fails immediately
+	LockWait time.Duration
 }
 
 // NewAggregator creates a new aggregator instance
 func NewAggregator(cfg Config) *Aggregator {
+	anomalyThreshold := cfg.AnomalyThreshold
+	if anomalyThreshold <= 0 {
+		anomalyThreshold = DefaultAnomalyThreshold
+	}
+
+	keywordDays := cfg.KeywordBaselineDays
+	if keywordDays <= 0 {
+		keywordDays = DefaultKeywordBaselineDays
+	}
+
+	router := db.NewRouter(cfg.Pool, cfg.ReplicaPool, cfg.MaxReplicaLag)
+
 	return &Aggregator{
 		pool:           cfg.Pool,
-		queries:        db.New(cfg.Pool),
+		queries:        router.Primary(),
+		router:         router,
+		rollup:         rollup.New(rollup.Config{Pool: cfg.Pool}),
 		asanaToken:     cfg.AsanaToken,
 		asanaWorkspace: cfg.AsanaWorkspaceGID,
 		asanaProject:   cfg.AsanaProjectGID,
+		asanaOptions:   cfg.AsanaTask,
+
+		slackWebhookURL:  cfg.SlackWebhookURL,
+		smtp:             cfg.SMTP,
+		anomalyThreshold: anomalyThreshold,
+		sampleSize:       cfg.SampleSize,
+		topTerms:         cfg.TopTerms,
+		keywordDays:      keywordDays,
+		sentimentCheck:   cfg.SentimentCheck,
+		persistScores:    cfg.PersistTextScores,
+		topClusters:      cfg.TopClusters,
+		attachmentFormat: cfg.AttachmentFormat,
+		lockWait:         cfg.LockWait,
 	}
 }
 
-// Run executes the daily aggregation job
+// Run executes the daily aggregation job.
+//
+// The report is created in two phases so that a failure never leads to duplicate Asana tasks:
+//  1. The run is recorded as a pending report_runs row within a transaction
+//  2. The Asana task is created and its GID is stored, completing the run
+//
+// Pending rows left behind by an interrupted run are resumed by the next run, a task created
+// before the interruption is found by its name in the Asana project and reused.
+// Concurrent runs are serialized by a single advisory lock, also covering the pending rows of
+// other dates, ErrRunInProgress is returned if the lock isn't released within the configured lock wait.
 func (a *Aggregator) Run(ctx context.Context) error {
 	slog.Info("Starting daily feedback aggregation...")
 
-	// Check if report run already exists
-	exists, err := a.dbReportExists(ctx)
+	// Calculate time window (last 24 hours in UTC)
+	windowStart, windowEnd := calculateTimeWindow()
+
+	// Only one run may create or resume reports
+	unlock, err := a.dbReportLock(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to check if report run exists: %w", err)
+		return err
 	}
+	defer unlock()
 
-	if exists {
-		slog.Info("Report run already exists, skipping aggregation")
+	// Bring the hourly rollup up to date, the counts are read from the raw feedback on failure
+	if _, err := a.rollup.Run(ctx); err != nil {
+		slog.Warn("Failed to update hourly rollup", "error", err)
+	}
 
-		return nil
+	// Record report run as pending
+	if err := a.dbReportClaim(ctx, windowStart, windowEnd); err != nil {
+		return fmt.Errorf("failed to record report run: %w", err)
 	}
 
-	// Calculate time window (last 24 hours in UTC)
-	windowStart, windowEnd := calculateTimeWindow()
+	// Complete all pending report runs, including ones interrupted by earlier runs
+	pending, err := a.queries.ListPendingReportRuns(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list pending report runs: %w", err)
+	}
+
+	for i := range pending {
+		if err := a.completeReport(ctx, &pending[i]); err != nil {
+			return fmt.Errorf("failed to complete report for %s: %w",
+				pending[i].ReportDate.Time.Format("2006-01-02"), err)
+		}
+	}
 
-	// Query feedback counts
-	counts, err := a.dbCountFeedback(ctx, windowStart, windowEnd)
+	return nil
+}
+
+// completeReport creates the Asana task for a pending report run and marks it as completed
+func (a *Aggregator) completeReport(ctx context.Context, report *db.ReportRun) error {
+	windowStart := report.WindowStart.Time
+	windowEnd := report.WindowEnd.Time
+	summary := reportSummary(report)
+
+	slog.Info("Completing pending report run",
+		"report_date", report.ReportDate.Time.Format("2006-01-02"))
+
+	// Load earlier reports for anomaly detection and trends, the report is created without them on failure
+	var history []ReportHistory
+
+	runs, err := a.dbReportHistory(ctx, report.ReportDate.Time, slices.Max(anomalyBaselineDays))
 	if err != nil {
-		return fmt.Errorf("failed to query feedback counts: %w", err)
+		slog.Warn("Failed to load report history, skipping anomaly detection and trends", "error", err)
+	} else {
+		// Compare against trailing baselines and mark anomalies
+		summary.Anomalies = a.detectReportAnomalies(ctx, report, summary, runs)
+
+		// Compare with the previous day and the same weekday last week
+		summary.Trends = compareTrends(summary, runs, report.ReportDate.Time)
+
+		history = reportHistory(runs)
 	}
 
+	// Quote what users actually said
+	a.analyzeMessages(ctx, report, summary)
+
 	// Create Asana task
-	asanaTaskGID, err := a.createAsanaTask(ctx, counts, windowStart, windowEnd)
+	asanaTaskGID, err := a.createAsanaTask(ctx, summary, history, windowStart, windowEnd)
 	if err != nil {
 		return fmt.Errorf("failed to create Asana task: %w", err)
 	}
 
-	// Create report run in database
-	report, err := a.dbReportCreate(
-		ctx,
-		windowStart, windowEnd,
-		counts.PositiveCount, counts.NegativeCount,
-		asanaTaskGID,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to create report: %w", err)
+	// Store Asana task GID and mark report run as completed
+	if err := a.dbReportComplete(ctx, report.ReportDate, asanaTaskGID); err != nil {
+		return fmt.Errorf("failed to complete report (Asana task %q was created): %w", asanaTaskGID, err)
 	}
 
 	slog.Info("Report created successfully",
-		"report_date", report.ReportDate,
+		"report_date", report.ReportDate.Time.Format("2006-01-02"),
 		"positive_count", report.PositiveCount,
-		"negative_count", report.NegativeCount)
+		"negative_count", report.NegativeCount,
+		"asana_task_gid", asanaTaskGID)
+
+	// Attach the raw feedback of the window to the task
+	a.attachFeedbackExport(ctx, report, asanaTaskGID)
+
+	// Post summary to chat, the report is already completed so a failure doesn't fail the run
+	if err := a.postSlackSummary(ctx, summary, windowStart, windowEnd, asanaTaskGID); err != nil {
+		slog.Warn("Failed to post Slack notification", "error", err)
+	}
+
+	// Email summary to stakeholders, a failure doesn't fail the run either
+	if err := a.sendEmailSummary(ctx, summary, windowStart, windowEnd, asanaTaskGID); err != nil {
+		slog.Warn("Failed to send email digest", "error", err)
+	}
 
 	return nil
 }
+
+// detectReportAnomalies compares the report against the trailing baselines and stores the result.
+// Storing the result is best effort, failures are logged and the report is created anyway.
+func (a *Aggregator) detectReportAnomalies(
+	ctx context.Context,
+	report *db.ReportRun,
+	summary *FeedbackSummary,
+	history []db.ReportRun,
+) []string {
+	reportDate := report.ReportDate.Time
+
+	anomalies := detectAnomalies(summary, history, reportDate, a.anomalyThreshold).Descriptions()
+
+	if err := a.dbReportMarkAnomaly(ctx, report.ReportDate, anomalies); err != nil {
+		slog.Warn("Failed to mark report anomaly", "error", err)
+	}
+
+	if len(anomalies) > 0 {
+		slog.Warn("Anomaly detected in daily feedback",
+			"report_date", reportDate.Format("2006-01-02"),
+			"anomalies", anomalies)
+	}
+
+	return anomalies
+}
+
+// analyzeMessages loads the messages of the report window and adds samples, near-duplicate clusters,
+// emerging terms and the sentiment check to the summary.
+// Text analysis is best effort, failures are logged and the report is created without it.
+func (a *Aggregator) analyzeMessages(ctx context.Context, report *db.ReportRun, summary *FeedbackSummary) {
+	if (a.sampleSize <= 0 && a.topTerms <= 0 && a.topClusters <= 0 && !a.sentimentCheck) || summary.Total == 0 {
+		return
+	}
+
+	windowStart := report.WindowStart.Time
+	windowEnd := report.WindowEnd.Time
+
+	messages, err := a.dbFeedbackMessages(ctx, windowStart, windowEnd, maxWindowMessages)
+	if err != nil {
+		slog.Warn("Failed to load feedback messages, skipping text analysis", "error", err)
+
+		return
+	}
+
+	// Seeding with the report date keeps the samples stable across runs
+	seed := report.ReportDate.Time.Format("2006-01-02")
+
+	summary.NegativeSamples = sampleMessages(messages, db.SentimentTypeNegative, a.sampleSize, seed)
+	summary.PositiveSamples = sampleMessages(messages, db.SentimentTypePositive, a.sampleSize, seed)
+
+	summary.Clusters = clusterMessages(messages, a.topClusters)
+
+	if a.sentimentCheck {
+		a.checkMessageSentiment(ctx, messages, summary)
+	}
+
+	if a.topTerms > 0 {
+		corpus, err := a.dbFeedbackMessages(ctx, windowStart.AddDate(0, 0, -a.keywordDays), windowStart, maxCorpusMessages)
+		if err != nil {
+			slog.Warn("Failed to load trailing corpus, skipping keyword extraction", "error", err)
+
+			return
+		}
+
+		summary.NegativeTerms = extractKeyTerms(messages, corpus, db.SentimentTypeNegative, a.topTerms)
+		summary.PositiveTerms = extractKeyTerms(messages, corpus, db.SentimentTypePositive, a.topTerms)
+	}
+}
+
+// checkMessageSentiment compares the rating of the messages with their text and optionally stores the scores
+func (a *Aggregator) checkMessageSentiment(ctx context.Context, messages []FeedbackMessage, summary *FeedbackSummary) {
+	summary.SentimentCheck = checkSentiment(messages)
+
+	slog.Info("Text sentiment checked",
+		"scored", summary.SentimentCheck.Scored,
+		"mismatches", len(summary.SentimentCheck.Mismatches),
+		"mismatch_rate", summary.SentimentCheck.MismatchRate())
+
+	if !a.persistScores {
+		return
+	}
+
+	if err := a.dbFeedbackTextScores(ctx, summary.SentimentCheck.Scores); err != nil {
+		slog.Warn("Failed to store text sentiment scores", "error", err)
+	}
+}
+
+// newAsanaClient creates an Asana client with the configured task options
+func (a *Aggregator) newAsanaClient() *AsanaClient {
+	client := newAsanaClient(a.asanaToken, a.asanaWorkspace, a.asanaProject)
+	client.options = a.asanaOptions
+
+	if a.asanaBaseURL != "" {
+		client.baseURL = a.asanaBaseURL
+	}
+
+	return client
+}
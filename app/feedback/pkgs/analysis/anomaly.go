@@ -0,0 +1,242 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+)
+
+const (
+	// DefaultAnomalyThreshold is the z-score above which a deviation is considered significant
+	DefaultAnomalyThreshold = 3.0
+
+	// anomalyMinBaselineReports is the minimum number of historical reports in a baseline window
+	anomalyMinBaselineReports = 5
+	// anomalyMinFeedback is the minimum number of feedback needed to test the negative rate
+	anomalyMinFeedback = 10
+	// anomalyNamePrefix marks the Asana task of an anomalous report
+	anomalyNamePrefix = "[ALERT] "
+)
+
+// anomalyBaselineDays are the trailing windows compared against the current report
+var anomalyBaselineDays = []int{7, 28}
+
+// AnomalyMetric identifies the measure that deviated from the baseline
+type AnomalyMetric string
+
+const (
+	AnomalyMetricNegativeRate AnomalyMetric = "negative_rate"
+	AnomalyMetricVolume       AnomalyMetric = "volume"
+)
+
+// Baseline holds the statistics of the trailing report runs
+type Baseline struct {
+	Days         int
+	Reports      int
+	Total        int64
+	Negative     int64
+	NegativeRate float64
+	VolumeMean   float64
+	VolumeStdDev float64
+}
+
+// AnomalySignal is a single statistically significant deviation
+type AnomalySignal struct {
+	Metric       AnomalyMetric
+	BaselineDays int
+	Observed     float64
+	Expected     float64
+	ZScore       float64
+}
+
+// String returns a human readable description of the signal
+func (s AnomalySignal) String() string {
+	direction := "above"
+	if s.ZScore < 0 {
+		direction = "below"
+	}
+
+	switch s.Metric {
+	case AnomalyMetricNegativeRate:
+		return fmt.Sprintf("Negative rate %.1f%% is %s the %d-day baseline of %.1f%% (z = %.1f)",
+			s.Observed*100, direction, s.BaselineDays, s.Expected*100, s.ZScore)
+	case AnomalyMetricVolume:
+		return fmt.Sprintf("Feedback volume %.0f is %s the %d-day average of %.1f (z = %.1f)",
+			s.Observed, direction, s.BaselineDays, s.Expected, s.ZScore)
+	default:
+		return fmt.Sprintf("%s deviates from the %d-day baseline (z = %.1f)", s.Metric, s.BaselineDays, s.ZScore)
+	}
+}
+
+// AnomalyResult holds the outcome of anomaly detection for a report
+type AnomalyResult struct {
+	Baselines []Baseline
+	Signals   []AnomalySignal
+}
+
+// Detected reports whether at least one significant deviation was found
+func (r *AnomalyResult) Detected() bool {
+	return r != nil && len(r.Signals) > 0
+}
+
+// Descriptions returns a human readable description of each signal
+func (r *AnomalyResult) Descriptions() []string {
+	if !r.Detected() {
+		return nil
+	}
+
+	descriptions := make([]string, 0, len(r.Signals))
+	for _, signal := range r.Signals {
+		descriptions = append(descriptions, signal.String())
+	}
+
+	return descriptions
+}
+
+// computeBaseline calculates the statistics of reports within the given number of days before reportDate
+func computeBaseline(history []db.ReportRun, reportDate time.Time, days int) Baseline {
+	baseline := Baseline{Days: days}
+	start := reportDate.AddDate(0, 0, -days)
+
+	var dailyTotals []int64
+
+	for _, report := range history {
+		date := report.ReportDate.Time
+		if date.Before(start) || !date.Before(reportDate) {
+			continue
+		}
+
+		total := int64(report.PositiveCount) + int64(report.NegativeCount)
+
+		baseline.Reports++
+		baseline.Total += total
+		baseline.Negative += int64(report.NegativeCount)
+		dailyTotals = append(dailyTotals, total)
+	}
+
+	if baseline.Reports == 0 {
+		return baseline
+	}
+
+	if baseline.Total > 0 {
+		baseline.NegativeRate = float64(baseline.Negative) / float64(baseline.Total)
+	}
+
+	baseline.VolumeMean = float64(baseline.Total) / float64(baseline.Reports)
+
+	var variance float64
+	for _, total := range dailyTotals {
+		variance += math.Pow(float64(total)-baseline.VolumeMean, 2)
+	}
+
+	if baseline.Reports > 1 {
+		baseline.VolumeStdDev = math.Sqrt(variance / float64(baseline.Reports-1))
+	}
+
+	return baseline
+}
+
+// detectAnomalies compares the report summary against trailing baselines.
+// The negative rate is tested with a binomial z-test, the volume with a z-score of daily totals.
+func detectAnomalies(
+	summary *FeedbackSummary,
+	history []db.ReportRun,
+	reportDate time.Time,
+	threshold float64,
+) *AnomalyResult {
+	result := &AnomalyResult{}
+
+	for _, days := range anomalyBaselineDays {
+		baseline := computeBaseline(history, reportDate, days)
+		result.Baselines = append(result.Baselines, baseline)
+
+		// Not enough history to tell what's normal
+		if baseline.Reports < anomalyMinBaselineReports {
+			continue
+		}
+
+		if signal, ok := testNegativeRate(summary, baseline, threshold); ok {
+			result.Signals = append(result.Signals, signal)
+		}
+
+		if signal, ok := testVolume(summary, baseline, threshold); ok {
+			result.Signals = append(result.Signals, signal)
+		}
+	}
+
+	return result
+}
+
+// testNegativeRate tests whether the observed negative count is plausible under the baseline rate
+func testNegativeRate(summary *FeedbackSummary, baseline Baseline, threshold float64) (AnomalySignal, bool) {
+	if summary.Total < anomalyMinFeedback {
+		return AnomalySignal{}, false
+	}
+
+	// Laplace smoothing keeps the variance positive when the baseline has no negatives (or only negatives)
+	expected := (float64(baseline.Negative) + 1) / (float64(baseline.Total) + 2)
+	n := float64(summary.Total)
+	stdDev := math.Sqrt(n * expected * (1 - expected))
+	z := (float64(summary.NegativeCount) - n*expected) / stdDev
+
+	if math.Abs(z) < threshold {
+		return AnomalySignal{}, false
+	}
+
+	return AnomalySignal{
+		Metric:       AnomalyMetricNegativeRate,
+		BaselineDays: baseline.Days,
+		Observed:     float64(summary.NegativeCount) / n,
+		Expected:     baseline.NegativeRate,
+		ZScore:       z,
+	}, true
+}
+
+// testVolume tests whether the observed feedback volume deviates from the baseline daily volume
+func testVolume(summary *FeedbackSummary, baseline Baseline, threshold float64) (AnomalySignal, bool) {
+	// Fall back to the Poisson standard deviation when the daily volume was constant
+	stdDev := baseline.VolumeStdDev
+	if stdDev == 0 {
+		stdDev = math.Sqrt(baseline.VolumeMean)
+	}
+
+	if stdDev == 0 {
+		return AnomalySignal{}, false
+	}
+
+	z := (float64(summary.Total) - baseline.VolumeMean) / stdDev
+
+	if math.Abs(z) < threshold {
+		return AnomalySignal{}, false
+	}
+
+	return AnomalySignal{
+		Metric:       AnomalyMetricVolume,
+		BaselineDays: baseline.Days,
+		Observed:     float64(summary.Total),
+		Expected:     baseline.VolumeMean,
+		ZScore:       z,
+	}, true
+}
+
+// formatAnomalyBanner creates the alert banner shown at the top of the Asana task notes
+func formatAnomalyBanner(anomalies []string) string {
+	if len(anomalies) == 0 {
+		return ""
+	}
+
+	var banner strings.Builder
+
+	banner.WriteString("⚠️ ALERT: Unusual feedback detected\n\n")
+
+	for _, anomaly := range anomalies {
+		banner.WriteString("• " + anomaly + "\n")
+	}
+
+	banner.WriteString("\n")
+
+	return banner.String()
+}
@@ -0,0 +1,204 @@
+package analysis
+
+import (
+	"context"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var anomalyReportDate = time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+// testHistory creates one report run per day before anomalyReportDate with the given counts
+func testHistory(counts ...[2]int32) []db.ReportRun {
+	history := make([]db.ReportRun, 0, len(counts))
+
+	for i, count := range counts {
+		date := anomalyReportDate.AddDate(0, 0, -(i + 1))
+		history = append(history, db.ReportRun{
+			ReportDate:    pgtype.Date{Time: date, Valid: true},
+			PositiveCount: count[0],
+			NegativeCount: count[1],
+			Status:        db.ReportStatusCompleted,
+		})
+	}
+
+	return history
+}
+
+// repeatCounts returns n copies of the given positive and negative counts
+func repeatCounts(n int, positive, negative int32) [][2]int32 {
+	counts := make([][2]int32, n)
+	for i := range counts {
+		counts[i] = [2]int32{positive, negative}
+	}
+
+	return counts
+}
+
+func testSummary(positive, negative int64) *FeedbackSummary {
+	return calculateFeedbackSummary(&db.CountFeedbackBySentimentRow{
+		PositiveCount: positive,
+		NegativeCount: negative,
+	})
+}
+
+func TestComputeBaseline(t *testing.T) {
+	history := testHistory([2]int32{80, 20}, [2]int32{60, 40}, [2]int32{90, 10})
+
+	// Reports outside of the window (and the report date itself) are ignored
+	for _, date := range []time.Time{anomalyReportDate, anomalyReportDate.AddDate(0, 0, -30)} {
+		history = append(history, db.ReportRun{ReportDate: pgtype.Date{Time: date, Valid: true}, NegativeCount: 1000})
+	}
+
+	baseline := computeBaseline(history, anomalyReportDate, 7)
+
+	if baseline.Reports != 3 {
+		t.Errorf("Expected 3 reports, got %d", baseline.Reports)
+	}
+
+	if baseline.Total != 300 || baseline.Negative != 70 {
+		t.Errorf("Expected total 300 and negative 70, got %d and %d", baseline.Total, baseline.Negative)
+	}
+
+	if math.Abs(baseline.NegativeRate-70.0/300.0) > 1e-9 {
+		t.Errorf("Expected negative rate %.4f, got %.4f", 70.0/300.0, baseline.NegativeRate)
+	}
+
+	if baseline.VolumeMean != 100 || baseline.VolumeStdDev != 0 {
+		t.Errorf("Expected volume mean 100 and stddev 0, got %.2f and %.2f",
+			baseline.VolumeMean, baseline.VolumeStdDev)
+	}
+}
+
+func TestDetectAnomalies(t *testing.T) {
+	stable := testHistory(repeatCounts(28, 90, 10)...)
+
+	tests := []struct {
+		name            string
+		summary         *FeedbackSummary
+		history         []db.ReportRun
+		expectedMetrics []AnomalyMetric
+	}{
+		{
+			name:    "stable",
+			summary: testSummary(88, 12),
+			history: stable,
+		},
+		{
+			name:            "negative rate spike",
+			summary:         testSummary(50, 50),
+			history:         stable,
+			expectedMetrics: []AnomalyMetric{AnomalyMetricNegativeRate, AnomalyMetricNegativeRate},
+		},
+		{
+			name:            "volume spike",
+			summary:         testSummary(270, 30),
+			history:         stable,
+			expectedMetrics: []AnomalyMetric{AnomalyMetricVolume, AnomalyMetricVolume},
+		},
+		{
+			name:    "insufficient history",
+			summary: testSummary(50, 50),
+			history: testHistory(repeatCounts(anomalyMinBaselineReports-1, 90, 10)...),
+		},
+		{
+			name:    "too little feedback to test negative rate",
+			summary: testSummary(1, 4),
+			history: testHistory(repeatCounts(28, 4, 1)...),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := detectAnomalies(tt.summary, tt.history, anomalyReportDate, DefaultAnomalyThreshold)
+
+			if len(result.Signals) != len(tt.expectedMetrics) {
+				t.Fatalf("Expected %d signals, got %+v", len(tt.expectedMetrics), result.Signals)
+			}
+
+			for i, signal := range result.Signals {
+				if signal.Metric != tt.expectedMetrics[i] {
+					t.Errorf("Expected signal %d to be %q, got %q", i, tt.expectedMetrics[i], signal.Metric)
+				}
+			}
+
+			if result.Detected() != (len(tt.expectedMetrics) > 0) {
+				t.Errorf("Expected Detected() to be %v", len(tt.expectedMetrics) > 0)
+			}
+		})
+	}
+}
+
+func TestAnomalySignal_String(t *testing.T) {
+	signal := AnomalySignal{
+		Metric:       AnomalyMetricNegativeRate,
+		BaselineDays: 7,
+		Observed:     0.5,
+		Expected:     0.1,
+		ZScore:       13.3,
+	}
+
+	expected := "Negative rate 50.0% is above the 7-day baseline of 10.0% (z = 13.3)"
+	if result := signal.String(); result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestFormatTaskTitle_Anomaly(t *testing.T) {
+	windowEnd := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	summary := testSummary(50, 50)
+	if result := formatTaskTitle(summary, windowEnd); result != "Daily Feedback Summary - 2024-06-15" {
+		t.Errorf("Expected title without prefix, got %q", result)
+	}
+
+	summary.Anomalies = []string{"Negative rate is high"}
+	if result := formatTaskTitle(summary, windowEnd); result != "[ALERT] Daily Feedback Summary - 2024-06-15" {
+		t.Errorf("Expected title with alert prefix, got %q", result)
+	}
+
+	notes := formatTaskNotes(summary, windowEnd.AddDate(0, 0, -1), windowEnd)
+	if !strings.HasPrefix(notes, "⚠️ ALERT: Unusual feedback detected\n\n• Negative rate is high\n") {
+		t.Errorf("Expected notes to start with the alert banner, got %q", notes)
+	}
+}
+
+func TestFormatAnomalyBanner_Empty(t *testing.T) {
+	if result := formatAnomalyBanner(nil); result != "" {
+		t.Errorf("Expected empty banner, got %q", result)
+	}
+}
+
+func TestAsanaClient_CheckReport_Anomaly(t *testing.T) {
+	server := httptest.NewServer(newFakeAsana())
+	defer server.Close()
+
+	client := newTestAsanaClient(server)
+	ctx := context.Background()
+
+	report := testReportRun(50, 50, "")
+	report.IsAnomaly = true
+	report.AnomalyDetails = pgtype.Text{String: "Negative rate is high\nVolume is high", Valid: true}
+
+	summary := reportSummary(report)
+	if len(summary.Anomalies) != 2 {
+		t.Fatalf("Expected 2 anomalies from the report run, got %v", summary.Anomalies)
+	}
+
+	gid, err := client.createTask(ctx, summary, nil, report.WindowStart.Time, report.WindowEnd.Time)
+	if err != nil {
+		t.Fatalf("createTask failed: %v", err)
+	}
+
+	// A task created with the alert prefix must match the anomalous report
+	report.AsanaTaskGid = pgtype.Text{String: gid, Valid: true}
+	if issue, err := client.checkReport(ctx, report); err != nil || issue != nil {
+		t.Errorf("Expected anomalous task to match, got %+v (err: %v)", issue, err)
+	}
+}
@@ -4,12 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"net/url"
+	"path"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/findmyname666/ddg3/feedback/pkgs/db"
 )
@@ -18,6 +23,11 @@ const (
 	asanaAPIBaseURL     = "https://app.asana.com/api/1.0"
 	asanaTasksEndpoint  = "/tasks"
 	asanaRequestTimeout = 30 * time.Second
+	asanaTaskListLimit  = 100
+
+	// asanaMaxNotesLength keeps the notes safely below Asana's limit of 65,535 characters
+	asanaMaxNotesLength = 60000
+	asanaNotesFooter    = "\n\nThis report was automatically generated by the feedback analysis job."
 )
 
 // AsanaTaskRequest represents the request body for creating an Asana task
@@ -27,11 +37,22 @@ type AsanaTaskRequest struct {
 
 // AsanaTaskData contains the task details
 type AsanaTaskData struct {
-	Workspace string   `json:"workspace"`           // Required: workspace GID
-	Name      string   `json:"name"`                // Required: task name
-	Notes     string   `json:"notes,omitempty"`     // Optional: task description
-	Completed bool     `json:"completed,omitempty"` // Optional: completion status
-	Projects  []string `json:"projects,omitempty"`  // Optional: project GIDs to add task to
+	Workspace    string            `json:"workspace"`               // Required: workspace GID
+	Name         string            `json:"name"`                    // Required: task name
+	Notes        string            `json:"notes,omitempty"`         // Optional: task description
+	HTMLNotes    string            `json:"html_notes,omitempty"`    // Optional: rich text task description
+	Completed    bool              `json:"completed,omitempty"`     // Optional: completion status
+	Projects     []string          `json:"projects,omitempty"`      // Optional: project GIDs to add task to
+	Memberships  []AsanaMembership `json:"memberships,omitempty"`   // Optional: project and section placement
+	Assignee     string            `json:"assignee,omitempty"`      // Optional: user GID or email
+	DueOn        string            `json:"due_on,omitempty"`        // Optional: due date (YYYY-MM-DD)
+	CustomFields map[string]any    `json:"custom_fields,omitempty"` // Optional: custom field values by GID
+}
+
+// AsanaMembership places a task in a section of a project
+type AsanaMembership struct {
+	Project string `json:"project"`
+	Section string `json:"section,omitempty"`
 }
 
 // AsanaTaskResponse represents the response from Asana API
@@ -39,11 +60,27 @@ type AsanaTaskResponse struct {
 	Data AsanaTaskResponseData `json:"data"`
 }
 
-// AsanaTaskResponseData contains the created task details
+// AsanaTaskResponseData contains the task details
 type AsanaTaskResponseData struct {
-	GID string `json:"gid"`
+	GID   string `json:"gid"`
+	Name  string `json:"name,omitempty"`
+	Notes string `json:"notes,omitempty"`
+}
+
+// AsanaTaskListResponse represents a page of tasks returned by Asana API
+type AsanaTaskListResponse struct {
+	Data     []AsanaTaskResponseData `json:"data"`
+	NextPage *AsanaNextPage          `json:"next_page"`
+}
+
+// AsanaNextPage points to the next page of a paginated response, nil on the last page
+type AsanaNextPage struct {
+	Offset string `json:"offset"`
 }
 
+// errNoTaskLookup is returned by findTask when no project is configured
+var errNoTaskLookup = errors.New("asana tasks can only be looked up in a project")
+
 // FeedbackSummary contains the aggregated feedback data
 type FeedbackSummary struct {
 	PositiveCount   int64
@@ -51,6 +88,20 @@ type FeedbackSummary struct {
 	Total           int64
 	PositivePercent float64
 	NegativePercent float64
+	// Anomalies describes significant deviations from the trailing baseline, empty if there are none
+	Anomalies []string
+	// Trends compares the report with the previous day and the same weekday last week
+	Trends []Trend
+	// NegativeSamples and PositiveSamples are messages sampled from the report window
+	NegativeSamples []string
+	PositiveSamples []string
+	// NegativeTerms and PositiveTerms are emerging terms compared to the trailing corpus
+	NegativeTerms []KeyTerm
+	PositiveTerms []KeyTerm
+	// SentimentCheck compares the ratings with the message text, nil if it wasn't run
+	SentimentCheck *SentimentCheck
+	// Clusters are the largest groups of near-duplicate messages
+	Clusters []MessageCluster
 }
 
 // AsanaClient handles communication with Asana API
@@ -60,6 +111,8 @@ type AsanaClient struct {
 	projectGID   string
 	httpClient   *http.Client
 	baseURL      string
+	retry        retryPolicy
+	options      AsanaTaskOptions
 }
 
 // newAsanaClient creates a new Asana client
@@ -70,13 +123,15 @@ func newAsanaClient(token, workspaceGID, projectGID string) *AsanaClient {
 		projectGID:   projectGID,
 		httpClient:   &http.Client{Timeout: asanaRequestTimeout},
 		baseURL:      asanaAPIBaseURL,
+		retry:        defaultRetryPolicy(),
 	}
 }
 
 // createAsanaTask creates a task in Asana with the aggregation results
 func (a *Aggregator) createAsanaTask(
 	ctx context.Context,
-	counts *db.CountFeedbackBySentimentRow,
+	summary *FeedbackSummary,
+	history []ReportHistory,
 	windowStart, windowEnd time.Time,
 ) (string, error) {
 	// Skip if no Asana credentials (workspace is required)
@@ -84,8 +139,6 @@ func (a *Aggregator) createAsanaTask(
 		return "", fmt.Errorf("asana credentials not provided")
 	}
 
-	// Calculate summary
-	summary := calculateFeedbackSummary(counts)
 	if summary.Total == 0 {
 		slog.Info("No feedback to report, skipping Asana task creation")
 
@@ -93,10 +146,10 @@ func (a *Aggregator) createAsanaTask(
 	}
 
 	// Create Asana client
-	client := newAsanaClient(a.asanaToken, a.asanaWorkspace, a.asanaProject)
+	client := a.newAsanaClient()
 
-	// Create task
-	taskGID, err := client.createTask(ctx, summary, windowStart, windowEnd)
+	// Create task, unless an earlier run created it already
+	taskGID, err := client.findOrCreateTask(ctx, summary, history, windowStart, windowEnd)
 	if err != nil {
 		return "", err
 	}
@@ -132,18 +185,32 @@ func formatTaskName(windowEnd time.Time) string {
 	return fmt.Sprintf("Daily Feedback Summary - %s", windowEnd.Format("2006-01-02"))
 }
 
+// formatTaskTitle creates the Asana task title, marking reports with anomalies
+func formatTaskTitle(summary *FeedbackSummary, windowEnd time.Time) string {
+	if len(summary.Anomalies) > 0 {
+		return anomalyNamePrefix + formatTaskName(windowEnd)
+	}
+
+	return formatTaskName(windowEnd)
+}
+
 // formatTaskNotes creates the Asana task description
 func formatTaskNotes(summary *FeedbackSummary, windowStart, windowEnd time.Time) string {
-	return fmt.Sprintf(`Feedback Summary Report
+	notes := formatNotesHeader(summary, windowStart, windowEnd)
+
+	return notes + strings.Join(fitNoteSections(notes, summary), "") + asanaNotesFooter
+}
+
+// formatNotesHeader creates the anomaly banner, counts and trends at the top of the task description
+func formatNotesHeader(summary *FeedbackSummary, windowStart, windowEnd time.Time) string {
+	return formatAnomalyBanner(summary.Anomalies) + fmt.Sprintf(`Feedback Summary Report
 
 Window: %s to %s (UTC)
 
 Results:
 • Positive: %d (%.1f%%)
 • Negative: %d (%.1f%%)
-• Total: %d
-
-This report was automatically generated by the feedback analysis job.`,
+• Total: %d%s`,
 		windowStart.UTC().Format("2006-01-02 15:04"),
 		windowEnd.UTC().Format("2006-01-02 15:04"),
 		summary.PositiveCount,
@@ -151,23 +218,78 @@ This report was automatically generated by the feedback analysis job.`,
 		summary.NegativeCount,
 		summary.NegativePercent,
 		summary.Total,
+		formatTrends(summary.Trends),
 	)
 }
 
-// buildTaskRequest creates an Asana task request
+// fitNoteSections returns the optional sections that fit below the header,
+// sections are dropped when they would exceed Asana's size limit
+func fitNoteSections(header string, summary *FeedbackSummary) []string {
+	length := utf8.RuneCountInString(header) + utf8.RuneCountInString(asanaNotesFooter)
+
+	var sections []string
+
+	for _, section := range formatNoteSections(summary) {
+		sectionLength := utf8.RuneCountInString(section)
+		if section == "" || length+sectionLength > asanaMaxNotesLength {
+			continue
+		}
+
+		length += sectionLength
+		sections = append(sections, section)
+	}
+
+	return sections
+}
+
+// formatNoteSections creates the optional sections of the Asana task notes in display order
+func formatNoteSections(summary *FeedbackSummary) []string {
+	return []string{
+		formatKeyTerms("Emerging terms in negative feedback", summary.NegativeTerms),
+		formatKeyTerms("Emerging terms in positive feedback", summary.PositiveTerms),
+		formatClusters(summary.Clusters),
+		formatSentimentCheck(summary.SentimentCheck),
+		formatSamples("Negative feedback samples", summary.NegativeSamples),
+		formatSamples("Positive feedback samples", summary.PositiveSamples),
+	}
+}
+
+// buildTaskRequest creates an Asana task request, the history of earlier reports is only used by notes templates
 func (c *AsanaClient) buildTaskRequest(
 	summary *FeedbackSummary,
+	history []ReportHistory,
 	windowStart, windowEnd time.Time,
 ) AsanaTaskRequest {
 	taskData := AsanaTaskData{
-		Workspace: c.workspaceGID,
-		Name:      formatTaskName(windowEnd),
-		Notes:     formatTaskNotes(summary, windowStart, windowEnd),
-		Completed: false,
+		Workspace:    c.workspaceGID,
+		Name:         formatTaskTitle(summary, windowEnd),
+		Notes:        formatTaskNotes(summary, windowStart, windowEnd),
+		Completed:    false,
+		Assignee:     c.options.assignee(windowEnd),
+		DueOn:        c.options.dueOn(windowEnd),
+		CustomFields: c.options.customFieldValues(summary),
+	}
+
+	// Custom templates replace the built-in notes, which are kept if the template fails for this report
+	if tmpl := c.options.NotesTemplate; tmpl != nil {
+		notes, err := tmpl.render(newNotesData(summary, history, windowStart, windowEnd))
+
+		switch {
+		case err != nil:
+			slog.Warn("Failed to render notes template, using built-in notes", "error", err)
+		case tmpl.IsHTML():
+			taskData.Notes = ""
+			taskData.HTMLNotes = notes
+		default:
+			taskData.Notes = notes
+		}
 	}
 
-	// Add project if specified
-	if c.projectGID != "" {
+	// Add project if specified, memberships are used to place the task in a section
+	switch {
+	case c.projectGID != "" && c.options.SectionGID != "":
+		taskData.Memberships = []AsanaMembership{{Project: c.projectGID, Section: c.options.SectionGID}}
+	case c.projectGID != "":
 		taskData.Projects = []string{c.projectGID}
 	}
 
@@ -176,14 +298,17 @@ func (c *AsanaClient) buildTaskRequest(
 	}
 }
 
-// createTask creates a task in Asana and returns the task GID
+// createTask creates a task in Asana and returns the task GID.
+// A request that failed after reaching Asana may still have created the task, the task is looked up
+// by its name before the request is sent again so that no duplicate is created.
 func (c *AsanaClient) createTask(
 	ctx context.Context,
 	summary *FeedbackSummary,
+	history []ReportHistory,
 	windowStart, windowEnd time.Time,
 ) (string, error) {
 	// Build request
-	taskRequest := c.buildTaskRequest(summary, windowStart, windowEnd)
+	taskRequest := c.buildTaskRequest(summary, history, windowStart, windowEnd)
 
 	// Marshal to JSON
 	jsonData, err := json.Marshal(taskRequest)
@@ -191,42 +316,218 @@ func (c *AsanaClient) createTask(
 		return "", fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	// Send HTTP request
-	response, err := c.sendRequest(ctx, jsonData)
-	if err != nil {
-		return "", err
+	ctx, cancel := c.retry.withDeadline(ctx)
+	defer cancel()
+
+	for attempt := 0; ; attempt++ {
+		// Send HTTP request, requests that didn't reach Asana are retried by sendRequest
+		response, err := c.sendRequest(ctx, jsonData)
+		if err == nil {
+			// Validate response
+			if response.Data.GID == "" {
+				return "", fmt.Errorf("no task GID in response")
+			}
+
+			return response.Data.GID, nil
+		}
+
+		if attempt >= c.retry.maxRetries || ctx.Err() != nil || !isTransientAsanaError(err) {
+			return "", err
+		}
+
+		taskGID, lookupErr := c.findTask(ctx, windowEnd)
+		if lookupErr != nil {
+			return "", fmt.Errorf("%w (looking up the task failed: %w)", err, lookupErr)
+		}
+
+		if taskGID != "" {
+			slog.Info("Asana task was created although the request failed",
+				"task_gid", taskGID,
+				"error", err)
+
+			return taskGID, nil
+		}
+
+		delay := c.retry.backoff(attempt)
+
+		slog.Warn("Asana task wasn't created, retrying",
+			"attempt", attempt+1,
+			"delay", delay,
+			"error", err)
+
+		if err := sleep(ctx, delay); err != nil {
+			return "", fmt.Errorf("asana request aborted after %d attempts: %w", attempt+1, err)
+		}
 	}
+}
+
+// findOrCreateTask returns the report task of windowEnd, creating it if it doesn't exist yet.
+// A pending report may already have a task when an earlier run failed after creating the task
+// but before its GID was stored.
+func (c *AsanaClient) findOrCreateTask(
+	ctx context.Context,
+	summary *FeedbackSummary,
+	history []ReportHistory,
+	windowStart, windowEnd time.Time,
+) (string, error) {
+	taskGID, err := c.findTask(ctx, windowEnd)
 
-	// Validate response
-	if response.Data.GID == "" {
-		return "", fmt.Errorf("no task GID in response")
+	switch {
+	case errors.Is(err, errNoTaskLookup):
+		slog.Debug("No Asana project configured, can't check for a task created by an earlier run")
+	case err != nil:
+		return "", fmt.Errorf("failed to look up existing Asana task: %w", err)
+	case taskGID != "":
+		slog.Info("Asana task of the report already exists", "task_gid", taskGID)
+
+		return taskGID, nil
 	}
 
-	return response.Data.GID, nil
+	return c.createTask(ctx, summary, history, windowStart, windowEnd)
 }
 
-// sendRequest sends an HTTP request to Asana API
+// findTask returns the GID of the report task of windowEnd in the project, or an empty string.
+// Only tasks modified since windowEnd are listed, report tasks are created after their window ends.
+// errNoTaskLookup is returned when no project is configured, tasks can only be listed per project.
+func (c *AsanaClient) findTask(ctx context.Context, windowEnd time.Time) (string, error) {
+	if c.projectGID == "" {
+		return "", errNoTaskLookup
+	}
+
+	// Anomalous reports have a prefix, the name of the report date is always at the end
+	name := formatTaskName(windowEnd)
+
+	query := url.Values{
+		"project":        {c.projectGID},
+		"modified_since": {windowEnd.UTC().Format(time.RFC3339)},
+		"opt_fields":     {"name"},
+		"limit":          {strconv.Itoa(asanaTaskListLimit)},
+	}
+
+	for {
+		body, err := c.do(ctx, http.MethodGet, asanaTasksEndpoint+"?"+query.Encode(), "", nil, http.StatusOK)
+		if err != nil {
+			return "", err
+		}
+
+		var response AsanaTaskListResponse
+		if err := json.Unmarshal(body, &response); err != nil {
+			return "", fmt.Errorf("failed to parse response: %w", err)
+		}
+
+		for _, task := range response.Data {
+			if strings.HasSuffix(task.Name, name) {
+				return task.GID, nil
+			}
+		}
+
+		if response.NextPage == nil || response.NextPage.Offset == "" {
+			return "", nil
+		}
+
+		query.Set("offset", response.NextPage.Offset)
+	}
+}
+
+// sendRequest sends a create task request to Asana API
 func (c *AsanaClient) sendRequest(ctx context.Context, jsonData []byte) (*AsanaTaskResponse, error) {
-	// Create HTTP request
-	url, err := url.JoinPath(c.baseURL, asanaTasksEndpoint)
+	body, err := c.do(ctx, http.MethodPost, asanaTasksEndpoint, "application/json", jsonData, http.StatusCreated)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse response
+	var response AsanaTaskResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	return &response, nil
+}
+
+// do sends an HTTP request to Asana API, retrying transient failures.
+// Network errors and 5xx responses are retried with exponential backoff and jitter,
+// 429 responses are retried after the delay requested by the Retry-After header.
+// POST requests are only retried when they didn't reach Asana, see isRetryableAsanaError.
+// All attempts share a single deadline. The endpoint may include a query string.
+func (c *AsanaClient) do(
+	ctx context.Context,
+	method, endpoint, contentType string,
+	payload []byte,
+	expectedStatus int,
+) ([]byte, error) {
+	endpointPath, query, _ := strings.Cut(endpoint, "?")
+
+	url, err := url.JoinPath(c.baseURL, endpointPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build Asana URL: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodPost,
-		url,
-		bytes.NewBuffer(jsonData),
-	)
+	if query != "" {
+		url += "?" + query
+	}
+
+	ctx, cancel := c.retry.withDeadline(ctx)
+	defer cancel()
+
+	for attempt := 0; ; attempt++ {
+		body, err := c.doOnce(ctx, method, url, contentType, payload, expectedStatus)
+		if err == nil {
+			return body, nil
+		}
+
+		if attempt >= c.retry.maxRetries || !isRetryableAsanaError(ctx, method, err) {
+			return nil, err
+		}
+
+		delay := c.retry.backoff(attempt)
+
+		var asanaErr *AsanaError
+		if errors.As(err, &asanaErr) && asanaErr.RetryAfter > 0 {
+			delay = asanaErr.RetryAfter
+		}
+
+		// Don't wait if the next attempt can't finish before the deadline
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, fmt.Errorf("asana request deadline exceeded after %d attempts: %w", attempt+1, err)
+		}
+
+		slog.Warn("Asana request failed, retrying",
+			"method", method,
+			"endpoint", endpoint,
+			"attempt", attempt+1,
+			"delay", delay,
+			"error", err)
+
+		if sleep(ctx, delay) != nil {
+			return nil, fmt.Errorf("asana request aborted after %d attempts: %w", attempt+1, err)
+		}
+	}
+}
+
+// doOnce performs a single HTTP request to Asana API
+func (c *AsanaClient) doOnce(
+	ctx context.Context,
+	method, url, contentType string,
+	payload []byte,
+	expectedStatus int,
+) ([]byte, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		reqBody = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request to %s: %w", url, err)
 	}
 
 	// Set headers
 	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
 
 	// Send request
 	resp, err := c.httpClient.Do(req)
@@ -246,15 +547,37 @@ func (c *AsanaClient) sendRequest(ctx context.Context, jsonData []byte) (*AsanaT
 	}
 
 	// Check status code
-	if resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("asana API returned status %d: %s", resp.StatusCode, string(body))
+	if resp.StatusCode != expectedStatus {
+		return nil, newAsanaError(resp, body)
+	}
+
+	return body, nil
+}
+
+// getTask fetches a task from Asana by its GID
+func (c *AsanaClient) getTask(ctx context.Context, taskGID string) (*AsanaTaskResponseData, error) {
+	body, err := c.do(ctx, http.MethodGet, path.Join(asanaTasksEndpoint, taskGID), "", nil, http.StatusOK)
+	if err != nil {
+		return nil, err
 	}
 
-	// Parse response
 	var response AsanaTaskResponse
 	if err := json.Unmarshal(body, &response); err != nil {
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
-	return &response, nil
+	return &response.Data, nil
+}
+
+// renameTask replaces the name of an existing Asana task.
+// The notes are left alone, they contain sections that can't be rendered from the stored report.
+func (c *AsanaClient) renameTask(ctx context.Context, taskGID, name string) error {
+	jsonData, err := json.Marshal(map[string]any{"data": map[string]any{"name": name}})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	_, err = c.do(ctx, http.MethodPut, path.Join(asanaTasksEndpoint, taskGID), "application/json", jsonData, http.StatusOK)
+
+	return err
 }
@@ -0,0 +1,156 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const asanaAttachmentsEndpoint = "/attachments"
+
+// AsanaAttachmentResponse represents the response from Asana API to an attachment upload
+type AsanaAttachmentResponse struct {
+	Data struct {
+		GID  string `json:"gid"`
+		Name string `json:"name"`
+	} `json:"data"`
+}
+
+// attachFeedbackExport uploads the feedback of the report window to the Asana task.
+// The task already exists at this point, so failures are logged instead of failing the run.
+func (a *Aggregator) attachFeedbackExport(ctx context.Context, report *db.ReportRun, taskGID string) {
+	if a.attachmentFormat == "" || taskGID == "" {
+		return
+	}
+
+	content, err := a.exportFeedback(ctx, report.WindowStart.Time, report.WindowEnd.Time)
+	if err != nil {
+		slog.Warn("Failed to export feedback, skipping Asana attachment", "error", err)
+
+		return
+	}
+
+	filename := fmt.Sprintf("feedback-%s.%s", report.ReportDate.Time.Format("2006-01-02"), a.attachmentFormat)
+	client := a.newAsanaClient()
+
+	attachmentGID, err := client.uploadAttachment(ctx, taskGID, filename, a.attachmentFormat.ContentType(), content)
+	if err != nil {
+		slog.Warn("Failed to attach feedback export to Asana task", "task_gid", taskGID, "error", err)
+
+		return
+	}
+
+	slog.Info("Feedback export attached to Asana task",
+		"task_gid", taskGID,
+		"attachment_gid", attachmentGID,
+		"filename", filename,
+		"size", len(content))
+}
+
+// exportFeedback renders the feedback of the time window in the configured attachment format
+func (a *Aggregator) exportFeedback(ctx context.Context, windowStart, windowEnd time.Time) ([]byte, error) {
+	rows, err := a.router.Read(ctx).GetFeedbackInTimeRange(ctx, db.GetFeedbackInTimeRangeParams{
+		CreatedAt:   pgtype.Timestamptz{Time: windowStart, Valid: true},
+		CreatedAt_2: pgtype.Timestamptz{Time: windowEnd, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feedback from DB: %w", err)
+	}
+
+	// Both queries select the same columns
+	feedback := make([]db.ExportFeedbackRow, len(rows))
+	for i := range rows {
+		feedback[i] = db.ExportFeedbackRow(rows[i])
+	}
+
+	return encodeFeedback(feedback, a.attachmentFormat)
+}
+
+// encodeFeedback renders feedback records in an export format
+func encodeFeedback(rows []db.ExportFeedbackRow, format ExportFormat) ([]byte, error) {
+	var buf bytes.Buffer
+
+	writer, err := NewFeedbackWriter(&buf, format)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range rows {
+		if err := writer.Write(&rows[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// uploadAttachment attaches a file to an Asana task and returns the attachment GID
+func (c *AsanaClient) uploadAttachment(
+	ctx context.Context,
+	taskGID, filename, contentType string,
+	content []byte,
+) (string, error) {
+	payload, formContentType, err := buildAttachmentForm(taskGID, filename, contentType, content)
+	if err != nil {
+		return "", err
+	}
+
+	body, err := c.do(ctx, http.MethodPost, asanaAttachmentsEndpoint, formContentType, payload, http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+
+	var response AsanaAttachmentResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if response.Data.GID == "" {
+		return "", fmt.Errorf("no attachment GID in response")
+	}
+
+	return response.Data.GID, nil
+}
+
+// buildAttachmentForm creates the multipart/form-data body of an attachment upload
+func buildAttachmentForm(taskGID, filename, contentType string, content []byte) ([]byte, string, error) {
+	var buf bytes.Buffer
+
+	form := multipart.NewWriter(&buf)
+
+	if err := form.WriteField("parent", taskGID); err != nil {
+		return nil, "", fmt.Errorf("failed to write form field: %w", err)
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, filename))
+	header.Set("Content-Type", contentType)
+
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create form file: %w", err)
+	}
+
+	if _, err := part.Write(content); err != nil {
+		return nil, "", fmt.Errorf("failed to write form file: %w", err)
+	}
+
+	if err := form.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to close form: %w", err)
+	}
+
+	return buf.Bytes(), form.FormDataContentType(), nil
+}
@@ -0,0 +1,125 @@
+package analysis
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+// uploadedAttachment is an attachment received by the fake Asana server
+type uploadedAttachment struct {
+	parent      string
+	filename    string
+	contentType string
+	content     string
+}
+
+// newFakeAttachmentServer creates an Asana server accepting attachment uploads.
+// The first failures requests are rejected with 429 to exercise retries, uploads are only retried
+// when Asana didn't process them.
+func newFakeAttachmentServer(t *testing.T, failures int32, uploads chan<- uploadedAttachment) *httptest.Server {
+	t.Helper()
+
+	var attempts atomic.Int32
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/attachments" {
+			t.Errorf("Expected POST /attachments, got %s %s", r.Method, r.URL.Path)
+		}
+
+		if auth := r.Header.Get("Authorization"); auth != "Bearer test-token" {
+			t.Errorf("Expected Authorization 'Bearer test-token', got %q", auth)
+		}
+
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("Failed to parse multipart form: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		if attempts.Add(1) <= failures {
+			w.WriteHeader(http.StatusTooManyRequests)
+
+			return
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("Expected file part: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		content, _ := io.ReadAll(file)
+		uploads <- uploadedAttachment{
+			parent:      r.FormValue("parent"),
+			filename:    header.Filename,
+			contentType: header.Header.Get("Content-Type"),
+			content:     string(content),
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data":{"gid":"att-1","name":"` + header.Filename + `"}}`))
+	}))
+}
+
+func TestAsanaClient_UploadAttachment(t *testing.T) {
+	uploads := make(chan uploadedAttachment, 1)
+	server := newFakeAttachmentServer(t, 0, uploads)
+	defer server.Close()
+
+	content, err := encodeFeedback(testFeedbackRows(), ExportFormatCSV)
+	if err != nil {
+		t.Fatalf("encodeFeedback failed: %v", err)
+	}
+
+	gid, err := newTestAsanaClient(server).uploadAttachment(
+		context.Background(), "1234567890", "feedback-2024-06-15.csv", ExportFormatCSV.ContentType(), content)
+	if err != nil {
+		t.Fatalf("uploadAttachment failed: %v", err)
+	}
+
+	if gid != "att-1" {
+		t.Errorf("Expected attachment GID 'att-1', got %q", gid)
+	}
+
+	upload := <-uploads
+
+	if upload.parent != "1234567890" {
+		t.Errorf("Expected parent '1234567890', got %q", upload.parent)
+	}
+
+	if upload.filename != "feedback-2024-06-15.csv" {
+		t.Errorf("Expected filename 'feedback-2024-06-15.csv', got %q", upload.filename)
+	}
+
+	if upload.contentType != "text/csv" {
+		t.Errorf("Expected content type 'text/csv', got %q", upload.contentType)
+	}
+
+	if upload.content != string(content) {
+		t.Errorf("Expected uploaded content %q, got %q", string(content), upload.content)
+	}
+}
+
+func TestAsanaClient_UploadAttachment_Retries(t *testing.T) {
+	uploads := make(chan uploadedAttachment, 1)
+	server := newFakeAttachmentServer(t, 2, uploads)
+	defer server.Close()
+
+	_, err := newTestAsanaClient(server).uploadAttachment(
+		context.Background(), "1234567890", "feedback.jsonl", ExportFormatJSONL.ContentType(), []byte("{}\n"))
+	if err != nil {
+		t.Fatalf("uploadAttachment failed: %v", err)
+	}
+
+	// The whole form must be sent again on retry
+	if upload := <-uploads; upload.content != "{}\n" || upload.parent != "1234567890" {
+		t.Errorf("Expected complete upload after retries, got %+v", upload)
+	}
+}
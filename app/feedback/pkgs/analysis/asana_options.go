@@ -0,0 +1,114 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CustomFieldMetric is a report value that can be stored in an Asana custom field
+type CustomFieldMetric string
+
+const (
+	CustomFieldPositive        CustomFieldMetric = "positive"
+	CustomFieldNegative        CustomFieldMetric = "negative"
+	CustomFieldTotal           CustomFieldMetric = "total"
+	CustomFieldNegativePercent CustomFieldMetric = "negative_percent"
+)
+
+// customFieldMetrics lists the supported metrics in a stable order
+var customFieldMetrics = []CustomFieldMetric{
+	CustomFieldPositive,
+	CustomFieldNegative,
+	CustomFieldTotal,
+	CustomFieldNegativePercent,
+}
+
+// AsanaTaskOptions configures optional properties of created Asana tasks
+type AsanaTaskOptions struct {
+	// CustomFields maps report metrics to the GIDs of Asana number custom fields
+	CustomFields map[CustomFieldMetric]string
+	// SectionGID places the task in a section of the project
+	SectionGID string
+	// Assignees is the on-call rotation, one assignee (user GID or email) per day
+	Assignees []string
+	// DueDays sets the due date to the given number of days after the report date, 0 sets no due date
+	DueDays int
+	// NotesTemplate replaces the built-in task description, nil keeps the built-in one
+	NotesTemplate *NotesTemplate
+}
+
+// ParseCustomFields parses "metric=field_gid" mappings, e.g. "negative_percent=1204567890"
+func ParseCustomFields(mappings []string) (map[CustomFieldMetric]string, error) {
+	fields := map[CustomFieldMetric]string{}
+
+	for _, mapping := range mappings {
+		name, gid, ok := strings.Cut(mapping, "=")
+		metric := CustomFieldMetric(strings.TrimSpace(name))
+		gid = strings.TrimSpace(gid)
+
+		if !ok || gid == "" {
+			return nil, fmt.Errorf("invalid custom field mapping %q, expected metric=field_gid", mapping)
+		}
+
+		if !slices.Contains(customFieldMetrics, metric) {
+			return nil, fmt.Errorf("unknown custom field metric %q, expected one of %v", metric, customFieldMetrics)
+		}
+
+		fields[metric] = gid
+	}
+
+	return fields, nil
+}
+
+// customFieldValues returns the custom field values of the summary keyed by field GID
+func (o AsanaTaskOptions) customFieldValues(summary *FeedbackSummary) map[string]any {
+	if len(o.CustomFields) == 0 {
+		return nil
+	}
+
+	values := map[CustomFieldMetric]any{
+		CustomFieldPositive: summary.PositiveCount,
+		CustomFieldNegative: summary.NegativeCount,
+		CustomFieldTotal:    summary.Total,
+		// Rounded to match the precision shown in the notes
+		CustomFieldNegativePercent: math.Round(summary.NegativePercent*10) / 10,
+	}
+
+	fields := make(map[string]any, len(o.CustomFields))
+	for metric, gid := range o.CustomFields {
+		fields[gid] = values[metric]
+	}
+
+	return fields
+}
+
+// assignee returns the on-call assignee of the report date.
+// The rotation advances by one assignee per day, so every run for the same day picks the same person.
+func (o AsanaTaskOptions) assignee(reportDate time.Time) string {
+	if len(o.Assignees) == 0 {
+		return ""
+	}
+
+	// Floored, days before 1970 are negative
+	secondsPerDay := int64((24 * time.Hour).Seconds())
+	days := reportDate.UTC().Unix() / secondsPerDay
+	if reportDate.UTC().Unix()%secondsPerDay < 0 {
+		days--
+	}
+
+	n := int64(len(o.Assignees))
+
+	return o.Assignees[((days%n)+n)%n]
+}
+
+// dueOn returns the due date of the task in Asana's format, or an empty string
+func (o AsanaTaskOptions) dueOn(reportDate time.Time) string {
+	if o.DueDays <= 0 {
+		return ""
+	}
+
+	return reportDate.UTC().AddDate(0, 0, o.DueDays).Format("2006-01-02")
+}
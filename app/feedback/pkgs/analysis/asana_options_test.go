@@ -0,0 +1,196 @@
+package analysis
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCustomFields(t *testing.T) {
+	tests := []struct {
+		name     string
+		mappings []string
+		expected map[CustomFieldMetric]string
+		wantErr  bool
+	}{
+		{
+			name:     "all metrics",
+			mappings: []string{"positive=101", "negative=102", " total = 103 ", "negative_percent=104"},
+			expected: map[CustomFieldMetric]string{
+				CustomFieldPositive:        "101",
+				CustomFieldNegative:        "102",
+				CustomFieldTotal:           "103",
+				CustomFieldNegativePercent: "104",
+			},
+		},
+		{
+			name:     "no mappings",
+			expected: map[CustomFieldMetric]string{},
+		},
+		{
+			name:     "missing GID",
+			mappings: []string{"positive="},
+			wantErr:  true,
+		},
+		{
+			name:     "missing separator",
+			mappings: []string{"positive"},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown metric",
+			mappings: []string{"neutral=101"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := ParseCustomFields(tt.mappings)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %v", fields)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("ParseCustomFields failed: %v", err)
+			}
+
+			if len(fields) != len(tt.expected) {
+				t.Fatalf("Expected %d fields, got %d", len(tt.expected), len(fields))
+			}
+
+			for metric, gid := range tt.expected {
+				if fields[metric] != gid {
+					t.Errorf("Expected %s field %q, got %q", metric, gid, fields[metric])
+				}
+			}
+		})
+	}
+}
+
+func TestAsanaTaskOptions_CustomFieldValues(t *testing.T) {
+	options := AsanaTaskOptions{
+		CustomFields: map[CustomFieldMetric]string{
+			CustomFieldNegative:        "102",
+			CustomFieldTotal:           "103",
+			CustomFieldNegativePercent: "104",
+		},
+	}
+	summary := &FeedbackSummary{PositiveCount: 2, NegativeCount: 1, Total: 3, NegativePercent: 100.0 / 3}
+
+	values := options.customFieldValues(summary)
+
+	expected := map[string]any{"102": int64(1), "103": int64(3), "104": 33.3}
+	if len(values) != len(expected) {
+		t.Fatalf("Expected %d values, got %v", len(expected), values)
+	}
+
+	for gid, value := range expected {
+		if values[gid] != value {
+			t.Errorf("Expected field %s value %v, got %v", gid, value, values[gid])
+		}
+	}
+
+	if values := (AsanaTaskOptions{}).customFieldValues(summary); values != nil {
+		t.Errorf("Expected no values without custom fields, got %v", values)
+	}
+}
+
+func TestAsanaTaskOptions_Assignee(t *testing.T) {
+	options := AsanaTaskOptions{Assignees: []string{"alice@example.com", "bob@example.com", "1200000000"}}
+	day := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	seen := map[string]bool{}
+	previous := ""
+
+	for i := range 3 {
+		assignee := options.assignee(day.AddDate(0, 0, i))
+		if assignee == previous {
+			t.Errorf("Expected assignee to rotate on day %d, got %q again", i, assignee)
+		}
+
+		seen[assignee] = true
+		previous = assignee
+	}
+
+	if len(seen) != 3 {
+		t.Errorf("Expected every assignee within 3 days, got %v", seen)
+	}
+
+	// Later runs for the same report date must pick the same assignee
+	if first, again := options.assignee(day), options.assignee(day.Add(5*time.Hour)); first != again {
+		t.Errorf("Expected the same assignee for the same day, got %q and %q", first, again)
+	}
+
+	// Report dates before 1970 rotate as well
+	before := time.Date(1969, time.December, 30, 0, 0, 0, 0, time.UTC)
+	if first, next := options.assignee(before), options.assignee(before.AddDate(0, 0, 1)); first == next {
+		t.Errorf("Expected assignee to rotate before 1970, got %q twice", first)
+	}
+
+	if first, again := options.assignee(before), options.assignee(before.Add(5*time.Hour)); first != again {
+		t.Errorf("Expected the same assignee for the same day before 1970, got %q and %q", first, again)
+	}
+
+	if assignee := (AsanaTaskOptions{}).assignee(day); assignee != "" {
+		t.Errorf("Expected no assignee without rotation, got %q", assignee)
+	}
+}
+
+func TestAsanaTaskOptions_DueOn(t *testing.T) {
+	day := time.Date(2024, time.June, 28, 0, 0, 0, 0, time.UTC)
+
+	if dueOn := (AsanaTaskOptions{DueDays: 3}).dueOn(day); dueOn != "2024-07-01" {
+		t.Errorf("Expected due date '2024-07-01', got %q", dueOn)
+	}
+
+	if dueOn := (AsanaTaskOptions{}).dueOn(day); dueOn != "" {
+		t.Errorf("Expected no due date, got %q", dueOn)
+	}
+}
+
+func TestBuildTaskRequest_Options(t *testing.T) {
+	client := newAsanaClient("test-token", "workspace-123", "project-456")
+	client.options = AsanaTaskOptions{
+		CustomFields: map[CustomFieldMetric]string{CustomFieldNegative: "102"},
+		SectionGID:   "section-789",
+		Assignees:    []string{"alice@example.com"},
+		DueDays:      1,
+	}
+	summary := &FeedbackSummary{PositiveCount: 80, NegativeCount: 20, Total: 100, NegativePercent: 20.0}
+	windowStart := time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	request := client.buildTaskRequest(summary, nil, windowStart, windowEnd)
+
+	// The section is set through memberships, which replace projects
+	if len(request.Data.Projects) != 0 {
+		t.Errorf("Expected no projects when a section is set, got %v", request.Data.Projects)
+	}
+
+	if len(request.Data.Memberships) != 1 ||
+		request.Data.Memberships[0] != (AsanaMembership{Project: "project-456", Section: "section-789"}) {
+		t.Errorf("Expected membership in project-456/section-789, got %+v", request.Data.Memberships)
+	}
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+
+	for _, expected := range []string{
+		`"memberships":[{"project":"project-456","section":"section-789"}]`,
+		`"assignee":"alice@example.com"`,
+		`"due_on":"2024-06-16"`,
+		`"custom_fields":{"102":20}`,
+	} {
+		if !strings.Contains(string(jsonData), expected) {
+			t.Errorf("Expected JSON to contain %s, got %s", expected, jsonData)
+		}
+	}
+}
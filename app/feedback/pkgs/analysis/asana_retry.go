@@ -0,0 +1,212 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	asanaMaxRetries      = 5
+	asanaInitialBackoff  = 1 * time.Second
+	asanaMaxBackoff      = 30 * time.Second
+	asanaRequestDeadline = 3 * time.Minute
+)
+
+// retryPolicy controls how failed Asana requests are retried.
+// The zero value performs a single attempt without an overall deadline.
+type retryPolicy struct {
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	deadline       time.Duration
+}
+
+// defaultRetryPolicy returns the retry policy used in production
+func defaultRetryPolicy() retryPolicy {
+	return retryPolicy{
+		maxRetries:     asanaMaxRetries,
+		initialBackoff: asanaInitialBackoff,
+		maxBackoff:     asanaMaxBackoff,
+		deadline:       asanaRequestDeadline,
+	}
+}
+
+// backoff returns the delay before the next attempt using exponential backoff with
+// "equal jitter": half of the delay is fixed, the other half is random
+func (p retryPolicy) backoff(attempt int) time.Duration {
+	delay := p.initialBackoff << attempt
+	if delay <= 0 || delay > p.maxBackoff {
+		delay = p.maxBackoff
+	}
+
+	if delay <= 0 {
+		return 0
+	}
+
+	half := delay / 2
+
+	return half + rand.N(half+1) // #nosec G404 - jitter doesn't need a secure random source
+}
+
+// withDeadline returns a context bounded by the policy's overall deadline
+func (p retryPolicy) withDeadline(ctx context.Context) (context.Context, context.CancelFunc) {
+	if p.deadline <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, p.deadline)
+}
+
+// AsanaErrorDetail is a single entry of the Asana error response
+type AsanaErrorDetail struct {
+	Message string `json:"message"`
+	Help    string `json:"help,omitempty"`
+	Phrase  string `json:"phrase,omitempty"`
+}
+
+// AsanaError is returned when Asana API responds with an unexpected status code
+type AsanaError struct {
+	StatusCode int
+	Errors     []AsanaErrorDetail
+	// RetryAfter is the delay requested by the Retry-After header of 429 and 503 responses
+	RetryAfter time.Duration
+	// Body holds the raw response body when it isn't a valid Asana error response
+	Body string
+}
+
+// Error implements the error interface
+func (e *AsanaError) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("asana API returned status %d: %s", e.StatusCode, e.Body)
+	}
+
+	messages := make([]string, 0, len(e.Errors))
+	for _, detail := range e.Errors {
+		messages = append(messages, detail.Message)
+	}
+
+	return fmt.Sprintf("asana API returned status %d: %s", e.StatusCode, strings.Join(messages, "; "))
+}
+
+// IsRateLimited reports whether the request was rejected by Asana's rate limiter
+func (e *AsanaError) IsRateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// IsServerError reports whether Asana failed with a 5xx status code
+func (e *AsanaError) IsServerError() bool {
+	return e.StatusCode >= http.StatusInternalServerError
+}
+
+// IsNotFound reports whether the requested Asana resource doesn't exist
+func (e *AsanaError) IsNotFound() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// newAsanaError creates an AsanaError from an HTTP response and its body
+func newAsanaError(resp *http.Response, body []byte) *AsanaError {
+	asanaErr := &AsanaError{StatusCode: resp.StatusCode}
+
+	var parsed struct {
+		Errors []AsanaErrorDetail `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &parsed); err == nil && len(parsed.Errors) > 0 {
+		asanaErr.Errors = parsed.Errors
+	} else {
+		asanaErr.Body = string(body)
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		asanaErr.RetryAfter = parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+
+	return asanaErr
+}
+
+// parseRetryAfter parses the Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := date.Sub(timeNow()); delay > 0 {
+			return delay
+		}
+	}
+
+	return 0
+}
+
+// isRetryableAsanaError reports whether a failed request should be attempted again.
+// POST requests aren't idempotent, Asana may have created the resource although the response was lost,
+// they are only retried when the request never reached Asana.
+func isRetryableAsanaError(ctx context.Context, method string, err error) bool {
+	// The caller gave up or the overall deadline passed
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if method == http.MethodPost {
+		return isUnsentAsanaError(err)
+	}
+
+	return isTransientAsanaError(err)
+}
+
+// isTransientAsanaError reports whether a request failed for a reason that may go away:
+// rate limiting, 5xx responses, network errors (connection refused, reset, timeouts) and truncated responses
+func isTransientAsanaError(err error) bool {
+	var asanaErr *AsanaError
+	if errors.As(err, &asanaErr) {
+		return asanaErr.IsRateLimited() || asanaErr.IsServerError()
+	}
+
+	var netErr net.Error
+
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isUnsentAsanaError reports whether a request failed before Asana processed it: the connection
+// couldn't be established, or Asana rejected it with 429, or with 503 and a Retry-After delay
+func isUnsentAsanaError(err error) bool {
+	var asanaErr *AsanaError
+	if errors.As(err, &asanaErr) {
+		return asanaErr.IsRateLimited() ||
+			(asanaErr.StatusCode == http.StatusServiceUnavailable && asanaErr.RetryAfter > 0)
+	}
+
+	var opErr *net.OpError
+
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// sleep waits for d or until ctx is canceled
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,451 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newTestAsanaClient creates a client pointing to the test server with fast retries
+func newTestAsanaClient(server *httptest.Server) *AsanaClient {
+	return &AsanaClient{
+		token:        "test-token",
+		workspaceGID: "workspace-123",
+		projectGID:   "project-456",
+		httpClient:   server.Client(),
+		baseURL:      server.URL,
+		retry: retryPolicy{
+			maxRetries:     3,
+			initialBackoff: 10 * time.Millisecond,
+			maxBackoff:     50 * time.Millisecond,
+			deadline:       5 * time.Second,
+		},
+	}
+}
+
+func TestAsanaClient_GetTask_RetriesServerErrors(t *testing.T) {
+	var attempts atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"errors":[{"message":"Service unavailable"}]}`))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data":{"gid":"1234567890"}}`))
+	}))
+	defer server.Close()
+
+	task, err := newTestAsanaClient(server).getTask(context.Background(), "1234567890")
+	if err != nil {
+		t.Fatalf("getTask failed: %v", err)
+	}
+
+	if task.GID != "1234567890" {
+		t.Errorf("Expected GID '1234567890', got %q", task.GID)
+	}
+
+	if got := attempts.Load(); got != 3 {
+		t.Errorf("Expected 3 attempts, got %d", got)
+	}
+}
+
+func TestAsanaClient_SendRequest_HonorsRetryAfter(t *testing.T) {
+	var attempts atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"errors":[{"message":"Rate limit enforced"}]}`))
+
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"data":{"gid":"1234567890"}}`))
+	}))
+	defer server.Close()
+
+	start := time.Now()
+
+	if _, err := newTestAsanaClient(server).sendRequest(context.Background(), []byte(`{}`)); err != nil {
+		t.Fatalf("sendRequest failed: %v", err)
+	}
+
+	// The backoff is at most 50ms, so waiting a full second proves Retry-After was used
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Expected to wait at least 1s before retrying, waited %v", elapsed)
+	}
+
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+}
+
+func TestAsanaClient_SendRequest_DoesNotRetryClientErrors(t *testing.T) {
+	var attempts atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"errors":[{"message":"workspace: Missing input","help":"See docs","phrase":"6 sad squid"}]}`))
+	}))
+	defer server.Close()
+
+	_, err := newTestAsanaClient(server).sendRequest(context.Background(), []byte(`{}`))
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	var asanaErr *AsanaError
+	if !errors.As(err, &asanaErr) {
+		t.Fatalf("Expected *AsanaError, got %T: %v", err, err)
+	}
+
+	if asanaErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", asanaErr.StatusCode)
+	}
+
+	if len(asanaErr.Errors) != 1 || asanaErr.Errors[0].Message != "workspace: Missing input" {
+		t.Errorf("Expected parsed error message, got %+v", asanaErr.Errors)
+	}
+
+	if asanaErr.Errors[0].Phrase != "6 sad squid" {
+		t.Errorf("Expected phrase '6 sad squid', got %q", asanaErr.Errors[0].Phrase)
+	}
+
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("Expected 1 attempt, got %d", got)
+	}
+}
+
+func TestAsanaClient_GetTask_GivesUpAfterMaxRetries(t *testing.T) {
+	var attempts atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+		_, _ = w.Write([]byte("internal error"))
+	}))
+	defer server.Close()
+
+	_, err := newTestAsanaClient(server).getTask(context.Background(), "1234567890")
+
+	var asanaErr *AsanaError
+	if !errors.As(err, &asanaErr) || !asanaErr.IsServerError() {
+		t.Fatalf("Expected server error, got %v", err)
+	}
+
+	if asanaErr.Body != "internal error" {
+		t.Errorf("Expected raw body for non-JSON error, got %q", asanaErr.Body)
+	}
+
+	// One initial attempt plus 3 retries
+	if got := attempts.Load(); got != 4 {
+		t.Errorf("Expected 4 attempts, got %d", got)
+	}
+}
+
+func TestAsanaClient_SendRequest_RetryAfterBeyondDeadline(t *testing.T) {
+	var attempts atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+
+	_, err := newTestAsanaClient(server).sendRequest(context.Background(), []byte(`{}`))
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	// Waiting 60s would exceed the 5s deadline, so the client must fail immediately
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected to fail fast, took %v", elapsed)
+	}
+
+	if got := attempts.Load(); got != 1 {
+		t.Errorf("Expected 1 attempt, got %d", got)
+	}
+}
+
+func TestAsanaClient_GetTask_RetriesNetworkErrors(t *testing.T) {
+	var attempts atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Drop the connection without a response on the first attempt
+		if attempts.Add(1) == 1 {
+			hijacker, ok := w.(http.Hijacker)
+			if !ok {
+				t.Fatal("Expected hijackable response writer")
+			}
+
+			conn, _, err := hijacker.Hijack()
+			if err != nil {
+				t.Fatalf("Failed to hijack connection: %v", err)
+			}
+
+			_ = conn.Close()
+
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"data":{"gid":"1234567890"}}`))
+	}))
+	defer server.Close()
+
+	if _, err := newTestAsanaClient(server).getTask(context.Background(), "1234567890"); err != nil {
+		t.Fatalf("getTask failed: %v", err)
+	}
+
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("Expected 2 attempts, got %d", got)
+	}
+}
+
+func TestAsanaClient_SendRequest_DoesNotRetryProcessedRequests(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond func(w http.ResponseWriter)
+	}{
+		{
+			name: "server error",
+			respond: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+		},
+		{
+			name: "service unavailable without Retry-After",
+			respond: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+		},
+		{
+			name: "lost response",
+			respond: func(w http.ResponseWriter) {
+				if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+					_ = conn.Close()
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attempts atomic.Int32
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				attempts.Add(1)
+				tt.respond(w)
+			}))
+			defer server.Close()
+
+			if _, err := newTestAsanaClient(server).sendRequest(context.Background(), []byte(`{}`)); err == nil {
+				t.Fatal("Expected error, got nil")
+			}
+
+			// The task may have been created, sending the request again could duplicate it
+			if got := attempts.Load(); got != 1 {
+				t.Errorf("Expected 1 attempt, got %d", got)
+			}
+		})
+	}
+}
+
+func TestIsRetryableAsanaError(t *testing.T) {
+	dialErr := &url.Error{Op: "Post", URL: "https://app.asana.com", Err: &net.OpError{
+		Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED,
+	}}
+	resetErr := &url.Error{Op: "Post", URL: "https://app.asana.com", Err: &net.OpError{
+		Op: "read", Net: "tcp", Err: syscall.ECONNRESET,
+	}}
+
+	tests := []struct {
+		name     string
+		method   string
+		err      error
+		expected bool
+	}{
+		{name: "POST connection refused", method: http.MethodPost, err: dialErr, expected: true},
+		{name: "POST rate limited", method: http.MethodPost, err: &AsanaError{StatusCode: 429}, expected: true},
+		{
+			name:     "POST unavailable with Retry-After",
+			method:   http.MethodPost,
+			err:      &AsanaError{StatusCode: 503, RetryAfter: time.Second},
+			expected: true,
+		},
+		{name: "POST unavailable", method: http.MethodPost, err: &AsanaError{StatusCode: 503}},
+		{name: "POST server error", method: http.MethodPost, err: &AsanaError{StatusCode: 500}},
+		{name: "POST connection reset", method: http.MethodPost, err: resetErr},
+		{name: "POST truncated response", method: http.MethodPost, err: io.ErrUnexpectedEOF},
+		{name: "GET server error", method: http.MethodGet, err: &AsanaError{StatusCode: 500}, expected: true},
+		{name: "GET connection reset", method: http.MethodGet, err: resetErr, expected: true},
+		{name: "PUT truncated response", method: http.MethodPut, err: io.ErrUnexpectedEOF, expected: true},
+		{name: "GET client error", method: http.MethodGet, err: &AsanaError{StatusCode: 400}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryableAsanaError(context.Background(), tt.method, tt.err); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestAsanaClient_CreateTask_DeduplicatesFailedRequests(t *testing.T) {
+	summary := reportSummary(testReportRun(75, 25, ""))
+	windowStart := time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	t.Run("task created although the response was lost", func(t *testing.T) {
+		fake := newFakeAsana()
+		fake.loseCreates = 1
+
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		taskGID, err := newTestAsanaClient(server).createTask(context.Background(), summary, nil, windowStart, windowEnd)
+		if err != nil {
+			t.Fatalf("createTask failed: %v", err)
+		}
+
+		if _, ok := fake.tasks[taskGID]; !ok {
+			t.Errorf("Expected GID of the created task, got %q", taskGID)
+		}
+
+		if fake.creates != 1 || len(fake.tasks) != 1 {
+			t.Errorf("Expected 1 create request and 1 task, got %d and %d", fake.creates, len(fake.tasks))
+		}
+	})
+
+	t.Run("task not created", func(t *testing.T) {
+		fake := newFakeAsana()
+		fake.failCreates = 1
+
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		_, err := newTestAsanaClient(server).createTask(context.Background(), summary, nil, windowStart, windowEnd)
+		if err != nil {
+			t.Fatalf("createTask failed: %v", err)
+		}
+
+		if fake.creates != 2 || len(fake.tasks) != 1 {
+			t.Errorf("Expected 2 create requests and 1 task, got %d and %d", fake.creates, len(fake.tasks))
+		}
+	})
+}
+
+func TestAsanaClient_FindTask(t *testing.T) {
+	fake := newFakeAsana()
+
+	// More tasks than fit on one page, the report task is on the second page
+	for i := range asanaTaskListLimit + 10 {
+		fake.tasks[fmt.Sprintf("%d", 100+i)] = AsanaTaskResponseData{Name: fmt.Sprintf("Other task %d", i)}
+	}
+
+	fake.tasks["500"] = AsanaTaskResponseData{Name: "[ALERT] Daily Feedback Summary - 2024-06-15"}
+
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := newTestAsanaClient(server)
+
+	taskGID, err := client.findTask(context.Background(), time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("findTask failed: %v", err)
+	}
+
+	if taskGID != "500" {
+		t.Errorf("Expected task '500', got %q", taskGID)
+	}
+
+	taskGID, err = client.findTask(context.Background(), time.Date(2024, time.June, 16, 0, 0, 0, 0, time.UTC))
+	if err != nil || taskGID != "" {
+		t.Errorf("Expected no task, got %q (err: %v)", taskGID, err)
+	}
+
+	client.projectGID = ""
+	if _, err := client.findTask(context.Background(), time.Now()); !errors.Is(err, errNoTaskLookup) {
+		t.Errorf("Expected errNoTaskLookup without a project, got %v", err)
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := retryPolicy{
+		initialBackoff: 100 * time.Millisecond,
+		maxBackoff:     1 * time.Second,
+	}
+
+	tests := []struct {
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{attempt: 0, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{attempt: 1, min: 100 * time.Millisecond, max: 200 * time.Millisecond},
+		{attempt: 2, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{attempt: 5, min: 500 * time.Millisecond, max: 1 * time.Second},
+		{attempt: 100, min: 500 * time.Millisecond, max: 1 * time.Second},
+	}
+
+	for _, tt := range tests {
+		for range 20 {
+			delay := policy.backoff(tt.attempt)
+			if delay < tt.min || delay > tt.max {
+				t.Errorf("attempt %d: expected delay in [%v, %v], got %v", tt.attempt, tt.min, tt.max, delay)
+			}
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	originalTimeNow := timeNow
+	defer func() {
+		timeNow = originalTimeNow
+	}()
+
+	mockTime := time.Date(2024, time.June, 15, 12, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time {
+		return mockTime
+	}
+
+	tests := []struct {
+		name     string
+		value    string
+		expected time.Duration
+	}{
+		{name: "empty", value: "", expected: 0},
+		{name: "seconds", value: "30", expected: 30 * time.Second},
+		{name: "http date", value: "Sat, 15 Jun 2024 12:00:10 GMT", expected: 10 * time.Second},
+		{name: "date in the past", value: "Sat, 15 Jun 2024 11:00:00 GMT", expected: 0},
+		{name: "invalid", value: "soon", expected: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := parseRetryAfter(tt.value); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
@@ -167,7 +167,7 @@ func TestBuildTaskRequest(t *testing.T) {
 	windowStart := time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC)
 	windowEnd := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
 
-	request := client.buildTaskRequest(summary, windowStart, windowEnd)
+	request := client.buildTaskRequest(summary, nil, windowStart, windowEnd)
 
 	// Verify workspace is set
 	if request.Data.Workspace != expectedAsanaWorkspace {
@@ -377,3 +377,65 @@ func TestAsanaTaskResponse_JSONUnmarshaling(t *testing.T) {
 		t.Errorf("Expected GID '%s', got %q", expectedAsanaTaskGID, response.Data.GID)
 	}
 }
+
+func TestAsanaClient_FindOrCreateTask(t *testing.T) {
+	summary := reportSummary(testReportRun(75, 25, ""))
+	windowStart := time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	t.Run("reuses task of an interrupted run", func(t *testing.T) {
+		fake := newFakeAsana()
+		fake.tasks["2000"] = AsanaTaskResponseData{GID: "2000", Name: "Daily Feedback Summary - 2024-06-15"}
+
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		client := newTestAsanaClient(server)
+
+		taskGID, err := client.findOrCreateTask(context.Background(), summary, nil, windowStart, windowEnd)
+		if err != nil {
+			t.Fatalf("findOrCreateTask failed: %v", err)
+		}
+
+		if taskGID != "2000" || fake.creates != 0 {
+			t.Errorf("Expected existing task '2000' without create requests, got %q and %d", taskGID, fake.creates)
+		}
+	})
+
+	t.Run("creates missing task", func(t *testing.T) {
+		fake := newFakeAsana()
+		fake.tasks["2000"] = AsanaTaskResponseData{GID: "2000", Name: "Daily Feedback Summary - 2024-06-14"}
+
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		client := newTestAsanaClient(server)
+
+		taskGID, err := client.findOrCreateTask(context.Background(), summary, nil, windowStart, windowEnd)
+		if err != nil {
+			t.Fatalf("findOrCreateTask failed: %v", err)
+		}
+
+		if taskGID == "2000" || fake.creates != 1 {
+			t.Errorf("Expected a new task, got %q and %d create requests", taskGID, fake.creates)
+		}
+	})
+
+	t.Run("creates task without project", func(t *testing.T) {
+		fake := newFakeAsana()
+
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		client := newTestAsanaClient(server)
+		client.projectGID = ""
+
+		if _, err := client.findOrCreateTask(context.Background(), summary, nil, windowStart, windowEnd); err != nil {
+			t.Fatalf("findOrCreateTask failed: %v", err)
+		}
+
+		if fake.creates != 1 {
+			t.Errorf("Expected 1 create request, got %d", fake.creates)
+		}
+	})
+}
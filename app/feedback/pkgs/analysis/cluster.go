@@ -0,0 +1,285 @@
+package analysis
+
+import (
+	"cmp"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+)
+
+const (
+	// DefaultTopClusters is the default number of near-duplicate clusters reported
+	DefaultTopClusters = 5
+
+	// clusterShingleSize is the number of characters of a shingle
+	clusterShingleSize = 4
+	// clusterBands and clusterRows define the LSH index, bands * rows MinHash values per message.
+	// Messages with a Jaccard similarity of about (1/bands)^(1/rows) = 0.5 become candidates.
+	clusterBands = 16
+	clusterRows  = 4
+	// clusterMinSimilarity is the Jaccard similarity of shingles above which messages are near duplicates
+	clusterMinSimilarity = 0.5
+	// clusterMinSize is the minimum number of messages of a reported cluster
+	clusterMinSize = 3
+	// clusterMaxRepresentativeCandidates bounds the work to find the representative of a large cluster
+	clusterMaxRepresentativeCandidates = 200
+)
+
+// MessageCluster is a group of nearly identical messages
+type MessageCluster struct {
+	Size     int
+	Negative int
+	Positive int
+	// Representative is the message most similar to the other messages of the cluster
+	Representative string
+}
+
+// clusterMessage is a message prepared for clustering
+type clusterMessage struct {
+	message  FeedbackMessage
+	shingles map[uint64]struct{}
+}
+
+// clusterMessages groups near-duplicate messages using MinHash and locality-sensitive hashing
+// and returns the n largest clusters. Candidate pairs found by LSH are verified with the exact
+// Jaccard similarity of their shingles, so the result doesn't depend on hash collisions.
+func clusterMessages(messages []FeedbackMessage, n int) []MessageCluster {
+	if n <= 0 {
+		return nil
+	}
+
+	prepared := make([]clusterMessage, 0, len(messages))
+
+	for _, message := range messages {
+		if shingles := messageShingles(message.Message); len(shingles) > 0 {
+			prepared = append(prepared, clusterMessage{message: message, shingles: shingles})
+		}
+	}
+
+	// Messages sharing all rows of a band are candidates
+	groups := newUnionFind(len(prepared))
+	buckets := map[uint64][]int{}
+
+	for i, message := range prepared {
+		signature := minHashSignature(message.shingles)
+
+		for band := range clusterBands {
+			key := bandKey(band, signature[band*clusterRows:(band+1)*clusterRows])
+
+			for _, j := range buckets[key] {
+				if groups.find(i) != groups.find(j) &&
+					jaccard(message.shingles, prepared[j].shingles) >= clusterMinSimilarity {
+					groups.union(i, j)
+				}
+			}
+
+			buckets[key] = append(buckets[key], i)
+		}
+	}
+
+	members := map[int][]int{}
+	for i := range prepared {
+		root := groups.find(i)
+		members[root] = append(members[root], i)
+	}
+
+	var clusters []MessageCluster
+
+	for _, indexes := range members {
+		if len(indexes) < clusterMinSize {
+			continue
+		}
+
+		clusters = append(clusters, newMessageCluster(prepared, indexes))
+	}
+
+	slices.SortFunc(clusters, func(a, b MessageCluster) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), cmp.Compare(a.Representative, b.Representative))
+	})
+
+	return clusters[:min(n, len(clusters))]
+}
+
+// newMessageCluster summarizes the messages of a cluster
+func newMessageCluster(prepared []clusterMessage, indexes []int) MessageCluster {
+	cluster := MessageCluster{Size: len(indexes)}
+
+	for _, i := range indexes {
+		switch prepared[i].message.Sentiment {
+		case db.SentimentTypeNegative:
+			cluster.Negative++
+		case db.SentimentTypePositive:
+			cluster.Positive++
+		}
+	}
+
+	// The representative is the medoid: the message with the highest similarity to the others.
+	// Ties are broken by the lowest ID to keep the output deterministic.
+	slices.SortFunc(indexes, func(a, b int) int {
+		return cmp.Compare(prepared[a].message.ID, prepared[b].message.ID)
+	})
+
+	candidates := indexes[:min(clusterMaxRepresentativeCandidates, len(indexes))]
+	best, bestSimilarity := candidates[0], -1.0
+
+	for _, i := range candidates {
+		var similarity float64
+		for _, j := range candidates {
+			if i != j {
+				similarity += jaccard(prepared[i].shingles, prepared[j].shingles)
+			}
+		}
+
+		if similarity > bestSimilarity {
+			best, bestSimilarity = i, similarity
+		}
+	}
+
+	cluster.Representative = prepared[best].message.Message
+
+	return cluster
+}
+
+// messageShingles returns the hashed character shingles of the normalized message
+func messageShingles(message string) map[uint64]struct{} {
+	runes := []rune(strings.Join(tokenize(message), " "))
+	if len(runes) == 0 {
+		return nil
+	}
+
+	shingles := map[uint64]struct{}{}
+
+	// Short messages are a single shingle
+	if len(runes) <= clusterShingleSize {
+		shingles[hashString(string(runes))] = struct{}{}
+
+		return shingles
+	}
+
+	for i := 0; i+clusterShingleSize <= len(runes); i++ {
+		shingles[hashString(string(runes[i:i+clusterShingleSize]))] = struct{}{}
+	}
+
+	return shingles
+}
+
+// minHashSignature returns the minimum of each of the bands * rows hash functions over the shingles
+func minHashSignature(shingles map[uint64]struct{}) []uint64 {
+	signature := make([]uint64, clusterBands*clusterRows)
+	for i := range signature {
+		signature[i] = ^uint64(0)
+	}
+
+	for shingle := range shingles {
+		for i := range signature {
+			// #nosec G115 - the index is only used as a hash seed
+			if value := mix64(shingle ^ mix64(uint64(i)+1)); value < signature[i] {
+				signature[i] = value
+			}
+		}
+	}
+
+	return signature
+}
+
+// bandKey hashes the rows of a band together with the band number
+func bandKey(band int, rows []uint64) uint64 {
+	key := mix64(uint64(band) + 1) // #nosec G115 - the band is small and non-negative
+	for _, row := range rows {
+		key = mix64(key ^ row)
+	}
+
+	return key
+}
+
+// jaccard returns the Jaccard similarity of two shingle sets
+func jaccard(a, b map[uint64]struct{}) float64 {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+
+	intersection := 0
+	for shingle := range a {
+		if _, ok := b[shingle]; ok {
+			intersection++
+		}
+	}
+
+	union := len(a) + len(b) - intersection
+	if union == 0 {
+		return 0
+	}
+
+	return float64(intersection) / float64(union)
+}
+
+// hashString returns the 64-bit FNV-1a hash of a string
+func hashString(value string) uint64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(value))
+
+	return hash.Sum64()
+}
+
+// mix64 is the SplitMix64 finalizer, used to derive independent hash functions
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
+
+// unionFind tracks which messages belong to the same cluster
+type unionFind struct {
+	parent []int
+}
+
+// newUnionFind creates n singleton sets
+func newUnionFind(n int) *unionFind {
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+
+	return &unionFind{parent: parent}
+}
+
+// find returns the root of the set containing i
+func (u *unionFind) find(i int) int {
+	for u.parent[i] != i {
+		u.parent[i] = u.parent[u.parent[i]]
+		i = u.parent[i]
+	}
+
+	return i
+}
+
+// union merges the sets containing i and j
+func (u *unionFind) union(i, j int) {
+	u.parent[u.find(i)] = u.find(j)
+}
+
+// formatClusters creates a section of the Asana task notes listing the largest clusters
+func formatClusters(clusters []MessageCluster) string {
+	if len(clusters) == 0 {
+		return ""
+	}
+
+	var section strings.Builder
+
+	section.WriteString("\n\nRepeated feedback:")
+
+	for _, cluster := range clusters {
+		fmt.Fprintf(&section, "\n• %d messages (%d negative, %d positive): \"%s\"",
+			cluster.Size, cluster.Negative, cluster.Positive,
+			truncateMessage(cluster.Representative, sampleMaxLength))
+	}
+
+	return section.String()
+}
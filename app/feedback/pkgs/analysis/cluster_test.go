@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+)
+
+func TestJaccard(t *testing.T) {
+	a := messageShingles("the login button does nothing")
+
+	if similarity := jaccard(a, messageShingles("The login button does nothing!")); similarity != 1 {
+		t.Errorf("Expected identical normalized messages to have similarity 1, got %f", similarity)
+	}
+
+	if similarity := jaccard(a, messageShingles("dark mode please")); similarity > 0.1 {
+		t.Errorf("Expected unrelated messages to have low similarity, got %f", similarity)
+	}
+
+	if similarity := jaccard(nil, nil); similarity != 0 {
+		t.Errorf("Expected empty sets to have similarity 0, got %f", similarity)
+	}
+}
+
+func TestMinHashSignature_EstimatesJaccard(t *testing.T) {
+	a := messageShingles("the checkout page shows an error after I enter my card details")
+	b := messageShingles("checkout page shows an error after entering card details")
+
+	signatureA := minHashSignature(a)
+	signatureB := minHashSignature(b)
+
+	equal := 0
+	for i := range signatureA {
+		if signatureA[i] == signatureB[i] {
+			equal++
+		}
+	}
+
+	estimate := float64(equal) / float64(len(signatureA))
+	if exact := jaccard(a, b); math.Abs(estimate-exact) > 0.25 {
+		t.Errorf("Expected MinHash estimate %.2f to be close to the Jaccard similarity %.2f", estimate, exact)
+	}
+}
+
+func TestClusterMessages(t *testing.T) {
+	texts := []struct {
+		sentiment db.SentimentType
+		message   string
+	}{
+		{db.SentimentTypeNegative, "The app crashes when I open the camera"},
+		{db.SentimentTypeNegative, "the app crashes when i open the camera!!"},
+		{db.SentimentTypeNegative, "App crashes when I open the camera"},
+		{db.SentimentTypeNegative, "The app crashes whenever I open the camera"},
+		{db.SentimentTypePositive, "app crashes when I open the camera, otherwise fine"},
+		{db.SentimentTypeNegative, "Please add dark mode"},
+		{db.SentimentTypeNegative, "please add a dark mode"},
+		{db.SentimentTypeNegative, "Please add dark mode."},
+		{db.SentimentTypePositive, "Love the new design"},
+		{db.SentimentTypeNegative, "Sync is slow on mobile data"},
+	}
+
+	messages := make([]FeedbackMessage, 0, len(texts))
+	for i, text := range texts {
+		messages = append(messages, FeedbackMessage{
+			ID:        int32(i + 1), // #nosec G115 - small test values
+			Sentiment: text.sentiment,
+			Message:   text.message,
+		})
+	}
+
+	clusters := clusterMessages(messages, DefaultTopClusters)
+	if len(clusters) != 2 {
+		t.Fatalf("Expected 2 clusters, got %+v", clusters)
+	}
+
+	crashes := clusters[0]
+	if crashes.Size != 5 || crashes.Negative != 4 || crashes.Positive != 1 {
+		t.Errorf("Expected crash cluster of 5 messages (4 negative), got %+v", crashes)
+	}
+
+	if !strings.Contains(strings.ToLower(crashes.Representative), "crashes when i open the camera") {
+		t.Errorf("Expected crash message as representative, got %q", crashes.Representative)
+	}
+
+	if darkMode := clusters[1]; darkMode.Size != 3 {
+		t.Errorf("Expected dark mode cluster of 3 messages, got %+v", darkMode)
+	}
+
+	t.Run("deterministic", func(t *testing.T) {
+		again := clusterMessages(messages, DefaultTopClusters)
+		for i := range clusters {
+			if clusters[i] != again[i] {
+				t.Errorf("Expected the same clusters, got %+v and %+v", clusters[i], again[i])
+			}
+		}
+	})
+
+	t.Run("limited", func(t *testing.T) {
+		if limited := clusterMessages(messages, 1); len(limited) != 1 || limited[0].Size != 5 {
+			t.Errorf("Expected only the largest cluster, got %+v", limited)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		if disabled := clusterMessages(messages, 0); disabled != nil {
+			t.Errorf("Expected no clusters, got %+v", disabled)
+		}
+	})
+}
+
+func TestFormatClusters(t *testing.T) {
+	clusters := []MessageCluster{{Size: 12, Negative: 11, Positive: 1, Representative: "App crashes on start"}}
+
+	expected := "\n\nRepeated feedback:\n• 12 messages (11 negative, 1 positive): \"App crashes on start\""
+	if result := formatClusters(clusters); result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	if result := formatClusters(nil); result != "" {
+		t.Errorf("Expected empty section, got %q", result)
+	}
+}
@@ -2,30 +2,147 @@ package analysis
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"math"
+	"strings"
 	"time"
 
 	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-// dbReportExists checks if a report run already exists for current day
-func (a *Aggregator) dbReportExists(ctx context.Context) (bool, error) {
-	reportDate := getCurrentDate()
+// dbReportClaim records a pending report run for the current day unless a report run already exists.
+// The check and the insert run in a single transaction, the existing row is locked with FOR UPDATE.
+func (a *Aggregator) dbReportClaim(ctx context.Context, windowStart, windowEnd time.Time) error {
+	reportDate := pgtype.Date{Time: windowEnd, Valid: true}
 
 	slog.Debug("Checking if report run exists",
-		"report_date", reportDate)
+		"report_date", windowEnd)
+
+	tx, err := a.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			slog.Warn("Failed to rollback transaction", "error", err)
+		}
+	}()
+
+	queries := a.queries.WithTx(tx)
+
+	existing, err := queries.GetReportRunForUpdate(ctx, reportDate)
+	if err == nil {
+		slog.Info("Report run already exists, skipping aggregation",
+			"report_date", windowEnd.Format("2006-01-02"),
+			"status", existing.Status)
+
+		return nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to check if report run exists: %w", err)
+	}
+
+	// Query feedback counts
+	counts, err := a.dbCountFeedback(ctx, windowStart, windowEnd)
+	if err != nil {
+		return fmt.Errorf("failed to query feedback counts: %w", err)
+	}
+
+	// Create pending report run, the Asana task GID is filled in later
+	report, err := dbReportCreate(ctx, queries, windowStart, windowEnd, counts.PositiveCount, counts.NegativeCount)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit report run: %w", err)
+	}
 
-	return a.queries.ReportRunExists(ctx, pgtype.Date{Time: reportDate, Valid: true})
+	slog.Info("Pending report run recorded",
+		"report_date", windowEnd.Format("2006-01-02"),
+		"positive_count", report.PositiveCount,
+		"negative_count", report.NegativeCount)
+
+	return nil
 }
 
-func (a *Aggregator) dbReportCreate(
+// dbReportLock takes the advisory lock of the reports, waiting up to the configured lock wait.
+// The lock is held on a dedicated connection until the returned function is called.
+// ErrRunInProgress is returned if another run still holds the lock after waiting.
+func (a *Aggregator) dbReportLock(ctx context.Context) (func(), error) {
+	conn, err := a.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection for report lock: %w", err)
+	}
+
+	queries := db.New(conn)
+	deadline := time.Now().Add(a.lockWait)
+
+	for {
+		locked, err := queries.TryReportRunLock(ctx)
+		if err != nil {
+			conn.Release()
+
+			return nil, fmt.Errorf("failed to take report lock: %w", err)
+		}
+
+		if locked {
+			break
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			conn.Release()
+
+			return nil, ErrRunInProgress
+		}
+
+		slog.Info("Another analysis run holds the report lock, waiting",
+			"remaining", remaining.Round(time.Second))
+
+		timer := time.NewTimer(min(reportLockPollInterval, remaining))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			conn.Release()
+
+			return nil, fmt.Errorf("failed to take report lock: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+
+	slog.Debug("Report lock taken")
+
+	return func() {
+		// Released even if the run was canceled, the lock would stay with the pooled connection otherwise
+		unlockCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+
+		unlocked, err := queries.ReleaseReportRunLock(unlockCtx)
+		if err != nil || !unlocked {
+			slog.Warn("Failed to release report lock, closing the connection", "error", err)
+
+			// Closing the session releases the lock, the pool discards closed connections
+			if err := conn.Conn().Close(unlockCtx); err != nil {
+				slog.Warn("Failed to close connection", "error", err)
+			}
+		}
+
+		conn.Release()
+	}, nil
+}
+
+// dbReportCreate inserts a pending report run
+func dbReportCreate(
 	ctx context.Context,
+	queries *db.Queries,
 	windowStart, windowEnd time.Time,
 	positiveCount, negativeCount int64,
-	asanaTaskGID string,
 ) (*db.ReportRun, error) {
 	// Create report run in database
 	reportDate := pgtype.Date{Time: windowEnd, Valid: true}
@@ -39,13 +156,12 @@ func (a *Aggregator) dbReportCreate(
 		return nil, fmt.Errorf("negative count %d exceeds int32 range", negativeCount)
 	}
 
-	report, err := a.queries.CreateReportRun(ctx, db.CreateReportRunParams{
+	report, err := queries.CreateReportRun(ctx, db.CreateReportRunParams{
 		ReportDate:    reportDate,
 		WindowStart:   pgtype.Timestamptz{Time: windowStart, Valid: true},
 		WindowEnd:     pgtype.Timestamptz{Time: windowEnd, Valid: true},
 		PositiveCount: int32(positiveCount), // #nosec G115 - validated above
 		NegativeCount: int32(negativeCount), // #nosec G115 - validated above
-		AsanaTaskGid:  pgtype.Text{String: asanaTaskGID, Valid: true},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert report run into DB: %w", err)
@@ -54,6 +170,21 @@ func (a *Aggregator) dbReportCreate(
 	return &report, nil
 }
 
+// dbReportComplete stores the Asana task GID and marks the report run as completed.
+// An empty GID (no task was created) is stored as NULL.
+func (a *Aggregator) dbReportComplete(ctx context.Context, reportDate pgtype.Date, asanaTaskGID string) error {
+	err := a.queries.UpdateAsanaTaskGid(ctx, db.UpdateAsanaTaskGidParams{
+		ReportDate:   reportDate,
+		AsanaTaskGid: pgtype.Text{String: asanaTaskGID, Valid: asanaTaskGID != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update report run in DB: %w", err)
+	}
+
+	return nil
+}
+
+// dbCountFeedback counts the feedback of the time window from the hourly rollup, the raw feedback otherwise
 func (a *Aggregator) dbCountFeedback(
 	ctx context.Context,
 	windowStart, windowEnd time.Time,
@@ -62,8 +193,25 @@ func (a *Aggregator) dbCountFeedback(
 		"window_start", windowStart,
 		"window_end", windowEnd)
 
+	counts, ok, err := a.dbCountFeedbackRollup(ctx, windowStart, windowEnd)
+	if err != nil {
+		slog.Warn("Failed to read hourly rollup, counting raw feedback", "error", err)
+	}
+
+	if ok {
+		return counts, nil
+	}
+
+	return a.dbCountRawFeedback(ctx, windowStart, windowEnd)
+}
+
+// dbCountRawFeedback counts the raw feedback of the time window, without writing to the database
+func (a *Aggregator) dbCountRawFeedback(
+	ctx context.Context,
+	windowStart, windowEnd time.Time,
+) (*db.CountFeedbackBySentimentRow, error) {
 	// Query feedback counts by sentiment
-	counts, err := a.queries.CountFeedbackBySentiment(ctx, db.CountFeedbackBySentimentParams{
+	rawCounts, err := a.router.Read(ctx).CountFeedbackBySentiment(ctx, db.CountFeedbackBySentimentParams{
 		CreatedAt:   pgtype.Timestamptz{Time: windowStart, Valid: true},
 		CreatedAt_2: pgtype.Timestamptz{Time: windowEnd, Valid: true},
 	})
@@ -72,8 +220,140 @@ func (a *Aggregator) dbCountFeedback(
 	}
 
 	slog.Debug("Feedback counts from DB",
-		"positive", counts.PositiveCount,
-		"negative", counts.NegativeCount)
+		"positive", rawCounts.PositiveCount,
+		"negative", rawCounts.NegativeCount)
+
+	return &rawCounts, nil
+}
+
+// dbCountFeedbackRollup sums the hourly rollup of the time window after recomputing its hours, an hour is
+// rolled up once shortly after it ended and feedback committed later would be missing otherwise.
+// Returns false if the window doesn't start and end on full hours or isn't rolled up yet.
+func (a *Aggregator) dbCountFeedbackRollup(
+	ctx context.Context,
+	windowStart, windowEnd time.Time,
+) (*db.CountFeedbackBySentimentRow, bool, error) {
+	if !windowStart.Equal(windowStart.Truncate(time.Hour)) || !windowEnd.Equal(windowEnd.Truncate(time.Hour)) {
+		return nil, false, nil
+	}
+
+	watermark, err := a.rollup.Watermark(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if watermark.Before(windowEnd) {
+		slog.Debug("Hourly rollup doesn't cover the window yet",
+			"watermark", watermark,
+			"window_end", windowEnd)
+
+		return nil, false, nil
+	}
+
+	if err := a.rollup.Refresh(ctx, windowStart, windowEnd); err != nil {
+		return nil, false, fmt.Errorf("failed to refresh hourly rollup: %w", err)
+	}
+
+	sums, err := a.queries.SumFeedbackHourlyStats(ctx, db.SumFeedbackHourlyStatsParams{
+		Hour:   pgtype.Timestamptz{Time: windowStart, Valid: true},
+		Hour_2: pgtype.Timestamptz{Time: windowEnd, Valid: true},
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to sum hourly stats: %w", err)
+	}
+
+	slog.Debug("Feedback counts from hourly rollup",
+		"positive", sums.PositiveCount,
+		"negative", sums.NegativeCount)
+
+	return &db.CountFeedbackBySentimentRow{
+		PositiveCount: sums.PositiveCount,
+		NegativeCount: sums.NegativeCount,
+	}, true, nil
+}
+
+// dbReportHistory loads report runs from the given number of days before reportDate
+func (a *Aggregator) dbReportHistory(ctx context.Context, reportDate time.Time, days int) ([]db.ReportRun, error) {
+	history, err := a.router.Read(ctx).ListReportRunsBetween(ctx, db.ListReportRunsBetweenParams{
+		ReportDate:   pgtype.Date{Time: reportDate.AddDate(0, 0, -days), Valid: true},
+		ReportDate_2: pgtype.Date{Time: reportDate, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query report history from DB: %w", err)
+	}
+
+	return history, nil
+}
+
+// dbReportMarkAnomaly stores the detected anomalies on the report run
+func (a *Aggregator) dbReportMarkAnomaly(ctx context.Context, reportDate pgtype.Date, anomalies []string) error {
+	details := strings.Join(anomalies, "\n")
+
+	err := a.queries.UpdateReportRunAnomaly(ctx, db.UpdateReportRunAnomalyParams{
+		ReportDate:     reportDate,
+		IsAnomaly:      len(anomalies) > 0,
+		AnomalyDetails: pgtype.Text{String: details, Valid: details != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update report run anomaly in DB: %w", err)
+	}
+
+	return nil
+}
+
+// dbFeedbackMessages loads up to limit non-empty feedback messages of the time window
+func (a *Aggregator) dbFeedbackMessages(
+	ctx context.Context,
+	windowStart, windowEnd time.Time,
+	limit int32,
+) ([]FeedbackMessage, error) {
+	rows, err := a.router.Read(ctx).ListFeedbackMessagesInTimeRange(ctx, db.ListFeedbackMessagesInTimeRangeParams{
+		CreatedAt:   pgtype.Timestamptz{Time: windowStart, Valid: true},
+		CreatedAt_2: pgtype.Timestamptz{Time: windowEnd, Valid: true},
+		Limit:       limit,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feedback messages from DB: %w", err)
+	}
+
+	if len(rows) == int(limit) {
+		slog.Warn("Feedback message limit reached, text analysis uses a subset of the window",
+			"window_start", windowStart,
+			"window_end", windowEnd,
+			"limit", limit)
+	}
+
+	messages := make([]FeedbackMessage, 0, len(rows))
+	for _, row := range rows {
+		messages = append(messages, FeedbackMessage{
+			ID:        row.ID,
+			Sentiment: row.Sentiment,
+			Message:   row.Message.String,
+		})
+	}
+
+	return messages, nil
+}
+
+// dbFeedbackTextScores stores the text sentiment score of each message
+func (a *Aggregator) dbFeedbackTextScores(ctx context.Context, scores map[int32]float64) error {
+	if len(scores) == 0 {
+		return nil
+	}
+
+	params := db.UpdateFeedbackTextScoresParams{
+		Ids:    make([]int32, 0, len(scores)),
+		Scores: make([]float32, 0, len(scores)),
+	}
+
+	for id, score := range scores {
+		params.Ids = append(params.Ids, id)
+		params.Scores = append(params.Scores, float32(score))
+	}
+
+	if err := a.queries.UpdateFeedbackTextScores(ctx, params); err != nil {
+		return fmt.Errorf("failed to update feedback text scores in DB: %w", err)
+	}
 
-	return &counts, nil
+	return nil
 }
@@ -0,0 +1,225 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/findmyname666/ddg3/feedback/pkgs/db/dbtest"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// insertFeedback inserts feedback created at the given time
+func insertFeedback(t *testing.T, pool *pgxpool.Pool, createdAt time.Time, sentiment db.SentimentType) {
+	t.Helper()
+
+	_, err := pool.Exec(context.Background(),
+		"INSERT INTO feedback (created_at, sentiment, message) VALUES ($1, $2, 'test')", createdAt, sentiment)
+	if err != nil {
+		t.Fatalf("Failed to insert feedback: %v", err)
+	}
+}
+
+// getReportRun loads the report run of the report date
+func getReportRun(t *testing.T, pool *pgxpool.Pool, reportDate time.Time) db.ReportRun {
+	t.Helper()
+
+	report, err := db.New(pool).GetReportRun(context.Background(), pgtype.Date{Time: reportDate, Valid: true})
+	if err != nil {
+		t.Fatalf("Failed to load report run of %s: %v", reportDate.Format("2006-01-02"), err)
+	}
+
+	return report
+}
+
+func TestDBReportClaim(t *testing.T) {
+	pool := dbtest.New(t)
+	ctx := context.Background()
+
+	a := NewAggregator(Config{Pool: pool})
+
+	windowEnd := time.Now().UTC().Truncate(time.Hour)
+	windowStart := windowEnd.Add(-time.Hour)
+
+	insertFeedback(t, pool, windowStart.Add(10*time.Minute), db.SentimentTypePositive)
+	insertFeedback(t, pool, windowStart.Add(20*time.Minute), db.SentimentTypePositive)
+	insertFeedback(t, pool, windowStart.Add(30*time.Minute), db.SentimentTypeNegative)
+	// Outside of the window
+	insertFeedback(t, pool, windowEnd.Add(time.Minute), db.SentimentTypeNegative)
+
+	if err := a.dbReportClaim(ctx, windowStart, windowEnd); err != nil {
+		t.Fatalf("dbReportClaim failed: %v", err)
+	}
+
+	report := getReportRun(t, pool, windowEnd)
+	if report.Status != db.ReportStatusPending || report.PositiveCount != 2 || report.NegativeCount != 1 {
+		t.Errorf("Expected pending report with 2 positive and 1 negative, got %+v", report)
+	}
+
+	// Claiming the period again keeps the recorded report
+	insertFeedback(t, pool, windowStart.Add(40*time.Minute), db.SentimentTypeNegative)
+
+	if err := a.dbReportClaim(ctx, windowStart, windowEnd); err != nil {
+		t.Fatalf("Second dbReportClaim failed: %v", err)
+	}
+
+	if again := getReportRun(t, pool, windowEnd); again.NegativeCount != 1 || again.CreatedAt != report.CreatedAt {
+		t.Errorf("Expected the report run to be unchanged, got %+v", again)
+	}
+}
+
+func TestDBCountFeedback_CountsLateFeedback(t *testing.T) {
+	pool := dbtest.New(t)
+	ctx := context.Background()
+
+	a := NewAggregator(Config{Pool: pool})
+
+	windowEnd := time.Now().UTC().Truncate(time.Hour)
+	windowStart := windowEnd.Add(-3 * time.Hour)
+
+	insertFeedback(t, pool, windowStart.Add(10*time.Minute), db.SentimentTypePositive)
+
+	if _, err := a.rollup.Run(ctx); err != nil {
+		t.Fatalf("Rollup run failed: %v", err)
+	}
+
+	// Committed after its hour was rolled up
+	insertFeedback(t, pool, windowStart.Add(20*time.Minute), db.SentimentTypeNegative)
+
+	counts, err := a.dbCountFeedback(ctx, windowStart, windowEnd)
+	if err != nil {
+		t.Fatalf("dbCountFeedback failed: %v", err)
+	}
+
+	if counts.PositiveCount != 1 || counts.NegativeCount != 1 {
+		t.Errorf("Expected 1 positive and 1 negative, got %+v", counts)
+	}
+}
+
+func TestRun_ResumesPendingReport(t *testing.T) {
+	pool := dbtest.New(t)
+	ctx := context.Background()
+
+	// A run two days ago recorded its report but failed after creating the Asana task
+	_, today := calculateTimeWindow()
+	pendingEnd := today.AddDate(0, 0, -2)
+
+	if _, err := dbReportCreate(ctx, db.New(pool), pendingEnd.AddDate(0, 0, -1), pendingEnd, 3, 1); err != nil {
+		t.Fatalf("Failed to create pending report run: %v", err)
+	}
+
+	fake := newFakeAsana()
+	fake.tasks["2000"] = AsanaTaskResponseData{GID: "2000", Name: formatTaskName(pendingEnd)}
+
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	a := NewAggregator(Config{
+		Pool:              pool,
+		AsanaToken:        "test-token",
+		AsanaWorkspaceGID: "workspace-123",
+		AsanaProjectGID:   "project-456",
+	})
+	a.asanaBaseURL = server.URL
+
+	if err := a.Run(ctx); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if fake.creates != 0 {
+		t.Errorf("Expected no task to be created, got %d create requests", fake.creates)
+	}
+
+	resumed := getReportRun(t, pool, pendingEnd)
+	if resumed.Status != db.ReportStatusCompleted || resumed.AsanaTaskGid.String != "2000" {
+		t.Errorf("Expected resumed report completed with task '2000', got %+v", resumed)
+	}
+
+	// Today's report has no feedback, it's completed without a task
+	if report := getReportRun(t, pool, today); report.Status != db.ReportStatusCompleted || report.AsanaTaskGid.Valid {
+		t.Errorf("Expected today's report completed without task, got %+v", report)
+	}
+}
+
+func TestDBReportLock(t *testing.T) {
+	pool := dbtest.New(t)
+	ctx := context.Background()
+
+	holder := NewAggregator(Config{Pool: pool})
+
+	unlock, err := holder.dbReportLock(ctx)
+	if err != nil {
+		t.Fatalf("dbReportLock failed: %v", err)
+	}
+
+	t.Run("fails without waiting", func(t *testing.T) {
+		start := time.Now()
+
+		_, err := NewAggregator(Config{Pool: pool}).dbReportLock(ctx)
+		if !errors.Is(err, ErrRunInProgress) {
+			t.Fatalf("Expected ErrRunInProgress, got %v", err)
+		}
+
+		if elapsed := time.Since(start); elapsed >= reportLockPollInterval {
+			t.Errorf("Expected to fail immediately, took %s", elapsed)
+		}
+	})
+
+	t.Run("fails after lock wait", func(t *testing.T) {
+		start := time.Now()
+
+		_, err := NewAggregator(Config{Pool: pool, LockWait: time.Second}).dbReportLock(ctx)
+		if !errors.Is(err, ErrRunInProgress) {
+			t.Fatalf("Expected ErrRunInProgress, got %v", err)
+		}
+
+		if elapsed := time.Since(start); elapsed < time.Second {
+			t.Errorf("Expected to wait for the lock wait, took %s", elapsed)
+		}
+	})
+
+	t.Run("run doesn't record a report", func(t *testing.T) {
+		insertFeedback(t, pool, time.Now().UTC().Add(-time.Hour), db.SentimentTypePositive)
+
+		if err := NewAggregator(Config{Pool: pool}).Run(ctx); !errors.Is(err, ErrRunInProgress) {
+			t.Fatalf("Expected ErrRunInProgress, got %v", err)
+		}
+
+		if _, err := db.New(pool).GetLatestReportRun(ctx); !errors.Is(err, pgx.ErrNoRows) {
+			t.Errorf("Expected no report run, got %v", err)
+		}
+	})
+
+	t.Run("takes lock released within lock wait", func(t *testing.T) {
+		time.AfterFunc(500*time.Millisecond, unlock)
+
+		unlockWaiter, err := NewAggregator(Config{Pool: pool, LockWait: 2 * reportLockPollInterval}).dbReportLock(ctx)
+		if err != nil {
+			t.Fatalf("Expected to take the lock after waiting, got %v", err)
+		}
+		unlockWaiter()
+	})
+}
+
+func TestReconcile_FixTakesReportLock(t *testing.T) {
+	pool := dbtest.New(t)
+	ctx := context.Background()
+
+	unlock, err := NewAggregator(Config{Pool: pool}).dbReportLock(ctx)
+	if err != nil {
+		t.Fatalf("dbReportLock failed: %v", err)
+	}
+	defer unlock()
+
+	a := NewAggregator(Config{Pool: pool, AsanaToken: "test-token", AsanaWorkspaceGID: "workspace-123"})
+
+	_, err = a.Reconcile(ctx, ReconcileOptions{Since: time.Now().UTC().AddDate(0, 0, -7), Fix: true})
+	if !errors.Is(err, ErrRunInProgress) {
+		t.Errorf("Expected ErrRunInProgress, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// DryRunFormat is the output format of a dry run
+type DryRunFormat string
+
+const (
+	DryRunFormatText DryRunFormat = "text"
+	DryRunFormatJSON DryRunFormat = "json"
+)
+
+// ParseDryRunFormat validates the name of a dry run output format
+func ParseDryRunFormat(name string) (DryRunFormat, error) {
+	switch format := DryRunFormat(name); format {
+	case DryRunFormatText, DryRunFormatJSON:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown dry run format %q, expected %q or %q", name, DryRunFormatText, DryRunFormatJSON)
+	}
+}
+
+// DryRunResult is the report a run would create for the current window
+type DryRunResult struct {
+	ReportDate    string    `json:"report_date"`
+	WindowStart   time.Time `json:"window_start"`
+	WindowEnd     time.Time `json:"window_end"`
+	PositiveCount int64     `json:"positive_count"`
+	NegativeCount int64     `json:"negative_count"`
+	// ExistingStatus is the status of an existing report run for the day, a real run would skip the day then
+	ExistingStatus string `json:"existing_status,omitempty"`
+	// AsanaTask is the request creating the task, nil when there is no feedback and no task would be created
+	AsanaTask *AsanaTaskRequest `json:"asana_task"`
+	// Attachment is the file name of the feedback export attached to the task
+	Attachment string `json:"attachment,omitempty"`
+}
+
+// DryRun computes the report of the current window and writes the Asana task it would create.
+// It only reads from the database and doesn't call Asana, so it works with a read-only database user.
+func (a *Aggregator) DryRun(ctx context.Context, w io.Writer, format DryRunFormat) error {
+	windowStart, windowEnd := calculateTimeWindow()
+
+	result, err := a.dryRunReport(ctx, windowStart, windowEnd)
+	if err != nil {
+		return err
+	}
+
+	return writeDryRunResult(w, result, format)
+}
+
+// dryRunReport builds the report of the time window without storing anything
+func (a *Aggregator) dryRunReport(ctx context.Context, windowStart, windowEnd time.Time) (*DryRunResult, error) {
+	reportDate := pgtype.Date{Time: windowEnd, Valid: true}
+
+	result := &DryRunResult{
+		ReportDate:  windowEnd.Format("2006-01-02"),
+		WindowStart: windowStart.UTC(),
+		WindowEnd:   windowEnd.UTC(),
+	}
+
+	existing, err := a.queries.GetReportRun(ctx, reportDate)
+	switch {
+	case err == nil:
+		result.ExistingStatus = string(existing.Status)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("failed to check if report run exists: %w", err)
+	}
+
+	// The run recomputes the rollup of the window before reading it, which gives the raw counts
+	counts, err := a.dbCountRawFeedback(ctx, windowStart, windowEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query feedback counts: %w", err)
+	}
+
+	summary := calculateFeedbackSummary(counts)
+	result.PositiveCount = summary.PositiveCount
+	result.NegativeCount = summary.NegativeCount
+
+	var history []ReportHistory
+
+	runs, err := a.dbReportHistory(ctx, windowEnd, slices.Max(anomalyBaselineDays))
+	if err != nil {
+		slog.Warn("Failed to load report history, skipping anomaly detection and trends", "error", err)
+	} else {
+		// Anomalies are detected without marking the report run
+		summary.Anomalies = detectAnomalies(summary, runs, windowEnd, a.anomalyThreshold).Descriptions()
+		summary.Trends = compareTrends(summary, runs, windowEnd)
+		history = reportHistory(runs)
+	}
+
+	// Text scores are never stored by a dry run
+	preview := *a
+	preview.persistScores = false
+
+	preview.analyzeMessages(ctx, &db.ReportRun{
+		ReportDate:  reportDate,
+		WindowStart: pgtype.Timestamptz{Time: windowStart, Valid: true},
+		WindowEnd:   pgtype.Timestamptz{Time: windowEnd, Valid: true},
+	}, summary)
+
+	if summary.Total == 0 {
+		return result, nil
+	}
+
+	request := a.newAsanaClient().buildTaskRequest(summary, history, windowStart, windowEnd)
+	result.AsanaTask = &request
+
+	if a.attachmentFormat != "" {
+		result.Attachment = fmt.Sprintf("feedback-%s.%s", result.ReportDate, a.attachmentFormat)
+	}
+
+	return result, nil
+}
+
+// writeDryRunResult writes the result of a dry run in the given format
+func writeDryRunResult(w io.Writer, result *DryRunResult, format DryRunFormat) error {
+	if format == DryRunFormatJSON {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("failed to write dry run result: %w", err)
+		}
+
+		return nil
+	}
+
+	if _, err := io.WriteString(w, formatDryRunResult(result)); err != nil {
+		return fmt.Errorf("failed to write dry run result: %w", err)
+	}
+
+	return nil
+}
+
+// formatDryRunResult renders the result of a dry run as text
+func formatDryRunResult(result *DryRunResult) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Dry run, no Asana task or report run was created\n\n")
+	fmt.Fprintf(&b, "Report date: %s\n", result.ReportDate)
+	fmt.Fprintf(&b, "Window: %s to %s (UTC)\n",
+		result.WindowStart.Format("2006-01-02 15:04"), result.WindowEnd.Format("2006-01-02 15:04"))
+	fmt.Fprintf(&b, "Feedback: %d positive, %d negative\n", result.PositiveCount, result.NegativeCount)
+
+	if result.ExistingStatus != "" {
+		fmt.Fprintf(&b, "Existing report run: %s, a real run would skip this day\n", result.ExistingStatus)
+	}
+
+	if result.AsanaTask == nil {
+		b.WriteString("\nNo feedback in the window, no Asana task would be created\n")
+
+		return b.String()
+	}
+
+	task := result.AsanaTask.Data
+
+	fmt.Fprintf(&b, "\nAsana task:\n")
+	fmt.Fprintf(&b, "Name: %s\n", task.Name)
+	fmt.Fprintf(&b, "Workspace: %s\n", task.Workspace)
+
+	for _, project := range task.Projects {
+		fmt.Fprintf(&b, "Project: %s\n", project)
+	}
+
+	for _, membership := range task.Memberships {
+		fmt.Fprintf(&b, "Project: %s, section: %s\n", membership.Project, membership.Section)
+	}
+
+	if task.Assignee != "" {
+		fmt.Fprintf(&b, "Assignee: %s\n", task.Assignee)
+	}
+
+	if task.DueOn != "" {
+		fmt.Fprintf(&b, "Due on: %s\n", task.DueOn)
+	}
+
+	for _, gid := range slices.Sorted(maps.Keys(task.CustomFields)) {
+		fmt.Fprintf(&b, "Custom field %s: %v\n", gid, task.CustomFields[gid])
+	}
+
+	if result.Attachment != "" {
+		fmt.Fprintf(&b, "Attachment: %s\n", result.Attachment)
+	}
+
+	if task.HTMLNotes != "" {
+		fmt.Fprintf(&b, "\nHTML notes:\n%s\n", task.HTMLNotes)
+	} else {
+		fmt.Fprintf(&b, "\nNotes:\n%s\n", task.Notes)
+	}
+
+	return b.String()
+}
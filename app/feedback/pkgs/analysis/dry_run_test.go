@@ -0,0 +1,126 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testDryRunResult creates a dry run result with a task request built from the given summary
+func testDryRunResult(summary *FeedbackSummary) *DryRunResult {
+	windowStart := time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	client := newAsanaClient("test-token", "workspace-123", "project-456")
+	client.options = AsanaTaskOptions{
+		CustomFields: map[CustomFieldMetric]string{CustomFieldTotal: "103"},
+		Assignees:    []string{"alice@example.com"},
+		DueDays:      2,
+	}
+
+	request := client.buildTaskRequest(summary, nil, windowStart, windowEnd)
+
+	return &DryRunResult{
+		ReportDate:    "2024-06-15",
+		WindowStart:   windowStart,
+		WindowEnd:     windowEnd,
+		PositiveCount: summary.PositiveCount,
+		NegativeCount: summary.NegativeCount,
+		AsanaTask:     &request,
+		Attachment:    "feedback-2024-06-15.csv",
+	}
+}
+
+func TestParseDryRunFormat(t *testing.T) {
+	for _, name := range []string{"text", "json"} {
+		if format, err := ParseDryRunFormat(name); err != nil || string(format) != name {
+			t.Errorf("Expected %q to be valid, got %q (err: %v)", name, format, err)
+		}
+	}
+
+	if _, err := ParseDryRunFormat("yaml"); err == nil {
+		t.Error("Expected error for unknown format, got nil")
+	}
+}
+
+func TestWriteDryRunResult_Text(t *testing.T) {
+	result := testDryRunResult(testSummary(80, 20))
+	result.ExistingStatus = "completed"
+
+	var buf bytes.Buffer
+	if err := writeDryRunResult(&buf, result, DryRunFormatText); err != nil {
+		t.Fatalf("writeDryRunResult failed: %v", err)
+	}
+
+	for _, expected := range []string{
+		"Report date: 2024-06-15",
+		"Window: 2024-06-14 00:00 to 2024-06-15 00:00 (UTC)",
+		"Feedback: 80 positive, 20 negative",
+		"Existing report run: completed, a real run would skip this day",
+		"Name: Daily Feedback Summary - 2024-06-15",
+		"Project: project-456",
+		"Assignee: alice@example.com",
+		"Due on: 2024-06-17",
+		"Custom field 103: 100",
+		"Attachment: feedback-2024-06-15.csv",
+		"• Positive: 80 (80.0%)",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected output to contain %q, got %q", expected, buf.String())
+		}
+	}
+}
+
+func TestWriteDryRunResult_NoFeedback(t *testing.T) {
+	result := &DryRunResult{ReportDate: "2024-06-15"}
+
+	var buf bytes.Buffer
+	if err := writeDryRunResult(&buf, result, DryRunFormatText); err != nil {
+		t.Fatalf("writeDryRunResult failed: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "no Asana task would be created") {
+		t.Errorf("Expected output to mention that no task is created, got %q", buf.String())
+	}
+
+	buf.Reset()
+
+	if err := writeDryRunResult(&buf, result, DryRunFormatJSON); err != nil {
+		t.Fatalf("writeDryRunResult failed: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), `"asana_task": null`) {
+		t.Errorf("Expected null task in JSON, got %q", buf.String())
+	}
+}
+
+func TestWriteDryRunResult_JSON(t *testing.T) {
+	result := testDryRunResult(testSummary(80, 20))
+
+	var buf bytes.Buffer
+	if err := writeDryRunResult(&buf, result, DryRunFormatJSON); err != nil {
+		t.Fatalf("writeDryRunResult failed: %v", err)
+	}
+
+	var decoded struct {
+		ReportDate    string           `json:"report_date"`
+		PositiveCount int64            `json:"positive_count"`
+		AsanaTask     AsanaTaskRequest `json:"asana_task"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Failed to parse JSON output: %v", err)
+	}
+
+	if decoded.ReportDate != "2024-06-15" || decoded.PositiveCount != 80 {
+		t.Errorf("Expected report date 2024-06-15 and 80 positive, got %+v", decoded)
+	}
+
+	// The payload is exactly the request sent to Asana
+	if decoded.AsanaTask.Data.Name != result.AsanaTask.Data.Name ||
+		decoded.AsanaTask.Data.Notes != result.AsanaTask.Data.Notes ||
+		decoded.AsanaTask.Data.DueOn != "2024-06-17" {
+		t.Errorf("Expected task request %+v, got %+v", result.AsanaTask.Data, decoded.AsanaTask.Data)
+	}
+}
@@ -0,0 +1,318 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const smtpTimeout = 30 * time.Second
+
+// emailHTMLTemplate renders the HTML part of the digest email
+var emailHTMLTemplate = template.Must(template.New("email").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif;">
+<h2>{{.Title}}</h2>
+{{if .Summary.Anomalies}}<div style="background: #fff3cd; border: 1px solid #ffc107; padding: 8px;">
+<strong>&#9888; Unusual feedback detected</strong>
+<ul>{{range .Summary.Anomalies}}<li>{{.}}</li>{{end}}</ul>
+</div>{{end}}
+<p>Window: {{.WindowStart}} to {{.WindowEnd}} (UTC)</p>
+<table cellpadding="6" style="border-collapse: collapse;">
+<tr>
+<td>Positive</td>
+<td><strong>{{.Summary.PositiveCount}}</strong></td>
+<td>{{printf "%.1f" .Summary.PositivePercent}}%</td>
+</tr>
+<tr>
+<td>Negative</td>
+<td><strong>{{.Summary.NegativeCount}}</strong></td>
+<td>{{printf "%.1f" .Summary.NegativePercent}}%</td>
+</tr>
+<tr>
+<td>Total</td>
+<td><strong>{{.Summary.Total}}</strong></td>
+<td></td>
+</tr>
+</table>
+{{range .Sections}}<p style="white-space: pre-line;">{{.}}</p>
+{{end}}{{if .AsanaTaskURL}}<p><a href="{{.AsanaTaskURL}}">View report in Asana</a></p>{{end}}
+<p style="color: #888;">This report was automatically generated by the feedback analysis job.</p>
+</body>
+</html>
+`))
+
+// SMTPConfig holds the configuration for the email digest
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// EmailClient sends emails over SMTP with STARTTLS and authentication
+type EmailClient struct {
+	host      string
+	port      int
+	username  string
+	password  string
+	from      string
+	tlsConfig *tls.Config
+}
+
+// newEmailClient creates a new SMTP email client
+func newEmailClient(cfg SMTPConfig) *EmailClient {
+	return &EmailClient{
+		host:      cfg.Host,
+		port:      cfg.Port,
+		username:  cfg.Username,
+		password:  cfg.Password,
+		from:      cfg.From,
+		tlsConfig: &tls.Config{ServerName: cfg.Host, MinVersion: tls.VersionTLS12},
+	}
+}
+
+// sendEmailSummary emails the aggregation results to the configured recipients
+func (a *Aggregator) sendEmailSummary(
+	ctx context.Context,
+	summary *FeedbackSummary,
+	windowStart, windowEnd time.Time,
+	asanaTaskGID string,
+) error {
+	// Skip if email isn't configured
+	if a.smtp.Host == "" || len(a.smtp.To) == 0 {
+		return nil
+	}
+
+	if summary.Total == 0 {
+		slog.Info("No feedback to report, skipping email digest")
+
+		return nil
+	}
+
+	client := newEmailClient(a.smtp)
+
+	message, err := buildEmailMessage(
+		a.smtp.From, a.smtp.To,
+		summary, windowStart, windowEnd,
+		a.asanaTaskURL(asanaTaskGID),
+	)
+	if err != nil {
+		return err
+	}
+
+	if err := client.sendMail(ctx, a.smtp.To, message); err != nil {
+		return err
+	}
+
+	slog.Info("Email digest sent successfully", "recipients", len(a.smtp.To))
+
+	return nil
+}
+
+// buildEmailMessage creates a multipart/alternative email with text and HTML parts
+func buildEmailMessage(
+	from string,
+	to []string,
+	summary *FeedbackSummary,
+	windowStart, windowEnd time.Time,
+	asanaTaskURL string,
+) ([]byte, error) {
+	// Plain text part uses the same content as the Asana task notes
+	textBody := formatTaskNotes(summary, windowStart, windowEnd)
+	if asanaTaskURL != "" {
+		textBody += "\n\nAsana task: " + asanaTaskURL
+	}
+
+	// HTML part shows the counts as a table followed by the same sections as the text part
+	var sections []string
+
+	header := formatNotesHeader(summary, windowStart, windowEnd)
+	for _, section := range append([]string{formatTrends(summary.Trends)}, fitNoteSections(header, summary)...) {
+		if section = strings.TrimSpace(section); section != "" {
+			sections = append(sections, section)
+		}
+	}
+
+	var htmlBody bytes.Buffer
+	if err := emailHTMLTemplate.Execute(&htmlBody, map[string]any{
+		"Title":        formatTaskTitle(summary, windowEnd),
+		"WindowStart":  windowStart.UTC().Format("2006-01-02 15:04"),
+		"WindowEnd":    windowEnd.UTC().Format("2006-01-02 15:04"),
+		"Summary":      summary,
+		"Sections":     sections,
+		"AsanaTaskURL": asanaTaskURL,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to render email HTML: %w", err)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	parts := []struct {
+		contentType string
+		content     string
+	}{
+		{contentType: "text/plain; charset=utf-8", content: textBody},
+		{contentType: "text/html; charset=utf-8", content: htmlBody.String()},
+	}
+
+	for _, part := range parts {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create email part: %w", err)
+		}
+
+		qp := quotedprintable.NewWriter(partWriter)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, fmt.Errorf("failed to write email part: %w", err)
+		}
+
+		if err := qp.Close(); err != nil {
+			return nil, fmt.Errorf("failed to write email part: %w", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finalize email body: %w", err)
+	}
+
+	var message bytes.Buffer
+	headers := []struct{ key, value string }{
+		{"From", from},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", formatTaskTitle(summary, windowEnd))},
+		{"Date", timeNow().UTC().Format(time.RFC1123Z)},
+		{"Message-ID", newMessageID(from)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + writer.Boundary()},
+	}
+
+	for _, h := range headers {
+		fmt.Fprintf(&message, "%s: %s\r\n", h.key, h.value)
+	}
+
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+// newMessageID generates a unique Message-ID header value using the sender's domain
+func newMessageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.TrimSuffix(from[at+1:], ">")
+	}
+
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("<%d@%s>", timeNow().UnixNano(), domain)
+	}
+
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(buf), domain)
+}
+
+// sendMail delivers a message to the recipients, upgrading the connection with STARTTLS
+func (c *EmailClient) sendMail(ctx context.Context, to []string, message []byte) error {
+	addr := net.JoinHostPort(c.host, strconv.Itoa(c.port))
+
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server %s: %w", addr, err)
+	}
+
+	// Bound the whole SMTP conversation
+	deadline := time.Now().Add(smtpTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	if err := conn.SetDeadline(deadline); err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to set SMTP connection deadline: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, c.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to create SMTP client: %w", err)
+	}
+	defer func() {
+		if err := client.Close(); err != nil {
+			slog.Debug("Failed to close SMTP connection", "error", err)
+		}
+	}()
+
+	// STARTTLS is mandatory, credentials must never be sent in clear text
+	if ok, _ := client.Extension("STARTTLS"); !ok {
+		return fmt.Errorf("SMTP server %s does not support STARTTLS", addr)
+	}
+
+	if err := client.StartTLS(c.tlsConfig); err != nil {
+		return fmt.Errorf("failed to start TLS: %w", err)
+	}
+
+	if c.username != "" {
+		auth := smtp.PlainAuth("", c.username, c.password, c.host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("failed to authenticate with SMTP server: %w", err)
+		}
+	}
+
+	// The envelope sender must be a bare address, the From header may include a display name
+	sender, err := mail.ParseAddress(c.from)
+	if err != nil {
+		return fmt.Errorf("invalid sender address %q: %w", c.from, err)
+	}
+
+	if err := client.Mail(sender.Address); err != nil {
+		return fmt.Errorf("failed to set sender %s: %w", sender.Address, err)
+	}
+
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("failed to add recipient %s: %w", recipient, err)
+		}
+	}
+
+	dataWriter, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to start message data: %w", err)
+	}
+
+	if _, err := dataWriter.Write(message); err != nil {
+		return fmt.Errorf("failed to write message data: %w", err)
+	}
+
+	if err := dataWriter.Close(); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+
+	if err := client.Quit(); err != nil {
+		return fmt.Errorf("failed to close SMTP session: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,349 @@
+package analysis
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTPServer is a minimal in-process SMTP server supporting STARTTLS and AUTH PLAIN
+type fakeSMTPServer struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	done      chan struct{}
+
+	// Recorded session data, valid after done is closed
+	usedTLS    bool
+	auth       string
+	from       string
+	recipients []string
+	data       string
+}
+
+// newFakeSMTPServer starts a fake SMTP server accepting a single session.
+// It returns the server and a TLS config trusting its certificate.
+func newFakeSMTPServer(t *testing.T) (*fakeSMTPServer, *tls.Config) {
+	t.Helper()
+
+	// Reuse the self-signed certificate of httptest's TLS server
+	certServer := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(certServer.Close)
+
+	transport, ok := certServer.Client().Transport.(*http.Transport)
+	if !ok {
+		t.Fatal("Unexpected transport type of httptest client")
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	server := &fakeSMTPServer{
+		listener:  listener,
+		tlsConfig: &tls.Config{Certificates: certServer.TLS.Certificates, MinVersion: tls.VersionTLS12},
+		done:      make(chan struct{}),
+	}
+
+	go server.serve(t)
+
+	clientTLS := &tls.Config{
+		RootCAs:    transport.TLSClientConfig.RootCAs,
+		ServerName: "127.0.0.1",
+		MinVersion: tls.VersionTLS12,
+	}
+
+	return server, clientTLS
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve(t *testing.T) {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer func() { _ = conn.Close() }()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) {
+		if _, err := io.WriteString(conn, line+"\r\n"); err != nil {
+			t.Errorf("Failed to write SMTP reply: %v", err)
+		}
+	}
+
+	reply("220 fake.example ESMTP")
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+		switch verb {
+		case "EHLO":
+			if s.usedTLS {
+				reply("250-fake.example\r\n250 AUTH PLAIN")
+			} else {
+				reply("250-fake.example\r\n250-STARTTLS\r\n250 AUTH PLAIN")
+			}
+		case "STARTTLS":
+			reply("220 Ready to start TLS")
+
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				t.Errorf("TLS handshake failed: %v", err)
+				return
+			}
+
+			conn = tlsConn
+			reader = bufio.NewReader(conn)
+			s.usedTLS = true
+		case "AUTH":
+			fields := strings.Fields(line)
+			decoded, err := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+			if err != nil {
+				reply("501 Invalid base64")
+				continue
+			}
+
+			s.auth = string(decoded)
+			reply("235 Authentication successful")
+		case "MAIL":
+			s.from = line
+			reply("250 OK")
+		case "RCPT":
+			s.recipients = append(s.recipients, line)
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+
+				if dataLine == ".\r\n" {
+					break
+				}
+
+				data.WriteString(dataLine)
+			}
+
+			s.data = data.String()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestBuildEmailMessage(t *testing.T) {
+	summary := &FeedbackSummary{
+		PositiveCount:   75,
+		NegativeCount:   25,
+		Total:           100,
+		PositivePercent: 75.0,
+		NegativePercent: 25.0,
+		NegativeSamples: []string{"Sync is slow & unreliable"},
+		Trends: []Trend{
+			{Label: "Day over day", CompareDate: time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+	windowStart := time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+	taskURL := "https://app.asana.com/0/project-456/1234567890"
+
+	raw, err := buildEmailMessage(
+		"FeedDuck <reports@example.com>",
+		[]string{"a@example.com", "b@example.com"},
+		summary, windowStart, windowEnd, taskURL,
+	)
+	if err != nil {
+		t.Fatalf("buildEmailMessage failed: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+
+	if subject := msg.Header.Get("Subject"); subject != "Daily Feedback Summary - 2024-06-15" {
+		t.Errorf("Expected subject 'Daily Feedback Summary - 2024-06-15', got %q", subject)
+	}
+
+	if to := msg.Header.Get("To"); to != "a@example.com, b@example.com" {
+		t.Errorf("Expected To 'a@example.com, b@example.com', got %q", to)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("Failed to parse Content-Type: %v", err)
+	}
+
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("Expected multipart/alternative, got %q", mediaType)
+	}
+
+	// Collect decoded parts by content type
+	parts := map[string]string{}
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("Failed to read part: %v", err)
+		}
+
+		content, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("Failed to read part content: %v", err)
+		}
+
+		parts[strings.SplitN(part.Header.Get("Content-Type"), ";", 2)[0]] = string(content)
+	}
+
+	text, ok := parts["text/plain"]
+	if !ok {
+		t.Fatal("Expected text/plain part")
+	}
+
+	// Quoted-printable text mode uses CRLF line endings
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if !strings.Contains(text, formatTaskNotes(summary, windowStart, windowEnd)) {
+		t.Errorf("Expected text part to contain task notes, got %q", text)
+	}
+
+	html, ok := parts["text/html"]
+	if !ok {
+		t.Fatal("Expected text/html part")
+	}
+
+	// The HTML part contains the same sections as the text part
+	for _, substr := range []string{
+		"<strong>75</strong>",
+		"25.0%",
+		`href="` + taskURL + `"`,
+		"Trends:",
+		"Day over day: no report for 2024-06-14",
+		"Negative feedback samples:",
+		"&#34;Sync is slow &amp; unreliable&#34;",
+	} {
+		if !strings.Contains(html, substr) {
+			t.Errorf("Expected HTML part to contain %q, got %q", substr, html)
+		}
+	}
+}
+
+func TestEmailClient_SendMail_Success(t *testing.T) {
+	server, clientTLS := newFakeSMTPServer(t)
+
+	client := newEmailClient(SMTPConfig{
+		Host:     "127.0.0.1",
+		Port:     server.port(),
+		Username: "user",
+		Password: "secret",
+		From:     "FeedDuck <reports@example.com>",
+	})
+	client.tlsConfig = clientTLS
+
+	recipients := []string{"a@example.com", "b@example.com"}
+	if err := client.sendMail(context.Background(), recipients, []byte("Subject: test\r\n\r\nhello\r\n")); err != nil {
+		t.Fatalf("sendMail failed: %v", err)
+	}
+
+	<-server.done
+
+	if !server.usedTLS {
+		t.Error("Expected session to be upgraded with STARTTLS")
+	}
+
+	if server.auth != "\x00user\x00secret" {
+		t.Errorf("Expected PLAIN credentials for user, got %q", server.auth)
+	}
+
+	if server.from != "MAIL FROM:<reports@example.com>" {
+		t.Errorf("Expected envelope sender reports@example.com, got %q", server.from)
+	}
+
+	if len(server.recipients) != 2 {
+		t.Errorf("Expected 2 recipients, got %d", len(server.recipients))
+	}
+
+	if !strings.Contains(server.data, "hello") {
+		t.Errorf("Expected message data to contain body, got %q", server.data)
+	}
+}
+
+func TestEmailClient_SendMail_RequiresSTARTTLS(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer func() { _ = listener.Close() }()
+
+	// Server that doesn't advertise STARTTLS
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+
+		reader := bufio.NewReader(conn)
+		_, _ = io.WriteString(conn, "220 plain.example ESMTP\r\n")
+
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			if strings.HasPrefix(strings.ToUpper(line), "EHLO") {
+				_, _ = io.WriteString(conn, "250-plain.example\r\n250 AUTH PLAIN\r\n")
+			} else {
+				_, _ = io.WriteString(conn, "221 Bye\r\n")
+				return
+			}
+		}
+	}()
+
+	client := newEmailClient(SMTPConfig{
+		Host: "127.0.0.1",
+		Port: listener.Addr().(*net.TCPAddr).Port,
+		From: "reports@example.com",
+	})
+
+	err = client.sendMail(context.Background(), []string{"a@example.com"}, []byte("hello"))
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("Expected STARTTLS error, got %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package analysis
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRowGroupSize is the number of rows buffered in memory before a Parquet row group is written
+const parquetRowGroupSize = 50_000
+
+// ExportFormat is the file format of exported feedback
+type ExportFormat string
+
+const (
+	ExportFormatCSV     ExportFormat = "csv"
+	ExportFormatJSONL   ExportFormat = "jsonl"
+	ExportFormatParquet ExportFormat = "parquet"
+)
+
+// ParseExportFormat validates the name of an export format
+func ParseExportFormat(name string) (ExportFormat, error) {
+	switch format := ExportFormat(name); format {
+	case ExportFormatCSV, ExportFormatJSONL, ExportFormatParquet:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown export format %q, expected %q, %q or %q",
+			name, ExportFormatCSV, ExportFormatJSONL, ExportFormatParquet)
+	}
+}
+
+// ContentType returns the MIME type of the format
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportFormatJSONL:
+		return "application/x-ndjson"
+	case ExportFormatParquet:
+		return "application/vnd.apache.parquet"
+	default:
+		return "text/csv"
+	}
+}
+
+// exportedFeedback is a single feedback record of an export
+type exportedFeedback struct {
+	ID        int32     `json:"id" parquet:"id,delta"`
+	CreatedAt time.Time `json:"created_at" parquet:"created_at,timestamp(microsecond)"`
+	Sentiment string    `json:"sentiment" parquet:"sentiment,dict"`
+	Message   *string   `json:"message" parquet:"message,optional"`
+}
+
+// newExportedFeedback converts a feedback row, the message is nil for feedback without a message
+func newExportedFeedback(feedback *db.ExportFeedbackRow) exportedFeedback {
+	record := exportedFeedback{
+		ID:        feedback.ID,
+		CreatedAt: feedback.CreatedAt.Time.UTC(),
+		Sentiment: string(feedback.Sentiment),
+	}
+
+	if feedback.Message.Valid {
+		message := feedback.Message.String
+		record.Message = &message
+	}
+
+	return record
+}
+
+// FeedbackWriter writes feedback records in an export format one at a time
+type FeedbackWriter interface {
+	Write(feedback *db.ExportFeedbackRow) error
+	// Close flushes buffered records, it doesn't close the underlying writer
+	Close() error
+}
+
+// NewFeedbackWriter creates a writer of feedback records in the given format
+func NewFeedbackWriter(w io.Writer, format ExportFormat) (FeedbackWriter, error) {
+	switch format {
+	case ExportFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write([]string{"id", "created_at", "sentiment", "message"}); err != nil {
+			return nil, fmt.Errorf("failed to write CSV header: %w", err)
+		}
+
+		return &csvFeedbackWriter{writer: writer}, nil
+	case ExportFormatJSONL:
+		return &jsonlFeedbackWriter{encoder: json.NewEncoder(w)}, nil
+	case ExportFormatParquet:
+		return &parquetFeedbackWriter{writer: newParquetWriter[exportedFeedback](w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// csvFeedbackWriter writes feedback as CSV with a header row
+type csvFeedbackWriter struct {
+	writer *csv.Writer
+}
+
+// Write writes a single feedback record
+func (w *csvFeedbackWriter) Write(feedback *db.ExportFeedbackRow) error {
+	record := []string{
+		strconv.FormatInt(int64(feedback.ID), 10),
+		feedback.CreatedAt.Time.UTC().Format(time.RFC3339),
+		string(feedback.Sentiment),
+		feedback.Message.String,
+	}
+
+	if err := w.writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write CSV record: %w", err)
+	}
+
+	return nil
+}
+
+// Close flushes buffered records
+func (w *csvFeedbackWriter) Close() error {
+	w.writer.Flush()
+
+	if err := w.writer.Error(); err != nil {
+		return fmt.Errorf("failed to flush CSV: %w", err)
+	}
+
+	return nil
+}
+
+// jsonlFeedbackWriter writes feedback as one JSON object per line
+type jsonlFeedbackWriter struct {
+	encoder *json.Encoder
+}
+
+// Write writes a single feedback record
+func (w *jsonlFeedbackWriter) Write(feedback *db.ExportFeedbackRow) error {
+	if err := w.encoder.Encode(newExportedFeedback(feedback)); err != nil {
+		return fmt.Errorf("failed to write JSON record: %w", err)
+	}
+
+	return nil
+}
+
+// Close is a no-op, records are written immediately
+func (w *jsonlFeedbackWriter) Close() error {
+	return nil
+}
+
+// parquetFeedbackWriter writes feedback as a Parquet file, the footer is written by Close
+type parquetFeedbackWriter struct {
+	writer *parquet.GenericWriter[exportedFeedback]
+}
+
+// Write buffers a single feedback record, full row groups are written to the underlying writer
+func (w *parquetFeedbackWriter) Write(feedback *db.ExportFeedbackRow) error {
+	if _, err := w.writer.Write([]exportedFeedback{newExportedFeedback(feedback)}); err != nil {
+		return fmt.Errorf("failed to write Parquet record: %w", err)
+	}
+
+	return nil
+}
+
+// Close writes the buffered rows and the Parquet footer
+func (w *parquetFeedbackWriter) Close() error {
+	if err := w.writer.Close(); err != nil {
+		return fmt.Errorf("failed to close Parquet writer: %w", err)
+	}
+
+	return nil
+}
+
+// newParquetWriter creates a zstd compressed Parquet writer, memory is bounded by the row group size
+func newParquetWriter[T any](w io.Writer) *parquet.GenericWriter[T] {
+	return parquet.NewGenericWriter[T](w,
+		parquet.Compression(&parquet.Zstd),
+		parquet.MaxRowsPerRowGroup(parquetRowGroupSize),
+	)
+}
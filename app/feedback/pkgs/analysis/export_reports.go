@@ -0,0 +1,219 @@
+package analysis
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/parquet-go/parquet-go"
+)
+
+// secondsPerDay converts report dates to the days since the Unix epoch of the Parquet DATE type
+const secondsPerDay = 24 * 60 * 60
+
+// reportRunColumns are the exported columns of report runs, in CSV column order
+var reportRunColumns = []string{
+	"report_date", "window_start", "window_end", "positive_count", "negative_count", "status",
+	"asana_task_gid", "created_at", "completed_at", "is_anomaly", "anomaly_details",
+}
+
+// exportedReportRun is a single report run record of a JSONL export, nullable columns are null
+type exportedReportRun struct {
+	ReportDate     string     `json:"report_date"`
+	WindowStart    time.Time  `json:"window_start"`
+	WindowEnd      time.Time  `json:"window_end"`
+	PositiveCount  int32      `json:"positive_count"`
+	NegativeCount  int32      `json:"negative_count"`
+	Status         string     `json:"status"`
+	AsanaTaskGID   *string    `json:"asana_task_gid"`
+	CreatedAt      time.Time  `json:"created_at"`
+	CompletedAt    *time.Time `json:"completed_at"`
+	IsAnomaly      bool       `json:"is_anomaly"`
+	AnomalyDetails *string    `json:"anomaly_details"`
+}
+
+// parquetReportRun is a single report run record of a Parquet export.
+// report_date uses the DATE type, stored as days since the Unix epoch.
+type parquetReportRun struct {
+	ReportDate     int32      `parquet:"report_date,date"`
+	WindowStart    time.Time  `parquet:"window_start,timestamp(microsecond)"`
+	WindowEnd      time.Time  `parquet:"window_end,timestamp(microsecond)"`
+	PositiveCount  int32      `parquet:"positive_count"`
+	NegativeCount  int32      `parquet:"negative_count"`
+	Status         string     `parquet:"status,dict"`
+	AsanaTaskGID   *string    `parquet:"asana_task_gid,optional"`
+	CreatedAt      time.Time  `parquet:"created_at,timestamp(microsecond)"`
+	CompletedAt    *time.Time `parquet:"completed_at,optional,timestamp(microsecond)"`
+	IsAnomaly      bool       `parquet:"is_anomaly"`
+	AnomalyDetails *string    `parquet:"anomaly_details,optional"`
+}
+
+// ReportRunWriter writes report run records in an export format one at a time
+type ReportRunWriter interface {
+	Write(run *db.ReportRun) error
+	// Close flushes buffered records, it doesn't close the underlying writer
+	Close() error
+}
+
+// NewReportRunWriter creates a writer of report run records in the given format
+func NewReportRunWriter(w io.Writer, format ExportFormat) (ReportRunWriter, error) {
+	switch format {
+	case ExportFormatCSV:
+		writer := csv.NewWriter(w)
+		if err := writer.Write(reportRunColumns); err != nil {
+			return nil, fmt.Errorf("failed to write CSV header: %w", err)
+		}
+
+		return &csvReportRunWriter{writer: writer}, nil
+	case ExportFormatJSONL:
+		return &jsonlReportRunWriter{encoder: json.NewEncoder(w)}, nil
+	case ExportFormatParquet:
+		return &parquetReportRunWriter{writer: newParquetWriter[parquetReportRun](w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// newExportedReportRun converts a report run row
+func newExportedReportRun(run *db.ReportRun) exportedReportRun {
+	return exportedReportRun{
+		ReportDate:     run.ReportDate.Time.Format(time.DateOnly),
+		WindowStart:    run.WindowStart.Time.UTC(),
+		WindowEnd:      run.WindowEnd.Time.UTC(),
+		PositiveCount:  run.PositiveCount,
+		NegativeCount:  run.NegativeCount,
+		Status:         string(run.Status),
+		AsanaTaskGID:   textPointer(run.AsanaTaskGid),
+		CreatedAt:      run.CreatedAt.Time.UTC(),
+		CompletedAt:    timePointer(run.CompletedAt),
+		IsAnomaly:      run.IsAnomaly,
+		AnomalyDetails: textPointer(run.AnomalyDetails),
+	}
+}
+
+// csvReportRunWriter writes report runs as CSV with a header row, null values are empty
+type csvReportRunWriter struct {
+	writer *csv.Writer
+}
+
+// Write writes a single report run record
+func (w *csvReportRunWriter) Write(run *db.ReportRun) error {
+	completedAt := ""
+	if run.CompletedAt.Valid {
+		completedAt = run.CompletedAt.Time.UTC().Format(time.RFC3339)
+	}
+
+	record := []string{
+		run.ReportDate.Time.Format(time.DateOnly),
+		run.WindowStart.Time.UTC().Format(time.RFC3339),
+		run.WindowEnd.Time.UTC().Format(time.RFC3339),
+		strconv.FormatInt(int64(run.PositiveCount), 10),
+		strconv.FormatInt(int64(run.NegativeCount), 10),
+		string(run.Status),
+		run.AsanaTaskGid.String,
+		run.CreatedAt.Time.UTC().Format(time.RFC3339),
+		completedAt,
+		strconv.FormatBool(run.IsAnomaly),
+		run.AnomalyDetails.String,
+	}
+
+	if err := w.writer.Write(record); err != nil {
+		return fmt.Errorf("failed to write CSV record: %w", err)
+	}
+
+	return nil
+}
+
+// Close flushes buffered records
+func (w *csvReportRunWriter) Close() error {
+	w.writer.Flush()
+
+	if err := w.writer.Error(); err != nil {
+		return fmt.Errorf("failed to flush CSV: %w", err)
+	}
+
+	return nil
+}
+
+// jsonlReportRunWriter writes report runs as one JSON object per line
+type jsonlReportRunWriter struct {
+	encoder *json.Encoder
+}
+
+// Write writes a single report run record
+func (w *jsonlReportRunWriter) Write(run *db.ReportRun) error {
+	if err := w.encoder.Encode(newExportedReportRun(run)); err != nil {
+		return fmt.Errorf("failed to write JSON record: %w", err)
+	}
+
+	return nil
+}
+
+// Close is a no-op, records are written immediately
+func (w *jsonlReportRunWriter) Close() error {
+	return nil
+}
+
+// parquetReportRunWriter writes report runs as a Parquet file, the footer is written by Close
+type parquetReportRunWriter struct {
+	writer *parquet.GenericWriter[parquetReportRun]
+}
+
+// Write buffers a single report run record
+func (w *parquetReportRunWriter) Write(run *db.ReportRun) error {
+	exported := newExportedReportRun(run)
+
+	record := parquetReportRun{
+		ReportDate:     int32(run.ReportDate.Time.Unix() / secondsPerDay), // #nosec G115 - dates fit in int32
+		WindowStart:    exported.WindowStart,
+		WindowEnd:      exported.WindowEnd,
+		PositiveCount:  exported.PositiveCount,
+		NegativeCount:  exported.NegativeCount,
+		Status:         exported.Status,
+		AsanaTaskGID:   exported.AsanaTaskGID,
+		CreatedAt:      exported.CreatedAt,
+		CompletedAt:    exported.CompletedAt,
+		IsAnomaly:      exported.IsAnomaly,
+		AnomalyDetails: exported.AnomalyDetails,
+	}
+
+	if _, err := w.writer.Write([]parquetReportRun{record}); err != nil {
+		return fmt.Errorf("failed to write Parquet record: %w", err)
+	}
+
+	return nil
+}
+
+// Close writes the buffered rows and the Parquet footer
+func (w *parquetReportRunWriter) Close() error {
+	if err := w.writer.Close(); err != nil {
+		return fmt.Errorf("failed to close Parquet writer: %w", err)
+	}
+
+	return nil
+}
+
+// textPointer returns nil for NULL text
+func textPointer(text pgtype.Text) *string {
+	if !text.Valid {
+		return nil
+	}
+
+	return &text.String
+}
+
+// timePointer returns nil for a NULL timestamp
+func timePointer(ts pgtype.Timestamptz) *time.Time {
+	if !ts.Valid {
+		return nil
+	}
+
+	t := ts.Time.UTC()
+
+	return &t
+}
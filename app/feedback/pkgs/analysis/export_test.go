@@ -0,0 +1,231 @@
+package analysis
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/parquet-go/parquet-go"
+)
+
+// testFeedbackRows creates feedback rows with a message containing CSV special characters and a missing message
+func testFeedbackRows() []db.ExportFeedbackRow {
+	createdAt := pgtype.Timestamptz{Time: time.Date(2024, time.June, 14, 10, 30, 0, 0, time.UTC), Valid: true}
+
+	return []db.ExportFeedbackRow{
+		{
+			ID:        1,
+			CreatedAt: createdAt,
+			Sentiment: db.SentimentTypeNegative,
+			Message:   pgtype.Text{String: "Broken, \"again\"\nplease fix", Valid: true},
+		},
+		{
+			ID:        2,
+			CreatedAt: createdAt,
+			Sentiment: db.SentimentTypePositive,
+		},
+	}
+}
+
+func TestEncodeFeedback(t *testing.T) {
+	tests := []struct {
+		format   ExportFormat
+		expected string
+	}{
+		{
+			format: ExportFormatCSV,
+			expected: "id,created_at,sentiment,message\n" +
+				"1,2024-06-14T10:30:00Z,negative,\"Broken, \"\"again\"\"\nplease fix\"\n" +
+				"2,2024-06-14T10:30:00Z,positive,\n",
+		},
+		{
+			format: ExportFormatJSONL,
+			expected: `{"id":1,"created_at":"2024-06-14T10:30:00Z","sentiment":"negative",` +
+				`"message":"Broken, \"again\"\nplease fix"}` + "\n" +
+				`{"id":2,"created_at":"2024-06-14T10:30:00Z","sentiment":"positive","message":null}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			result, err := encodeFeedback(testFeedbackRows(), tt.format)
+			if err != nil {
+				t.Fatalf("encodeFeedback failed: %v", err)
+			}
+
+			if string(result) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, string(result))
+			}
+		})
+	}
+}
+
+func TestParseExportFormat(t *testing.T) {
+	for _, name := range []string{"csv", "jsonl", "parquet"} {
+		if format, err := ParseExportFormat(name); err != nil || string(format) != name {
+			t.Errorf("Expected %q to be valid, got %q (err: %v)", name, format, err)
+		}
+	}
+
+	if _, err := ParseExportFormat("xlsx"); err == nil {
+		t.Error("Expected error for unknown format, got nil")
+	}
+}
+
+func TestNewFeedbackWriter_Parquet(t *testing.T) {
+	var buf bytes.Buffer
+
+	writer, err := NewFeedbackWriter(&buf, ExportFormatParquet)
+	if err != nil {
+		t.Fatalf("NewFeedbackWriter failed: %v", err)
+	}
+
+	rows := testFeedbackRows()
+	for i := range rows {
+		if err := writer.Write(&rows[i]); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	records, err := parquet.Read[exportedFeedback](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read Parquet file: %v", err)
+	}
+
+	expected := []exportedFeedback{newExportedFeedback(&rows[0]), newExportedFeedback(&rows[1])}
+	if !reflect.DeepEqual(records, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, records)
+	}
+}
+
+// testReportRuns creates a completed report run with an anomaly and a pending run without a task
+func testReportRuns() []db.ReportRun {
+	reportDate := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+	timestamp := func(t time.Time) pgtype.Timestamptz { return pgtype.Timestamptz{Time: t, Valid: true} }
+
+	return []db.ReportRun{
+		{
+			ReportDate:     pgtype.Date{Time: reportDate, Valid: true},
+			WindowStart:    timestamp(reportDate.AddDate(0, 0, -1)),
+			WindowEnd:      timestamp(reportDate),
+			PositiveCount:  10,
+			NegativeCount:  5,
+			AsanaTaskGid:   pgtype.Text{String: "1234567890", Valid: true},
+			CreatedAt:      timestamp(reportDate.Add(time.Minute)),
+			Status:         db.ReportStatusCompleted,
+			CompletedAt:    timestamp(reportDate.Add(2 * time.Minute)),
+			IsAnomaly:      true,
+			AnomalyDetails: pgtype.Text{String: "negative rate, 7-day baseline", Valid: true},
+		},
+		{
+			ReportDate:  pgtype.Date{Time: reportDate.AddDate(0, 0, 1), Valid: true},
+			WindowStart: timestamp(reportDate),
+			WindowEnd:   timestamp(reportDate.AddDate(0, 0, 1)),
+			CreatedAt:   timestamp(reportDate.AddDate(0, 0, 1).Add(time.Minute)),
+			Status:      db.ReportStatusPending,
+		},
+	}
+}
+
+func TestNewReportRunWriter(t *testing.T) {
+	tests := []struct {
+		format   ExportFormat
+		expected string
+	}{
+		{
+			format: ExportFormatCSV,
+			expected: "report_date,window_start,window_end,positive_count,negative_count,status," +
+				"asana_task_gid,created_at,completed_at,is_anomaly,anomaly_details\n" +
+				"2024-06-15,2024-06-14T00:00:00Z,2024-06-15T00:00:00Z,10,5,completed,1234567890," +
+				"2024-06-15T00:01:00Z,2024-06-15T00:02:00Z,true,\"negative rate, 7-day baseline\"\n" +
+				"2024-06-16,2024-06-15T00:00:00Z,2024-06-16T00:00:00Z,0,0,pending,,2024-06-16T00:01:00Z,,false,\n",
+		},
+		{
+			format: ExportFormatJSONL,
+			expected: `{"report_date":"2024-06-15","window_start":"2024-06-14T00:00:00Z",` +
+				`"window_end":"2024-06-15T00:00:00Z","positive_count":10,"negative_count":5,"status":"completed",` +
+				`"asana_task_gid":"1234567890","created_at":"2024-06-15T00:01:00Z",` +
+				`"completed_at":"2024-06-15T00:02:00Z","is_anomaly":true,` +
+				`"anomaly_details":"negative rate, 7-day baseline"}` + "\n" +
+				`{"report_date":"2024-06-16","window_start":"2024-06-15T00:00:00Z",` +
+				`"window_end":"2024-06-16T00:00:00Z","positive_count":0,"negative_count":0,"status":"pending",` +
+				`"asana_task_gid":null,"created_at":"2024-06-16T00:01:00Z","completed_at":null,` +
+				`"is_anomaly":false,"anomaly_details":null}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+
+			writer, err := NewReportRunWriter(&buf, tt.format)
+			if err != nil {
+				t.Fatalf("NewReportRunWriter failed: %v", err)
+			}
+
+			runs := testReportRuns()
+			for i := range runs {
+				if err := writer.Write(&runs[i]); err != nil {
+					t.Fatalf("Write failed: %v", err)
+				}
+			}
+
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Close failed: %v", err)
+			}
+
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
+
+func TestNewReportRunWriter_Parquet(t *testing.T) {
+	var buf bytes.Buffer
+
+	writer, err := NewReportRunWriter(&buf, ExportFormatParquet)
+	if err != nil {
+		t.Fatalf("NewReportRunWriter failed: %v", err)
+	}
+
+	runs := testReportRuns()
+	for i := range runs {
+		if err := writer.Write(&runs[i]); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	records, err := parquet.Read[parquetReportRun](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read Parquet file: %v", err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(records))
+	}
+
+	reportDate := time.Unix(int64(records[0].ReportDate)*secondsPerDay, 0).UTC()
+	if date := reportDate.Format(time.DateOnly); date != "2024-06-15" {
+		t.Errorf("Expected report date 2024-06-15, got %s", date)
+	}
+
+	if records[0].AsanaTaskGID == nil || *records[0].AsanaTaskGID != "1234567890" {
+		t.Errorf("Expected task GID 1234567890, got %v", records[0].AsanaTaskGID)
+	}
+
+	if records[1].CompletedAt != nil || records[1].AnomalyDetails != nil {
+		t.Errorf("Expected NULL completed_at and anomaly_details, got %+v", records[1])
+	}
+}
@@ -0,0 +1,195 @@
+package analysis
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+)
+
+const (
+	// DefaultTopTerms is the default number of emerging terms reported per sentiment
+	DefaultTopTerms = 5
+	// DefaultKeywordBaselineDays is the default number of days of the trailing corpus
+	DefaultKeywordBaselineDays = 14
+
+	// keywordMinCount is the minimum number of messages of the day containing a term
+	keywordMinCount = 3
+	// keywordMinScore is the log-likelihood ratio of a significant term (p < 0.001)
+	keywordMinScore = 10.83
+	// maxCorpusMessages bounds the number of messages loaded for the trailing corpus
+	maxCorpusMessages = 50000
+)
+
+// KeyTerm is a term that is used significantly more often than in the trailing corpus
+type KeyTerm struct {
+	Term string
+	// Count is the number of messages of the day containing the term, Messages the number of all messages
+	Count    int
+	Messages int
+	// BaselineCount and BaselineMessages are the same for the trailing corpus
+	BaselineCount    int
+	BaselineMessages int
+	// Score is the log-likelihood ratio (G²) of the term
+	Score float64
+}
+
+// Share returns the fraction of the day's messages containing the term
+func (t KeyTerm) Share() float64 {
+	if t.Messages == 0 {
+		return 0
+	}
+
+	return float64(t.Count) / float64(t.Messages)
+}
+
+// BaselineShare returns the fraction of the trailing corpus messages containing the term
+func (t KeyTerm) BaselineShare() float64 {
+	if t.BaselineMessages == 0 {
+		return 0
+	}
+
+	return float64(t.BaselineCount) / float64(t.BaselineMessages)
+}
+
+// termCounts counts the number of messages of a sentiment containing each term
+func termCounts(messages []FeedbackMessage, sentiment db.SentimentType) (map[string]int, int) {
+	counts := map[string]int{}
+	total := 0
+
+	for _, message := range messages {
+		if message.Sentiment != sentiment {
+			continue
+		}
+
+		total++
+
+		for term := range messageTerms(message.Message) {
+			counts[term]++
+		}
+	}
+
+	return counts, total
+}
+
+// extractKeyTerms returns the top n terms of the day's messages of a sentiment that are
+// significantly more frequent than in the trailing corpus, scored by the log-likelihood ratio.
+// Nothing is returned without a trailing corpus to compare with.
+func extractKeyTerms(day, corpus []FeedbackMessage, sentiment db.SentimentType, n int) []KeyTerm {
+	if n <= 0 {
+		return nil
+	}
+
+	dayCounts, dayTotal := termCounts(day, sentiment)
+	corpusCounts, corpusTotal := termCounts(corpus, sentiment)
+
+	if dayTotal == 0 || corpusTotal == 0 {
+		return nil
+	}
+
+	var terms []KeyTerm
+
+	for term, count := range dayCounts {
+		baselineCount := corpusCounts[term]
+
+		// Only terms that became more frequent are emerging
+		share := float64(count) / float64(dayTotal)
+		if count < keywordMinCount || share <= float64(baselineCount)/float64(corpusTotal) {
+			continue
+		}
+
+		score := logLikelihood(count, dayTotal, baselineCount, corpusTotal)
+		if score < keywordMinScore {
+			continue
+		}
+
+		terms = append(terms, KeyTerm{
+			Term:             term,
+			Count:            count,
+			Messages:         dayTotal,
+			BaselineCount:    baselineCount,
+			BaselineMessages: corpusTotal,
+			Score:            score,
+		})
+	}
+
+	// Phrases are preferred over their words when they score the same
+	slices.SortFunc(terms, func(a, b KeyTerm) int {
+		return cmp.Or(
+			cmp.Compare(b.Score, a.Score),
+			cmp.Compare(strings.Count(b.Term, " "), strings.Count(a.Term, " ")),
+			cmp.Compare(a.Term, b.Term),
+		)
+	})
+
+	return selectKeyTerms(terms, n)
+}
+
+// selectKeyTerms picks the first n terms, skipping words already covered by a selected phrase
+func selectKeyTerms(terms []KeyTerm, n int) []KeyTerm {
+	selected := make([]KeyTerm, 0, n)
+
+	for _, term := range terms {
+		if len(selected) == n {
+			break
+		}
+
+		covered := slices.ContainsFunc(selected, func(s KeyTerm) bool {
+			return slices.Contains(strings.Fields(s.Term), term.Term)
+		})
+		if covered {
+			continue
+		}
+
+		selected = append(selected, term)
+	}
+
+	return selected
+}
+
+// logLikelihood computes Dunning's log-likelihood ratio (G²) of a term occurring in a of n1
+// messages of the day and in b of n2 messages of the corpus
+func logLikelihood(a, n1, b, n2 int) float64 {
+	observed := [4]float64{float64(a), float64(n1 - a), float64(b), float64(n2 - b)}
+	total := float64(n1 + n2)
+	withTerm := float64(a + b)
+	withoutTerm := total - withTerm
+
+	expected := [4]float64{
+		float64(n1) * withTerm / total,
+		float64(n1) * withoutTerm / total,
+		float64(n2) * withTerm / total,
+		float64(n2) * withoutTerm / total,
+	}
+
+	var g2 float64
+
+	for i := range observed {
+		if observed[i] > 0 && expected[i] > 0 {
+			g2 += observed[i] * math.Log(observed[i]/expected[i])
+		}
+	}
+
+	return 2 * g2
+}
+
+// formatKeyTerms creates a section of the Asana task notes listing emerging terms
+func formatKeyTerms(title string, terms []KeyTerm) string {
+	if len(terms) == 0 {
+		return ""
+	}
+
+	var section strings.Builder
+
+	section.WriteString("\n\n" + title + ":")
+
+	for _, term := range terms {
+		fmt.Fprintf(&section, "\n• %s: %d messages (%.1f%% vs. %.1f%% usually)",
+			term.Term, term.Count, term.Share()*100, term.BaselineShare()*100)
+	}
+
+	return section.String()
+}
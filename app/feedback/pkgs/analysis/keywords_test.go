@@ -0,0 +1,105 @@
+package analysis
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+)
+
+func TestLogLikelihood(t *testing.T) {
+	tests := []struct {
+		name           string
+		a, n1, b, n2   int
+		expectedResult float64
+	}{
+		{name: "more frequent than usual", a: 10, n1: 100, b: 10, n2: 1000, expectedResult: 22.9078},
+		{name: "as frequent as usual", a: 5, n1: 100, b: 50, n2: 1000, expectedResult: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := logLikelihood(tt.a, tt.n1, tt.b, tt.n2); math.Abs(result-tt.expectedResult) > 1e-3 {
+				t.Errorf("Expected %.4f, got %.4f", tt.expectedResult, result)
+			}
+		})
+	}
+}
+
+// feedbackMessages creates messages of a sentiment from repeated texts
+func feedbackMessages(sentiment db.SentimentType, texts map[string]int) []FeedbackMessage {
+	var messages []FeedbackMessage
+
+	for _, text := range slices.Sorted(maps.Keys(texts)) {
+		for i := range texts[text] {
+			messages = append(messages, FeedbackMessage{
+				ID:        int32(len(messages)), // #nosec G115 - small test values
+				Sentiment: sentiment,
+				Message:   fmt.Sprintf("%s %d", text, i),
+			})
+		}
+	}
+
+	return messages
+}
+
+func TestExtractKeyTerms(t *testing.T) {
+	corpus := feedbackMessages(db.SentimentTypeNegative, map[string]int{
+		"search is slow":      50,
+		"dark mode missing":   50,
+		"checkout page error": 2,
+	})
+
+	day := feedbackMessages(db.SentimentTypeNegative, map[string]int{
+		"search is slow":      5,
+		"checkout page error": 10,
+	})
+
+	terms := extractKeyTerms(day, corpus, db.SentimentTypeNegative, 5)
+	if len(terms) == 0 {
+		t.Fatal("Expected emerging terms, got none")
+	}
+
+	// Phrases are preferred, their words are covered by them
+	if terms[0].Term != "checkout page" {
+		t.Errorf("Expected a checkout phrase first, got %+v", terms)
+	}
+
+	for _, term := range terms {
+		if term.Term == "search" || term.Term == "slow" {
+			t.Errorf("Expected usual terms not to be reported, got %+v", term)
+		}
+
+		if term.Count != 10 || term.Messages != 15 {
+			t.Errorf("Expected term in 10 of 15 messages, got %+v", term)
+		}
+	}
+
+	t.Run("other sentiment", func(t *testing.T) {
+		if terms := extractKeyTerms(day, corpus, db.SentimentTypePositive, 5); terms != nil {
+			t.Errorf("Expected no terms, got %+v", terms)
+		}
+	})
+
+	t.Run("no trailing corpus", func(t *testing.T) {
+		if terms := extractKeyTerms(day, nil, db.SentimentTypeNegative, 5); terms != nil {
+			t.Errorf("Expected no terms without a corpus, got %+v", terms)
+		}
+	})
+}
+
+func TestFormatKeyTerms(t *testing.T) {
+	terms := []KeyTerm{{Term: "checkout page", Count: 10, Messages: 40, BaselineCount: 2, BaselineMessages: 200}}
+
+	expected := "\n\nEmerging terms:\n• checkout page: 10 messages (25.0% vs. 1.0% usually)"
+	if result := formatKeyTerms("Emerging terms", terms); result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	if result := formatKeyTerms("Emerging terms", nil); result != "" {
+		t.Errorf("Expected empty section, got %q", result)
+	}
+}
@@ -0,0 +1,278 @@
+package analysis
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+)
+
+// notesTemplateFuncs are the helper functions available in notes templates
+var notesTemplateFuncs = map[string]any{
+	"date": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02")
+	},
+	"datetime": func(t time.Time) string {
+		return t.UTC().Format("2006-01-02 15:04")
+	},
+	"percent": func(value float64) string {
+		return fmt.Sprintf("%.1f%%", value)
+	},
+}
+
+// ReportHistory is an earlier daily report available to notes templates
+type ReportHistory struct {
+	ReportDate      time.Time
+	PositiveCount   int64
+	NegativeCount   int64
+	Total           int64
+	PositivePercent float64
+	NegativePercent float64
+	IsAnomaly       bool
+}
+
+// NotesData is the data passed to notes templates
+type NotesData struct {
+	Summary     *FeedbackSummary
+	Title       string
+	WindowStart time.Time
+	WindowEnd   time.Time
+	// History holds the earlier daily reports, oldest first
+	History []ReportHistory
+	// DefaultNotes is the built-in plain text notes, e.g. to append them to a custom header
+	DefaultNotes string
+}
+
+// NotesTemplate renders the Asana task description from a user-provided template
+type NotesTemplate struct {
+	name string
+	html bool
+	text *texttemplate.Template
+	page *htmltemplate.Template
+}
+
+// LoadNotesTemplate parses a text/template file rendering the plain text notes of the task
+func LoadNotesTemplate(path string) (*NotesTemplate, error) {
+	content, err := os.ReadFile(path) // #nosec G304 -- path is provided by the operator
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notes template: %w", err)
+	}
+
+	name := filepath.Base(path)
+
+	text, err := texttemplate.New(name).Funcs(notesTemplateFuncs).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse notes template: %w", err)
+	}
+
+	tmpl := &NotesTemplate{name: name, text: text}
+
+	return tmpl, tmpl.validate()
+}
+
+// LoadHTMLNotesTemplate parses an html/template file rendering the rich text notes (html_notes) of the task.
+// The output must be wrapped in a <body> element and be valid XML as required by Asana.
+func LoadHTMLNotesTemplate(path string) (*NotesTemplate, error) {
+	content, err := os.ReadFile(path) // #nosec G304 -- path is provided by the operator
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HTML notes template: %w", err)
+	}
+
+	name := filepath.Base(path)
+
+	page, err := htmltemplate.New(name).Funcs(notesTemplateFuncs).Option("missingkey=error").Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML notes template: %w", err)
+	}
+
+	tmpl := &NotesTemplate{name: name, html: true, page: page}
+
+	return tmpl, tmpl.validate()
+}
+
+// IsHTML reports whether the template renders html_notes instead of notes
+func (t *NotesTemplate) IsHTML() bool {
+	return t.html
+}
+
+// validate renders the template with sample data so that broken templates fail at startup
+func (t *NotesTemplate) validate() error {
+	if _, err := t.render(sampleNotesData()); err != nil {
+		return fmt.Errorf("invalid notes template %q: %w", t.name, err)
+	}
+
+	return nil
+}
+
+// render executes the template and checks the result can be sent to Asana
+func (t *NotesTemplate) render(data *NotesData) (string, error) {
+	var buf bytes.Buffer
+
+	var err error
+	if t.html {
+		err = t.page.Execute(&buf, data)
+	} else {
+		err = t.text.Execute(&buf, data)
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to render notes template: %w", err)
+	}
+
+	notes := buf.String()
+
+	if length := utf8.RuneCountInString(notes); length > asanaMaxNotesLength {
+		return "", fmt.Errorf("rendered notes have %d characters, the limit is %d", length, asanaMaxNotesLength)
+	}
+
+	if t.html {
+		if err := checkHTMLNotes(notes); err != nil {
+			return "", err
+		}
+	}
+
+	return notes, nil
+}
+
+// checkHTMLNotes checks that rich text notes are a well-formed document with a <body> root element
+func checkHTMLNotes(notes string) error {
+	decoder := xml.NewDecoder(strings.NewReader(notes))
+	depth := 0
+
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("rendered HTML notes aren't valid XML: %w", err)
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			if depth == 0 && element.Name.Local != "body" {
+				return fmt.Errorf("rendered HTML notes must be wrapped in <body>, got <%s>", element.Name.Local)
+			}
+
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && strings.TrimSpace(string(element)) != "" {
+				return fmt.Errorf("rendered HTML notes must be wrapped in <body>, got text outside of it")
+			}
+		}
+	}
+
+	return nil
+}
+
+// newNotesData collects the data of a report for notes templates
+func newNotesData(summary *FeedbackSummary, history []ReportHistory, windowStart, windowEnd time.Time) *NotesData {
+	return &NotesData{
+		Summary:      summary,
+		Title:        formatTaskTitle(summary, windowEnd),
+		WindowStart:  windowStart.UTC(),
+		WindowEnd:    windowEnd.UTC(),
+		History:      history,
+		DefaultNotes: formatTaskNotes(summary, windowStart, windowEnd),
+	}
+}
+
+// reportHistory converts earlier report runs for notes templates
+func reportHistory(runs []db.ReportRun) []ReportHistory {
+	history := make([]ReportHistory, 0, len(runs))
+
+	for i := range runs {
+		summary := reportSummary(&runs[i])
+
+		history = append(history, ReportHistory{
+			ReportDate:      runs[i].ReportDate.Time,
+			PositiveCount:   summary.PositiveCount,
+			NegativeCount:   summary.NegativeCount,
+			Total:           summary.Total,
+			PositivePercent: summary.PositivePercent,
+			NegativePercent: summary.NegativePercent,
+			IsAnomaly:       runs[i].IsAnomaly,
+		})
+	}
+
+	return history
+}
+
+// RenderNotesPreview writes the notes of a sample report rendered with the template,
+// or with the built-in notes when the template is nil
+func RenderNotesPreview(w io.Writer, tmpl *NotesTemplate) error {
+	data := sampleNotesData()
+
+	notes := data.DefaultNotes
+	if tmpl != nil {
+		rendered, err := tmpl.render(data)
+		if err != nil {
+			return err
+		}
+
+		notes = rendered
+	}
+
+	if _, err := fmt.Fprintf(w, "%s\n\n%s\n", data.Title, notes); err != nil {
+		return fmt.Errorf("failed to write notes preview: %w", err)
+	}
+
+	return nil
+}
+
+// sampleNotesData creates a report using every field of the notes data, for validation and previews
+func sampleNotesData() *NotesData {
+	windowEnd := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+	windowStart := windowEnd.AddDate(0, 0, -1)
+
+	summary := calculateFeedbackSummary(&db.CountFeedbackBySentimentRow{PositiveCount: 120, NegativeCount: 48})
+	summary.Anomalies = []string{"Negative share 28.6% is above the 7-day baseline of 12.4% (z-score 3.4)"}
+	summary.NegativeSamples = []string{"The app crashes when I open the settings."}
+	summary.PositiveSamples = []string{"Love the new search, it's so fast!"}
+	summary.NegativeTerms = []KeyTerm{
+		{Term: "settings crash", Count: 12, Messages: 48, BaselineCount: 1, BaselineMessages: 300, Score: 41.2},
+	}
+	summary.Clusters = []MessageCluster{
+		{Size: 9, Negative: 9, Representative: "The app crashes when I open the settings."},
+	}
+	summary.SentimentCheck = &SentimentCheck{
+		Scored: 150,
+		Mismatches: []SentimentMismatch{
+			{ID: 42, Sentiment: db.SentimentTypePositive, Score: -0.8, Message: "Terrible, nothing works"},
+		},
+	}
+
+	history := make([]ReportHistory, 0, 7)
+	for i := 7; i >= 1; i-- {
+		history = append(history, ReportHistory{
+			ReportDate:      windowEnd.AddDate(0, 0, -i),
+			PositiveCount:   110,
+			NegativeCount:   15,
+			Total:           125,
+			PositivePercent: 88.0,
+			NegativePercent: 12.0,
+		})
+	}
+
+	summary.Trends = []Trend{
+		{Label: "Day over day", CompareDate: windowEnd.AddDate(0, 0, -1), Available: true,
+			PositiveDelta: 10, NegativeDelta: 33, TotalDelta: 43, PositivePercentDelta: -16.6, NegativePercentDelta: 16.6},
+		{Label: "Week over week", CompareDate: windowEnd.AddDate(0, 0, -7), Available: true,
+			PositiveDelta: 10, NegativeDelta: 33, TotalDelta: 43, PositivePercentDelta: -16.6, NegativePercentDelta: 16.6},
+	}
+
+	return newNotesData(summary, history, windowStart, windowEnd)
+}
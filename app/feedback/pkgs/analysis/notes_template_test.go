@@ -0,0 +1,262 @@
+package analysis
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTemplate writes a template file to a temporary directory and returns its path
+func writeTemplate(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write template: %v", err)
+	}
+
+	return path
+}
+
+func TestLoadNotesTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     bool
+		template string
+		wantErr  bool
+	}{
+		{
+			name:     "text template",
+			template: `{{.Title}}: {{.Summary.NegativeCount}} negative ({{percent .Summary.NegativePercent}})`,
+		},
+		{
+			name: "text template with history",
+			template: `{{range .History}}{{date .ReportDate}}: {{.Total}}
+{{end}}{{.DefaultNotes}}`,
+		},
+		{
+			name:     "syntax error",
+			template: `{{.Title`,
+			wantErr:  true,
+		},
+		{
+			name:     "unknown field",
+			template: `{{.Summary.Neutral}}`,
+			wantErr:  true,
+		},
+		{
+			name:     "unknown function",
+			template: `{{upper .Title}}`,
+			wantErr:  true,
+		},
+		{
+			name:     "HTML template",
+			html:     true,
+			template: `<body><strong>{{.Title}}</strong>{{range .Summary.NegativeSamples}}<li>{{.}}</li>{{end}}</body>`,
+		},
+		{
+			name:     "HTML template without body",
+			html:     true,
+			template: `<strong>{{.Title}}</strong>`,
+			wantErr:  true,
+		},
+		{
+			name:     "HTML template with text outside of body",
+			html:     true,
+			template: `{{.Title}}<body></body>`,
+			wantErr:  true,
+		},
+		{
+			name:     "HTML template with unclosed element",
+			html:     true,
+			template: `<body><ul><li>{{.Title}}</body>`,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeTemplate(t, "notes.tmpl", tt.template)
+
+			load := LoadNotesTemplate
+			if tt.html {
+				load = LoadHTMLNotesTemplate
+			}
+
+			tmpl, err := load(path)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Failed to load template: %v", err)
+			}
+
+			if tmpl.IsHTML() != tt.html {
+				t.Errorf("Expected IsHTML %v, got %v", tt.html, tmpl.IsHTML())
+			}
+		})
+	}
+
+	if _, err := LoadNotesTemplate(filepath.Join(t.TempDir(), "missing.tmpl")); err == nil {
+		t.Error("Expected error for missing file, got nil")
+	}
+}
+
+func TestNotesTemplate_HTMLEscaping(t *testing.T) {
+	tmpl, err := LoadHTMLNotesTemplate(writeTemplate(t, "notes.html",
+		`<body>{{range .Summary.NegativeSamples}}<li>{{.}}</li>{{end}}</body>`))
+	if err != nil {
+		t.Fatalf("Failed to load template: %v", err)
+	}
+
+	summary := testSummary(10, 5)
+	summary.NegativeSamples = []string{`<script>alert("x")</script> & more`}
+
+	notes, err := tmpl.render(newNotesData(summary, nil, time.Now().AddDate(0, 0, -1), time.Now()))
+	if err != nil {
+		t.Fatalf("Failed to render template: %v", err)
+	}
+
+	if strings.Contains(notes, "<script>") {
+		t.Errorf("Expected message to be escaped, got %q", notes)
+	}
+}
+
+func TestBuildTaskRequest_NotesTemplate(t *testing.T) {
+	windowStart := time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	t.Run("text template", func(t *testing.T) {
+		tmpl, err := LoadNotesTemplate(writeTemplate(t, "notes.txt",
+			`Negative: {{.Summary.NegativeCount}} on {{date .WindowEnd}}`))
+		if err != nil {
+			t.Fatalf("Failed to load template: %v", err)
+		}
+
+		client := newAsanaClient("test-token", "workspace-123", "project-456")
+		client.options.NotesTemplate = tmpl
+
+		request := client.buildTaskRequest(testSummary(10, 5), nil, windowStart, windowEnd)
+
+		if request.Data.Notes != "Negative: 5 on 2024-06-15" {
+			t.Errorf("Expected rendered notes, got %q", request.Data.Notes)
+		}
+
+		if request.Data.HTMLNotes != "" {
+			t.Errorf("Expected no HTML notes, got %q", request.Data.HTMLNotes)
+		}
+	})
+
+	t.Run("template with history", func(t *testing.T) {
+		tmpl, err := LoadNotesTemplate(writeTemplate(t, "notes.txt",
+			`{{range .History}}{{date .ReportDate}}: {{.Total}} {{end}}`))
+		if err != nil {
+			t.Fatalf("Failed to load template: %v", err)
+		}
+
+		client := newAsanaClient("test-token", "workspace-123", "project-456")
+		client.options.NotesTemplate = tmpl
+
+		history := reportHistory(testHistory([2]int32{30, 10}))
+		request := client.buildTaskRequest(testSummary(10, 5), history, windowStart, windowEnd)
+
+		if expected := history[0].ReportDate.Format("2006-01-02") + ": 40 "; request.Data.Notes != expected {
+			t.Errorf("Expected notes %q, got %q", expected, request.Data.Notes)
+		}
+	})
+
+	t.Run("HTML template", func(t *testing.T) {
+		tmpl, err := LoadHTMLNotesTemplate(writeTemplate(t, "notes.html",
+			`<body><strong>Negative: {{.Summary.NegativeCount}}</strong></body>`))
+		if err != nil {
+			t.Fatalf("Failed to load template: %v", err)
+		}
+
+		client := newAsanaClient("test-token", "workspace-123", "project-456")
+		client.options.NotesTemplate = tmpl
+
+		request := client.buildTaskRequest(testSummary(10, 5), nil, windowStart, windowEnd)
+
+		if request.Data.HTMLNotes != "<body><strong>Negative: 5</strong></body>" {
+			t.Errorf("Expected rendered HTML notes, got %q", request.Data.HTMLNotes)
+		}
+
+		// Asana rejects requests setting both notes and html_notes
+		if request.Data.Notes != "" {
+			t.Errorf("Expected no plain notes, got %q", request.Data.Notes)
+		}
+	})
+
+	t.Run("render failure falls back to built-in notes", func(t *testing.T) {
+		// Passes validation with the sample history but fails for a report without history
+		tmpl, err := LoadNotesTemplate(writeTemplate(t, "notes.txt", `{{(index .History 0).Total}}`))
+		if err != nil {
+			t.Fatalf("Failed to load template: %v", err)
+		}
+
+		client := newAsanaClient("test-token", "workspace-123", "project-456")
+		client.options.NotesTemplate = tmpl
+
+		summary := testSummary(10, 5)
+		request := client.buildTaskRequest(summary, nil, windowStart, windowEnd)
+
+		if expected := formatTaskNotes(summary, windowStart, windowEnd); request.Data.Notes != expected {
+			t.Errorf("Expected built-in notes %q, got %q", expected, request.Data.Notes)
+		}
+	})
+}
+
+func TestReportHistory(t *testing.T) {
+	history := reportHistory(testHistory([2]int32{30, 10}, [2]int32{0, 0}))
+
+	if len(history) != 2 {
+		t.Fatalf("Expected 2 history entries, got %d", len(history))
+	}
+
+	if history[0].Total != 40 || history[0].NegativePercent != 25 {
+		t.Errorf("Expected total 40 and 25%% negative, got %+v", history[0])
+	}
+
+	// The order of the report runs is kept
+	if expected := anomalyReportDate.AddDate(0, 0, -1); !history[0].ReportDate.Equal(expected) {
+		t.Errorf("Expected report date %v, got %v", expected, history[0].ReportDate)
+	}
+}
+
+func TestRenderNotesPreview(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := RenderNotesPreview(&buf, nil); err != nil {
+		t.Fatalf("RenderNotesPreview failed: %v", err)
+	}
+
+	// The built-in notes are the default
+	for _, expected := range []string{"Daily Feedback Summary", "Results:", "Negative feedback samples:"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected preview to contain %q, got %q", expected, buf.String())
+		}
+	}
+
+	tmpl, err := LoadNotesTemplate(writeTemplate(t, "notes.txt", `custom {{.Summary.Total}}`))
+	if err != nil {
+		t.Fatalf("Failed to load template: %v", err)
+	}
+
+	buf.Reset()
+
+	if err := RenderNotesPreview(&buf, tmpl); err != nil {
+		t.Fatalf("RenderNotesPreview failed: %v", err)
+	}
+
+	if !strings.Contains(buf.String(), "custom 168") {
+		t.Errorf("Expected preview of the template, got %q", buf.String())
+	}
+}
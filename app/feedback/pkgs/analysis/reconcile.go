@@ -0,0 +1,310 @@
+package analysis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ReconcileIssueKind describes an inconsistency between report_runs and Asana
+type ReconcileIssueKind string
+
+const (
+	// ReconcileIssueMissingTask means the report has feedback but no Asana task GID
+	ReconcileIssueMissingTask ReconcileIssueKind = "missing_task"
+	// ReconcileIssueTaskNotFound means the referenced Asana task doesn't exist (e.g., it was deleted)
+	ReconcileIssueTaskNotFound ReconcileIssueKind = "task_not_found"
+	// ReconcileIssueTaskMismatch means the Asana task doesn't match the report's date or counts.
+	// Only the name is fixed, notes with outdated counts stay unresolved.
+	ReconcileIssueTaskMismatch ReconcileIssueKind = "task_mismatch"
+)
+
+// ReconcileOptions holds the options of a reconcile run
+type ReconcileOptions struct {
+	// Since is the first report date to verify
+	Since time.Time
+	// Fix recreates missing tasks and renames mismatched ones
+	Fix bool
+}
+
+// ReconcileIssue is a single inconsistency found by Reconcile
+type ReconcileIssue struct {
+	ReportDate time.Time
+	Kind       ReconcileIssueKind
+	TaskGID    string
+	Detail     string
+	// Fixed is set when the issue was resolved, NewTaskGID when a task was recreated
+	Fixed      bool
+	NewTaskGID string
+
+	// Task name expected by the report when the name differs, empty otherwise
+	expectedName string
+	// Set when the notes don't contain the report's counts
+	notesMismatch bool
+}
+
+// ReconcileResult summarizes a reconcile run
+type ReconcileResult struct {
+	Checked int
+	Issues  []ReconcileIssue
+}
+
+// Unresolved returns the number of issues that weren't fixed
+func (r *ReconcileResult) Unresolved() int {
+	count := 0
+
+	for _, issue := range r.Issues {
+		if !issue.Fixed {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Reconcile verifies that the Asana tasks referenced by report_runs exist and match the reports.
+// With opts.Fix set, missing tasks are recreated and mismatched tasks are renamed. Fixing takes the
+// report lock, so an analysis run can't complete a report at the same time.
+func (a *Aggregator) Reconcile(ctx context.Context, opts ReconcileOptions) (*ReconcileResult, error) {
+	if a.asanaToken == "" || a.asanaWorkspace == "" {
+		return nil, fmt.Errorf("asana credentials not provided")
+	}
+
+	if opts.Fix {
+		unlock, err := a.dbReportLock(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+
+	slog.Info("Reconciling report runs with Asana tasks...",
+		"since", opts.Since.Format("2006-01-02"),
+		"fix", opts.Fix)
+
+	reports, err := a.queries.ListReportRunsSince(ctx, pgtype.Date{Time: opts.Since, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list report runs: %w", err)
+	}
+
+	client := a.newAsanaClient()
+	result := &ReconcileResult{}
+
+	for i := range reports {
+		report := &reports[i]
+
+		// Pending reports are completed by the analysis job itself
+		if report.Status == db.ReportStatusPending {
+			continue
+		}
+
+		result.Checked++
+
+		issue, err := client.checkReport(ctx, report)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check report for %s: %w",
+				report.ReportDate.Time.Format("2006-01-02"), err)
+		}
+
+		if issue == nil {
+			continue
+		}
+
+		slog.Warn("Report run doesn't match Asana",
+			"report_date", issue.ReportDate.Format("2006-01-02"),
+			"issue", issue.Kind,
+			"task_gid", issue.TaskGID,
+			"detail", issue.Detail)
+
+		if opts.Fix {
+			if err := a.fixReport(ctx, client, report, issue); err != nil {
+				return nil, fmt.Errorf("failed to fix report for %s: %w",
+					report.ReportDate.Time.Format("2006-01-02"), err)
+			}
+		}
+
+		result.Issues = append(result.Issues, *issue)
+	}
+
+	slog.Info("Reconciliation finished",
+		"checked", result.Checked,
+		"issues", len(result.Issues),
+		"unresolved", result.Unresolved())
+
+	return result, nil
+}
+
+// fixReport resolves an issue and stores the GID of a recreated task
+func (a *Aggregator) fixReport(
+	ctx context.Context,
+	client *AsanaClient,
+	report *db.ReportRun,
+	issue *ReconcileIssue,
+) error {
+	if err := client.fixReport(ctx, report, issue); err != nil {
+		return err
+	}
+
+	if issue.NewTaskGID != "" {
+		if err := a.dbReportComplete(ctx, report.ReportDate, issue.NewTaskGID); err != nil {
+			return fmt.Errorf("failed to store recreated task %q: %w", issue.NewTaskGID, err)
+		}
+	}
+
+	slog.Info("Report run fixed",
+		"report_date", issue.ReportDate.Format("2006-01-02"),
+		"issue", issue.Kind,
+		"new_task_gid", issue.NewTaskGID)
+
+	return nil
+}
+
+// checkReport compares a report run with its Asana task and returns nil when they match
+func (c *AsanaClient) checkReport(ctx context.Context, report *db.ReportRun) (*ReconcileIssue, error) {
+	summary := reportSummary(report)
+	windowEnd := report.WindowEnd.Time
+	taskGID := strings.TrimSpace(report.AsanaTaskGid.String)
+
+	if taskGID == "" {
+		// No task is created when there is no feedback
+		if summary.Total == 0 {
+			return nil, nil
+		}
+
+		return &ReconcileIssue{
+			ReportDate: report.ReportDate.Time,
+			Kind:       ReconcileIssueMissingTask,
+			Detail:     fmt.Sprintf("report has %d feedback but no Asana task", summary.Total),
+		}, nil
+	}
+
+	task, err := c.getTask(ctx, taskGID)
+	if err != nil {
+		var asanaErr *AsanaError
+		if errors.As(err, &asanaErr) && asanaErr.IsNotFound() {
+			return &ReconcileIssue{
+				ReportDate: report.ReportDate.Time,
+				Kind:       ReconcileIssueTaskNotFound,
+				TaskGID:    taskGID,
+				Detail:     "Asana task doesn't exist",
+			}, nil
+		}
+
+		return nil, err
+	}
+
+	issue := &ReconcileIssue{
+		ReportDate: report.ReportDate.Time,
+		Kind:       ReconcileIssueTaskMismatch,
+		TaskGID:    taskGID,
+	}
+
+	var details []string
+
+	if expected := formatTaskTitle(summary, windowEnd); task.Name != expected {
+		issue.expectedName = expected
+		details = append(details, fmt.Sprintf("name is %q, expected %q", task.Name, expected))
+	}
+
+	// Custom templates decide what the notes contain, only the task name can be compared then
+	if c.options.NotesTemplate == nil {
+		if notesDetails := notesMismatch(task.Notes, summary); len(notesDetails) > 0 {
+			issue.notesMismatch = true
+			details = append(details, notesDetails...)
+		}
+	}
+
+	if len(details) == 0 {
+		return nil, nil
+	}
+
+	issue.Detail = strings.Join(details, "; ")
+
+	return issue, nil
+}
+
+// fixReport recreates a missing task or renames a mismatched one. A report without a task GID reuses the
+// task of its name if the project has one.
+// A task whose notes don't match keeps its notes and the issue stays unresolved.
+func (c *AsanaClient) fixReport(ctx context.Context, report *db.ReportRun, issue *ReconcileIssue) error {
+	summary := reportSummary(report)
+	windowStart := report.WindowStart.Time
+	windowEnd := report.WindowEnd.Time
+
+	switch issue.Kind {
+	case ReconcileIssueMissingTask:
+		// The task usually exists, the run failed before storing its GID
+		taskGID, err := c.findOrCreateTask(ctx, summary, nil, windowStart, windowEnd)
+		if err != nil {
+			return err
+		}
+
+		issue.NewTaskGID = taskGID
+	case ReconcileIssueTaskNotFound:
+		// The stored task was deleted in Asana, it is recreated
+		taskGID, err := c.createTask(ctx, summary, nil, windowStart, windowEnd)
+		if err != nil {
+			return err
+		}
+
+		issue.NewTaskGID = taskGID
+	case ReconcileIssueTaskMismatch:
+		if issue.expectedName != "" {
+			if err := c.renameTask(ctx, issue.TaskGID, issue.expectedName); err != nil {
+				return err
+			}
+		}
+
+		if issue.notesMismatch {
+			slog.Warn("Asana task notes don't match the report, they have to be corrected in Asana",
+				"report_date", issue.ReportDate.Format("2006-01-02"),
+				"task_gid", issue.TaskGID)
+
+			return nil
+		}
+	default:
+		return fmt.Errorf("unknown issue kind %q", issue.Kind)
+	}
+
+	issue.Fixed = true
+
+	return nil
+}
+
+// notesMismatch describes the counts of the report missing from the task notes.
+// Only the counts are compared because the rest of the notes may change over time.
+func notesMismatch(notes string, summary *FeedbackSummary) []string {
+	var details []string
+
+	for _, expected := range []string{
+		fmt.Sprintf("Positive: %d ", summary.PositiveCount),
+		fmt.Sprintf("Negative: %d ", summary.NegativeCount),
+		fmt.Sprintf("Total: %d", summary.Total),
+	} {
+		if !strings.Contains(notes, expected) {
+			details = append(details, fmt.Sprintf("notes don't contain %q", strings.TrimSpace(expected)))
+		}
+	}
+
+	return details
+}
+
+// reportSummary calculates the feedback summary of a stored report run
+func reportSummary(report *db.ReportRun) *FeedbackSummary {
+	summary := calculateFeedbackSummary(&db.CountFeedbackBySentimentRow{
+		PositiveCount: int64(report.PositiveCount),
+		NegativeCount: int64(report.NegativeCount),
+	})
+
+	if report.IsAnomaly && report.AnomalyDetails.Valid {
+		summary.Anomalies = strings.Split(report.AnomalyDetails.String, "\n")
+	}
+
+	return summary
+}
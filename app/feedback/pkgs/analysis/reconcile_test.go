@@ -0,0 +1,368 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// fakeAsana is an in-memory Asana API supporting task create, get, list and update
+type fakeAsana struct {
+	mu      sync.Mutex
+	tasks   map[string]AsanaTaskResponseData
+	nextGID int
+	// creates counts the create requests
+	creates int
+	// failCreates rejects the next create requests with 500 without creating the task
+	failCreates int
+	// loseCreates drops the connection after creating the task for the next create requests
+	loseCreates int
+}
+
+func newFakeAsana() *fakeAsana {
+	return &fakeAsana{tasks: map[string]AsanaTaskResponseData{}, nextGID: 1000}
+}
+
+func (f *fakeAsana) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	writeJSON := func(status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	notFound := map[string]any{"errors": []AsanaErrorDetail{{Message: "task: Unknown object"}}}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/tasks":
+		var request AsanaTaskRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeJSON(http.StatusBadRequest, map[string]any{})
+			return
+		}
+
+		f.creates++
+		if f.failCreates > 0 {
+			f.failCreates--
+			writeJSON(http.StatusInternalServerError, map[string]any{})
+			return
+		}
+
+		f.nextGID++
+		task := AsanaTaskResponseData{
+			GID:   fmt.Sprintf("%d", f.nextGID),
+			Name:  request.Data.Name,
+			Notes: request.Data.Notes,
+		}
+		f.tasks[task.GID] = task
+
+		if f.loseCreates > 0 {
+			f.loseCreates--
+			if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+				_ = conn.Close()
+			}
+			return
+		}
+
+		writeJSON(http.StatusCreated, AsanaTaskResponse{Data: task})
+	case r.Method == http.MethodGet && r.URL.Path == "/tasks":
+		// Tasks are listed in GID order, the offset is the index of the first task of the page
+		gids := slices.Sorted(maps.Keys(f.tasks))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		end := min(offset+limit, len(gids))
+
+		page := AsanaTaskListResponse{}
+		for _, gid := range gids[offset:end] {
+			page.Data = append(page.Data, AsanaTaskResponseData{GID: gid, Name: f.tasks[gid].Name})
+		}
+
+		if end < len(gids) {
+			page.NextPage = &AsanaNextPage{Offset: strconv.Itoa(end)}
+		}
+
+		writeJSON(http.StatusOK, page)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/tasks/"):
+		task, ok := f.tasks[strings.TrimPrefix(r.URL.Path, "/tasks/")]
+		if !ok {
+			writeJSON(http.StatusNotFound, notFound)
+			return
+		}
+
+		writeJSON(http.StatusOK, AsanaTaskResponse{Data: task})
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/tasks/"):
+		gid := strings.TrimPrefix(r.URL.Path, "/tasks/")
+
+		task, ok := f.tasks[gid]
+		if !ok {
+			writeJSON(http.StatusNotFound, notFound)
+			return
+		}
+
+		// Only the fields present in the request are changed
+		var request struct {
+			Data map[string]*string `json:"data"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			writeJSON(http.StatusBadRequest, map[string]any{})
+			return
+		}
+
+		if name := request.Data["name"]; name != nil {
+			task.Name = *name
+		}
+
+		if notes := request.Data["notes"]; notes != nil {
+			task.Notes = *notes
+		}
+		f.tasks[gid] = task
+		writeJSON(http.StatusOK, AsanaTaskResponse{Data: task})
+	default:
+		writeJSON(http.StatusNotFound, notFound)
+	}
+}
+
+// testReportRun creates a completed report run for 2024-06-15
+func testReportRun(positive, negative int32, taskGID string) *db.ReportRun {
+	windowStart := time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	return &db.ReportRun{
+		ReportDate:    pgtype.Date{Time: windowEnd, Valid: true},
+		WindowStart:   pgtype.Timestamptz{Time: windowStart, Valid: true},
+		WindowEnd:     pgtype.Timestamptz{Time: windowEnd, Valid: true},
+		PositiveCount: positive,
+		NegativeCount: negative,
+		AsanaTaskGid:  pgtype.Text{String: taskGID, Valid: taskGID != ""},
+		Status:        db.ReportStatusCompleted,
+	}
+}
+
+func TestAsanaClient_CheckReport(t *testing.T) {
+	fake := newFakeAsana()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := newTestAsanaClient(server)
+
+	// Create a task matching the report
+	matching := testReportRun(75, 25, "")
+	matchingGID, err := client.createTask(
+		context.Background(),
+		reportSummary(matching),
+		nil,
+		matching.WindowStart.Time,
+		matching.WindowEnd.Time,
+	)
+	if err != nil {
+		t.Fatalf("createTask failed: %v", err)
+	}
+
+	// Create a task with outdated counts
+	fake.tasks["2000"] = AsanaTaskResponseData{
+		GID:   "2000",
+		Name:  "Daily Feedback Summary - 2024-06-15",
+		Notes: "• Positive: 70 (70.0%)\n• Negative: 30 (30.0%)\n• Total: 100",
+	}
+
+	tests := []struct {
+		name         string
+		report       *db.ReportRun
+		expectedKind ReconcileIssueKind
+	}{
+		{
+			name:   "matching task",
+			report: testReportRun(75, 25, matchingGID),
+		},
+		{
+			name:   "no task and no feedback",
+			report: testReportRun(0, 0, ""),
+		},
+		{
+			name:         "no task with feedback",
+			report:       testReportRun(75, 25, ""),
+			expectedKind: ReconcileIssueMissingTask,
+		},
+		{
+			name:         "deleted task",
+			report:       testReportRun(75, 25, "9999"),
+			expectedKind: ReconcileIssueTaskNotFound,
+		},
+		{
+			name:         "mismatched counts",
+			report:       testReportRun(75, 25, "2000"),
+			expectedKind: ReconcileIssueTaskMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issue, err := client.checkReport(context.Background(), tt.report)
+			if err != nil {
+				t.Fatalf("checkReport failed: %v", err)
+			}
+
+			if tt.expectedKind == "" {
+				if issue != nil {
+					t.Errorf("Expected no issue, got %+v", issue)
+				}
+
+				return
+			}
+
+			if issue == nil {
+				t.Fatalf("Expected issue %q, got nil", tt.expectedKind)
+			}
+
+			if issue.Kind != tt.expectedKind {
+				t.Errorf("Expected issue %q, got %q", tt.expectedKind, issue.Kind)
+			}
+		})
+	}
+}
+
+func TestAsanaClient_FixReport(t *testing.T) {
+	fake := newFakeAsana()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	client := newTestAsanaClient(server)
+	ctx := context.Background()
+
+	t.Run("reuses existing task of report without task GID", func(t *testing.T) {
+		fake := newFakeAsana()
+		fake.tasks["5000"] = AsanaTaskResponseData{GID: "5000", Name: "Daily Feedback Summary - 2024-06-15"}
+
+		server := httptest.NewServer(fake)
+		defer server.Close()
+
+		client := newTestAsanaClient(server)
+		report := testReportRun(75, 25, "")
+
+		issue, err := client.checkReport(ctx, report)
+		if err != nil || issue == nil || issue.Kind != ReconcileIssueMissingTask {
+			t.Fatalf("Expected missing task issue, got %v (err: %v)", issue, err)
+		}
+
+		if err := client.fixReport(ctx, report, issue); err != nil {
+			t.Fatalf("fixReport failed: %v", err)
+		}
+
+		if !issue.Fixed || issue.NewTaskGID != "5000" {
+			t.Errorf("Expected existing task '5000' to be stored, got %+v", issue)
+		}
+
+		if fake.creates != 0 {
+			t.Errorf("Expected no create requests, got %d", fake.creates)
+		}
+	})
+
+	t.Run("recreates deleted task", func(t *testing.T) {
+		report := testReportRun(75, 25, "9999")
+
+		issue, err := client.checkReport(ctx, report)
+		if err != nil || issue == nil {
+			t.Fatalf("Expected issue, got %v (err: %v)", issue, err)
+		}
+
+		if err := client.fixReport(ctx, report, issue); err != nil {
+			t.Fatalf("fixReport failed: %v", err)
+		}
+
+		if !issue.Fixed || issue.NewTaskGID == "" {
+			t.Fatalf("Expected fixed issue with new task GID, got %+v", issue)
+		}
+
+		// The recreated task must pass verification
+		report.AsanaTaskGid = pgtype.Text{String: issue.NewTaskGID, Valid: true}
+		if issue, err := client.checkReport(ctx, report); err != nil || issue != nil {
+			t.Errorf("Expected recreated task to match, got %+v (err: %v)", issue, err)
+		}
+	})
+
+	t.Run("renames mismatched task", func(t *testing.T) {
+		notes := "• Positive: 10 (66.7%)\n• Negative: 5 (33.3%)\n• Total: 15\n\nNegative feedback samples:\n• Slow"
+		fake.tasks["3000"] = AsanaTaskResponseData{GID: "3000", Name: "Renamed", Notes: notes}
+		report := testReportRun(10, 5, "3000")
+
+		issue, err := client.checkReport(ctx, report)
+		if err != nil || issue == nil {
+			t.Fatalf("Expected issue, got %v (err: %v)", issue, err)
+		}
+
+		if err := client.fixReport(ctx, report, issue); err != nil {
+			t.Fatalf("fixReport failed: %v", err)
+		}
+
+		if issue.NewTaskGID != "" {
+			t.Errorf("Expected task to be updated in place, got new GID %q", issue.NewTaskGID)
+		}
+
+		if !issue.Fixed {
+			t.Errorf("Expected fixed issue, got %+v", issue)
+		}
+
+		task := fake.tasks["3000"]
+		if task.Name != "Daily Feedback Summary - 2024-06-15" {
+			t.Errorf("Expected task name to be updated, got %q", task.Name)
+		}
+
+		if task.Notes != notes {
+			t.Errorf("Expected notes to be kept, got %q", task.Notes)
+		}
+
+		if issue, err := client.checkReport(ctx, report); err != nil || issue != nil {
+			t.Errorf("Expected updated task to match, got %+v (err: %v)", issue, err)
+		}
+	})
+
+	t.Run("keeps notes with outdated counts", func(t *testing.T) {
+		notes := "• Positive: 70 (70.0%)\n• Negative: 30 (30.0%)\n• Total: 100"
+		fake.tasks["4000"] = AsanaTaskResponseData{GID: "4000", Name: "Daily Feedback Summary - 2024-06-15", Notes: notes}
+		report := testReportRun(75, 25, "4000")
+
+		issue, err := client.checkReport(ctx, report)
+		if err != nil || issue == nil {
+			t.Fatalf("Expected issue, got %v (err: %v)", issue, err)
+		}
+
+		if err := client.fixReport(ctx, report, issue); err != nil {
+			t.Fatalf("fixReport failed: %v", err)
+		}
+
+		if issue.Fixed {
+			t.Errorf("Expected issue to stay unresolved, got %+v", issue)
+		}
+
+		if task := fake.tasks["4000"]; task.Notes != notes {
+			t.Errorf("Expected notes to be kept, got %q", task.Notes)
+		}
+	})
+}
+
+func TestReconcileResult_Unresolved(t *testing.T) {
+	result := &ReconcileResult{
+		Issues: []ReconcileIssue{
+			{Kind: ReconcileIssueMissingTask, Fixed: true},
+			{Kind: ReconcileIssueTaskNotFound},
+			{Kind: ReconcileIssueTaskMismatch},
+		},
+	}
+
+	if got := result.Unresolved(); got != 2 {
+		t.Errorf("Expected 2 unresolved issues, got %d", got)
+	}
+}
@@ -0,0 +1,102 @@
+package analysis
+
+import (
+	"cmp"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+)
+
+const (
+	// DefaultSampleSize is the default number of sampled messages per sentiment
+	DefaultSampleSize = 3
+
+	// sampleMaxLength is the maximum number of characters of a sampled message
+	sampleMaxLength = 280
+	// maxWindowMessages bounds the number of messages loaded for text analysis
+	maxWindowMessages = 10000
+)
+
+// FeedbackMessage is a feedback message loaded for text analysis
+type FeedbackMessage struct {
+	ID        int32
+	Sentiment db.SentimentType
+	Message   string
+}
+
+// sampleMessages picks up to n messages of the given sentiment.
+// Messages are ranked by a hash of the seed and their ID, so the sample is random
+// but stays the same for a given seed (report date) across runs.
+func sampleMessages(messages []FeedbackMessage, sentiment db.SentimentType, n int, seed string) []string {
+	if n <= 0 {
+		return nil
+	}
+
+	type ranked struct {
+		rank    uint64
+		message FeedbackMessage
+	}
+
+	var candidates []ranked
+
+	for _, message := range messages {
+		if message.Sentiment != sentiment || strings.TrimSpace(message.Message) == "" {
+			continue
+		}
+
+		candidates = append(candidates, ranked{rank: sampleRank(seed, message.ID), message: message})
+	}
+
+	slices.SortFunc(candidates, func(a, b ranked) int {
+		return cmp.Or(cmp.Compare(a.rank, b.rank), cmp.Compare(a.message.ID, b.message.ID))
+	})
+
+	samples := make([]string, 0, min(n, len(candidates)))
+	for _, candidate := range candidates[:min(n, len(candidates))] {
+		samples = append(samples, truncateMessage(candidate.message.Message, sampleMaxLength))
+	}
+
+	return samples
+}
+
+// sampleRank returns a deterministic pseudo-random rank of a message
+func sampleRank(seed string, id int32) uint64 {
+	hash := fnv.New64a()
+	_, _ = fmt.Fprintf(hash, "%s:%d", seed, id)
+
+	return hash.Sum64()
+}
+
+// truncateMessage collapses whitespace and shortens the message to at most maxLength characters
+func truncateMessage(message string, maxLength int) string {
+	message = strings.Join(strings.Fields(message), " ")
+
+	if utf8.RuneCountInString(message) <= maxLength {
+		return message
+	}
+
+	runes := []rune(message)
+
+	return strings.TrimSpace(string(runes[:maxLength-1])) + "…"
+}
+
+// formatSamples creates a section of the Asana task notes listing sampled messages
+func formatSamples(title string, samples []string) string {
+	if len(samples) == 0 {
+		return ""
+	}
+
+	var section strings.Builder
+
+	section.WriteString("\n\n" + title + ":")
+
+	for _, sample := range samples {
+		fmt.Fprintf(&section, "\n• \"%s\"", sample)
+	}
+
+	return section.String()
+}
@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+)
+
+// testMessages creates n messages of each sentiment
+func testMessages(n int) []FeedbackMessage {
+	messages := make([]FeedbackMessage, 0, 2*n)
+
+	for i := range n {
+		messages = append(messages,
+			FeedbackMessage{ID: int32(2 * i), Sentiment: db.SentimentTypePositive, Message: fmt.Sprintf("great %d", i)},
+			FeedbackMessage{ID: int32(2*i + 1), Sentiment: db.SentimentTypeNegative, Message: fmt.Sprintf("broken %d", i)},
+		)
+	}
+
+	return messages
+}
+
+func TestSampleMessages(t *testing.T) {
+	messages := testMessages(50)
+
+	t.Run("picks n messages of the sentiment", func(t *testing.T) {
+		samples := sampleMessages(messages, db.SentimentTypeNegative, 3, "2024-06-15")
+		if len(samples) != 3 {
+			t.Fatalf("Expected 3 samples, got %d", len(samples))
+		}
+
+		for _, sample := range samples {
+			if !strings.HasPrefix(sample, "broken ") {
+				t.Errorf("Expected negative message, got %q", sample)
+			}
+		}
+	})
+
+	t.Run("deterministic for a seed", func(t *testing.T) {
+		first := sampleMessages(messages, db.SentimentTypePositive, 5, "2024-06-15")
+
+		// The order of loaded messages must not matter
+		reversed := slices.Clone(messages)
+		slices.Reverse(reversed)
+
+		if second := sampleMessages(reversed, db.SentimentTypePositive, 5, "2024-06-15"); !slices.Equal(first, second) {
+			t.Errorf("Expected the same samples, got %v and %v", first, second)
+		}
+
+		if other := sampleMessages(messages, db.SentimentTypePositive, 5, "2024-06-16"); slices.Equal(first, other) {
+			t.Errorf("Expected different samples for another day, got %v", other)
+		}
+	})
+
+	t.Run("fewer messages than n", func(t *testing.T) {
+		if samples := sampleMessages(testMessages(2), db.SentimentTypeNegative, 5, "seed"); len(samples) != 2 {
+			t.Errorf("Expected 2 samples, got %v", samples)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		if samples := sampleMessages(messages, db.SentimentTypeNegative, 0, "seed"); samples != nil {
+			t.Errorf("Expected no samples, got %v", samples)
+		}
+	})
+}
+
+func TestTruncateMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{name: "short", message: "It works", expected: "It works"},
+		{name: "collapses whitespace", message: "  It\n\nworks\t ", expected: "It works"},
+		{name: "truncated", message: "abcdefghijkl", expected: "abcdefghi…"},
+		{name: "multibyte", message: "ééééééééééééé", expected: "ééééééééé…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := truncateMessage(tt.message, 10); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatTaskNotes_Samples(t *testing.T) {
+	windowEnd := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+	windowStart := windowEnd.AddDate(0, 0, -1)
+
+	summary := testSummary(1, 1)
+	summary.NegativeSamples = []string{"The app crashes"}
+	summary.PositiveSamples = []string{"Love it"}
+
+	notes := formatTaskNotes(summary, windowStart, windowEnd)
+
+	expected := "• Total: 2\n\nNegative feedback samples:\n• \"The app crashes\"" +
+		"\n\nPositive feedback samples:\n• \"Love it\"\n\nThis report was automatically generated"
+	if !strings.Contains(notes, expected) {
+		t.Errorf("Expected notes to contain %q, got %s", expected, notes)
+	}
+
+	t.Run("respects size limit", func(t *testing.T) {
+		summary.NegativeSamples = []string{strings.Repeat("x", asanaMaxNotesLength)}
+
+		notes := formatTaskNotes(summary, windowStart, windowEnd)
+
+		if length := utf8.RuneCountInString(notes); length > asanaMaxNotesLength {
+			t.Errorf("Expected notes of at most %d characters, got %d", asanaMaxNotesLength, length)
+		}
+
+		// Sections that still fit are kept
+		if !strings.Contains(notes, "Love it") {
+			t.Errorf("Expected positive samples to be kept, got %s", notes)
+		}
+	})
+}
@@ -0,0 +1,213 @@
+package analysis
+
+import (
+	"bufio"
+	"cmp"
+	_ "embed"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+)
+
+const (
+	// sentimentNegationWindow is the number of words after a negation whose score is flipped
+	sentimentNegationWindow = 3
+	// sentimentBoost multiplies the score of a word following an intensifier
+	sentimentBoost = 1.5
+	// sentimentNormalization controls how fast the normalized score approaches ±1
+	sentimentNormalization = 15
+	// sentimentMismatchThreshold is the normalized score opposite to the rating that counts as mismatch
+	sentimentMismatchThreshold = 0.25
+	// maxMismatchesListed is the maximum number of mismatched messages listed in the report
+	maxMismatchesListed = 5
+)
+
+//go:embed data/sentiment_lexicon_en.txt
+var sentimentLexiconFile string
+
+// sentimentLexicon maps words to their sentiment score
+var sentimentLexicon = loadLexicon(sentimentLexiconFile)
+
+// negations flip the sentiment of the following words
+var negations = map[string]struct{}{
+	"not": {}, "no": {}, "never": {}, "without": {}, "hardly": {}, "nothing": {}, "nobody": {}, "neither": {},
+	"nor": {}, "cannot": {}, "don't": {}, "dont": {}, "doesn't": {}, "doesnt": {}, "didn't": {}, "didnt": {},
+	"isn't": {}, "isnt": {}, "wasn't": {}, "wasnt": {}, "aren't": {}, "arent": {}, "weren't": {}, "werent": {},
+	"can't": {}, "cant": {}, "couldn't": {}, "couldnt": {}, "won't": {}, "wont": {}, "wouldn't": {},
+	"wouldnt": {}, "shouldn't": {}, "shouldnt": {},
+}
+
+// intensifiers strengthen the sentiment of the following word
+var intensifiers = map[string]struct{}{
+	"very": {}, "really": {}, "extremely": {}, "super": {}, "so": {}, "too": {}, "totally": {},
+	"absolutely": {}, "completely": {}, "incredibly": {},
+}
+
+// loadLexicon parses a bundled lexicon of "<word> <score>" lines, skipping empty lines and # comments
+func loadLexicon(content string) map[string]float64 {
+	lexicon := map[string]float64{}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			panic(fmt.Sprintf("invalid sentiment lexicon line %q", line))
+		}
+
+		score, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			panic(fmt.Sprintf("invalid sentiment lexicon score in line %q: %v", line, err))
+		}
+
+		lexicon[strings.ToLower(fields[0])] = score
+	}
+
+	return lexicon
+}
+
+// scoreText returns the lexicon-based sentiment of a message normalized to [-1, 1].
+// A negation flips the score of the next few words within the same clause,
+// an intensifier boosts the score of the next word.
+func scoreText(message string) float64 {
+	var sum float64
+
+	for _, clause := range splitClauses(message) {
+		negated := 0
+		boosted := false
+
+		for _, token := range tokenize(clause) {
+			if _, ok := negations[token]; ok {
+				negated = sentimentNegationWindow
+
+				continue
+			}
+
+			if _, ok := intensifiers[token]; ok {
+				boosted = true
+
+				continue
+			}
+
+			score, ok := sentimentLexicon[token]
+
+			if ok && boosted {
+				score *= sentimentBoost
+			}
+
+			if ok && negated > 0 {
+				score = -score
+			}
+
+			sum += score
+			boosted = false
+
+			if negated > 0 {
+				negated--
+			}
+		}
+	}
+
+	if sum == 0 {
+		return 0
+	}
+
+	return sum / math.Sqrt(sum*sum+sentimentNormalization)
+}
+
+// SentimentMismatch is a message whose text reads opposite to its rating
+type SentimentMismatch struct {
+	ID        int32
+	Sentiment db.SentimentType
+	Score     float64
+	Message   string
+}
+
+// SentimentCheck compares the rating of messages with the sentiment of their text
+type SentimentCheck struct {
+	// Scored is the number of messages with text
+	Scored int
+	// Mismatches are ordered by the strength of the opposite sentiment
+	Mismatches []SentimentMismatch
+	// Scores holds the text score of each message by ID
+	Scores map[int32]float64
+}
+
+// MismatchRate returns the fraction of scored messages whose text contradicts the rating
+func (c *SentimentCheck) MismatchRate() float64 {
+	if c == nil || c.Scored == 0 {
+		return 0
+	}
+
+	return float64(len(c.Mismatches)) / float64(c.Scored)
+}
+
+// checkSentiment scores the text of each message and finds messages that contradict their rating
+func checkSentiment(messages []FeedbackMessage) *SentimentCheck {
+	check := &SentimentCheck{Scores: make(map[int32]float64, len(messages))}
+
+	for _, message := range messages {
+		if strings.TrimSpace(message.Message) == "" {
+			continue
+		}
+
+		score := scoreText(message.Message)
+
+		check.Scored++
+		check.Scores[message.ID] = score
+
+		if isSentimentMismatch(message.Sentiment, score) {
+			check.Mismatches = append(check.Mismatches, SentimentMismatch{
+				ID:        message.ID,
+				Sentiment: message.Sentiment,
+				Score:     score,
+				Message:   message.Message,
+			})
+		}
+	}
+
+	slices.SortFunc(check.Mismatches, func(a, b SentimentMismatch) int {
+		return cmp.Or(cmp.Compare(math.Abs(b.Score), math.Abs(a.Score)), cmp.Compare(a.ID, b.ID))
+	})
+
+	return check
+}
+
+// isSentimentMismatch reports whether the text score clearly contradicts the rating
+func isSentimentMismatch(sentiment db.SentimentType, score float64) bool {
+	switch sentiment {
+	case db.SentimentTypePositive:
+		return score <= -sentimentMismatchThreshold
+	case db.SentimentTypeNegative:
+		return score >= sentimentMismatchThreshold
+	default:
+		return false
+	}
+}
+
+// formatSentimentCheck creates a section of the Asana task notes listing mismatched messages
+func formatSentimentCheck(check *SentimentCheck) string {
+	if check == nil || len(check.Mismatches) == 0 {
+		return ""
+	}
+
+	var section strings.Builder
+
+	fmt.Fprintf(&section, "\n\nRating doesn't match the text: %d of %d messages (%.1f%%)",
+		len(check.Mismatches), check.Scored, check.MismatchRate()*100)
+
+	for _, mismatch := range check.Mismatches[:min(maxMismatchesListed, len(check.Mismatches))] {
+		fmt.Fprintf(&section, "\n• Rated %s: \"%s\"",
+			mismatch.Sentiment, truncateMessage(mismatch.Message, sampleMaxLength))
+	}
+
+	return section.String()
+}
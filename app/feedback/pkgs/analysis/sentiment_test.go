@@ -0,0 +1,121 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+)
+
+func TestLoadLexicon(t *testing.T) {
+	lexicon := loadLexicon("# comment\n\nGreat 2\nawful -3\n")
+
+	if len(lexicon) != 2 || lexicon["great"] != 2 || lexicon["awful"] != -3 {
+		t.Errorf("Expected 2 parsed words, got %v", lexicon)
+	}
+
+	if len(sentimentLexicon) == 0 {
+		t.Error("Expected the bundled lexicon to be loaded")
+	}
+}
+
+func TestScoreText(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected int // sign of the score
+	}{
+		{name: "positive", message: "Great app, love it!", expected: 1},
+		{name: "negative", message: "It crashes all the time", expected: -1},
+		{name: "neutral", message: "I opened the settings page", expected: 0},
+		{name: "negation", message: "The search is not good", expected: -1},
+		{name: "negated negative", message: "Honestly not bad at all", expected: 1},
+		{name: "negation ends with the clause", message: "No ads. Great", expected: 1},
+		{name: "mixed", message: "Love the design but it crashes and the sync is broken", expected: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := scoreText(tt.message)
+
+			if score < -1 || score > 1 {
+				t.Fatalf("Expected score in [-1, 1], got %f", score)
+			}
+
+			sign := 0
+			if score > 0 {
+				sign = 1
+			} else if score < 0 {
+				sign = -1
+			}
+
+			if sign != tt.expected {
+				t.Errorf("Expected score with sign %d, got %f", tt.expected, score)
+			}
+		})
+	}
+}
+
+func TestScoreText_Intensifier(t *testing.T) {
+	if plain, boosted := scoreText("it is slow"), scoreText("it is very slow"); boosted >= plain {
+		t.Errorf("Expected intensifier to strengthen the score, got %f and %f", plain, boosted)
+	}
+}
+
+func TestCheckSentiment(t *testing.T) {
+	messages := []FeedbackMessage{
+		{ID: 1, Sentiment: db.SentimentTypePositive, Message: "Love it"},
+		{ID: 2, Sentiment: db.SentimentTypePositive, Message: "Terrible, it crashes every time and the sync is broken"},
+		{ID: 3, Sentiment: db.SentimentTypeNegative, Message: "Slow and buggy"},
+		{ID: 4, Sentiment: db.SentimentTypeNegative, Message: "Excellent, works perfectly"},
+		{ID: 5, Sentiment: db.SentimentTypeNegative, Message: ""},
+	}
+
+	check := checkSentiment(messages)
+
+	if check.Scored != 4 {
+		t.Errorf("Expected 4 scored messages, got %d", check.Scored)
+	}
+
+	if len(check.Mismatches) != 2 {
+		t.Fatalf("Expected 2 mismatches, got %+v", check.Mismatches)
+	}
+
+	// The strongest contradiction comes first
+	if check.Mismatches[0].ID != 2 || check.Mismatches[1].ID != 4 {
+		t.Errorf("Expected mismatches 2 and 4, got %+v", check.Mismatches)
+	}
+
+	if rate := check.MismatchRate(); rate != 0.5 {
+		t.Errorf("Expected mismatch rate 0.5, got %f", rate)
+	}
+
+	if _, ok := check.Scores[5]; ok || len(check.Scores) != 4 {
+		t.Errorf("Expected scores of messages with text only, got %v", check.Scores)
+	}
+}
+
+func TestFormatSentimentCheck(t *testing.T) {
+	check := &SentimentCheck{
+		Scored: 40,
+		Mismatches: []SentimentMismatch{
+			{ID: 2, Sentiment: db.SentimentTypePositive, Score: -0.8, Message: "It crashes"},
+		},
+	}
+
+	expected := "\n\nRating doesn't match the text: 1 of 40 messages (2.5%)\n• Rated positive: \"It crashes\""
+	if result := formatSentimentCheck(check); result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	for _, empty := range []*SentimentCheck{nil, {Scored: 40}} {
+		if result := formatSentimentCheck(empty); result != "" {
+			t.Errorf("Expected empty section, got %q", result)
+		}
+	}
+
+	notes := formatTaskNotes(&FeedbackSummary{SentimentCheck: check}, anomalyReportDate, anomalyReportDate)
+	if !strings.Contains(notes, expected) {
+		t.Errorf("Expected notes to contain the sentiment check, got %s", notes)
+	}
+}
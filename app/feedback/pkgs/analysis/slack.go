@@ -0,0 +1,213 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+)
+
+const (
+	slackRequestTimeout = 30 * time.Second
+	asanaTaskURLFormat  = "https://app.asana.com/0/%s/%s"
+)
+
+// SlackMessage represents the payload of a Slack (or Mattermost) incoming webhook.
+// Text is used as a notification fallback and by clients without Block Kit support.
+type SlackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []SlackBlock `json:"blocks,omitempty"`
+}
+
+// SlackBlock is a single Block Kit layout block
+type SlackBlock struct {
+	Type     string      `json:"type"`
+	Text     *SlackText  `json:"text,omitempty"`
+	Fields   []SlackText `json:"fields,omitempty"`
+	Elements []SlackText `json:"elements,omitempty"`
+}
+
+// SlackText is a Block Kit text object
+type SlackText struct {
+	Type string `json:"type"` // "plain_text" or "mrkdwn"
+	Text string `json:"text"`
+}
+
+// SlackClient posts messages to a Slack-compatible incoming webhook
+type SlackClient struct {
+	webhookURL string
+	httpClient *http.Client
+}
+
+// newSlackClient creates a new Slack webhook client
+func newSlackClient(webhookURL string) *SlackClient {
+	return &SlackClient{
+		webhookURL: webhookURL,
+		httpClient: &http.Client{Timeout: slackRequestTimeout},
+	}
+}
+
+// postSlackSummary posts the aggregation results to the configured webhook
+func (a *Aggregator) postSlackSummary(
+	ctx context.Context,
+	summary *FeedbackSummary,
+	windowStart, windowEnd time.Time,
+	asanaTaskGID string,
+) error {
+	// Skip if no webhook is configured
+	if a.slackWebhookURL == "" {
+		return nil
+	}
+
+	if summary.Total == 0 {
+		slog.Info("No feedback to report, skipping Slack notification")
+
+		return nil
+	}
+
+	client := newSlackClient(a.slackWebhookURL)
+	message := buildSlackMessage(summary, windowStart, windowEnd, a.asanaTaskURL(asanaTaskGID))
+
+	if err := client.postMessage(ctx, message); err != nil {
+		return err
+	}
+
+	slog.Info("Slack notification sent successfully")
+
+	return nil
+}
+
+// asanaTaskURL returns a link to the Asana task or an empty string when no task exists
+func (a *Aggregator) asanaTaskURL(taskGID string) string {
+	if taskGID == "" {
+		return ""
+	}
+
+	// Asana accepts "0" as a placeholder when the task isn't viewed within a project
+	projectGID := a.asanaProject
+	if projectGID == "" {
+		projectGID = "0"
+	}
+
+	return fmt.Sprintf(asanaTaskURLFormat, projectGID, taskGID)
+}
+
+// buildSlackMessage creates a Block Kit message with the aggregation results
+func buildSlackMessage(
+	summary *FeedbackSummary,
+	windowStart, windowEnd time.Time,
+	asanaTaskURL string,
+) SlackMessage {
+	title := formatTaskTitle(summary, windowEnd)
+	window := fmt.Sprintf("Window: %s to %s (UTC)",
+		windowStart.UTC().Format("2006-01-02 15:04"),
+		windowEnd.UTC().Format("2006-01-02 15:04"),
+	)
+
+	blocks := []SlackBlock{
+		{
+			Type: "header",
+			Text: &SlackText{Type: "plain_text", Text: title},
+		},
+		{
+			Type:     "context",
+			Elements: []SlackText{{Type: "mrkdwn", Text: window}},
+		},
+		{
+			Type: "section",
+			Fields: []SlackText{
+				{
+					Type: "mrkdwn",
+					Text: fmt.Sprintf("*Positive*\n%d (%.1f%%)", summary.PositiveCount, summary.PositivePercent),
+				},
+				{
+					Type: "mrkdwn",
+					Text: fmt.Sprintf("*Negative*\n%d (%.1f%%)", summary.NegativeCount, summary.NegativePercent),
+				},
+				{
+					Type: "mrkdwn",
+					Text: fmt.Sprintf("*Total*\n%d", summary.Total),
+				},
+			},
+		},
+	}
+
+	if len(summary.Anomalies) > 0 {
+		alert := ":warning: *Unusual feedback detected*"
+		for _, anomaly := range summary.Anomalies {
+			alert += "\n• " + anomaly
+		}
+
+		// Show the alert right below the header
+		blocks = slices.Insert(blocks, 1, SlackBlock{
+			Type: "section",
+			Text: &SlackText{Type: "mrkdwn", Text: alert},
+		})
+	}
+
+	if asanaTaskURL != "" {
+		blocks = append(blocks, SlackBlock{
+			Type: "section",
+			Text: &SlackText{Type: "mrkdwn", Text: fmt.Sprintf("<%s|View report in Asana>", asanaTaskURL)},
+		})
+	}
+
+	text := fmt.Sprintf("%s: %d positive (%.1f%%), %d negative (%.1f%%), %d total",
+		title,
+		summary.PositiveCount, summary.PositivePercent,
+		summary.NegativeCount, summary.NegativePercent,
+		summary.Total,
+	)
+
+	return SlackMessage{
+		Text:   text,
+		Blocks: blocks,
+	}
+}
+
+// postMessage sends a message to the incoming webhook
+func (c *SlackClient) postMessage(ctx context.Context, message SlackMessage) error {
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal Slack message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		c.webhookURL,
+		bytes.NewBuffer(jsonData),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create Slack webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send Slack webhook request: %w", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			slog.Warn("Failed to close response body", "error", err)
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read Slack webhook response: %w", err)
+	}
+
+	// Slack responds with 200 "ok", Mattermost with 200 and an empty body
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack webhook returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
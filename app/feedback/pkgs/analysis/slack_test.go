@@ -0,0 +1,188 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildSlackMessage(t *testing.T) {
+	summary := &FeedbackSummary{
+		PositiveCount:   75,
+		NegativeCount:   25,
+		Total:           100,
+		PositivePercent: 75.0,
+		NegativePercent: 25.0,
+	}
+	windowStart := time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+	taskURL := "https://app.asana.com/0/project-456/1234567890"
+
+	message := buildSlackMessage(summary, windowStart, windowEnd, taskURL)
+
+	if !strings.Contains(message.Text, "Daily Feedback Summary - 2024-06-15") {
+		t.Errorf("Expected fallback text to contain task name, got %q", message.Text)
+	}
+
+	if len(message.Blocks) != 4 {
+		t.Fatalf("Expected 4 blocks, got %d", len(message.Blocks))
+	}
+
+	if message.Blocks[0].Type != "header" {
+		t.Errorf("Expected first block to be header, got %q", message.Blocks[0].Type)
+	}
+
+	// Verify counts and percentages are rendered as fields
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		t.Fatalf("Failed to marshal message: %v", err)
+	}
+
+	expectedSubstrings := []string{
+		`75 (75.0%)`,
+		`25 (25.0%)`,
+		`*Total*\n100`,
+		`Window: 2024-06-14 00:00 to 2024-06-15 00:00 (UTC)`,
+	}
+
+	for _, substr := range expectedSubstrings {
+		if !strings.Contains(string(jsonData), substr) {
+			t.Errorf("Expected message to contain %q, but it didn't.\nGot: %s", substr, jsonData)
+		}
+	}
+
+	// Verify the Asana link is the last block
+	expectedLink := "<" + taskURL + "|View report in Asana>"
+	if link := message.Blocks[3].Text; link == nil || link.Text != expectedLink {
+		t.Errorf("Expected Asana link block %q, got %+v", expectedLink, link)
+	}
+}
+
+func TestBuildSlackMessage_NoAsanaTask(t *testing.T) {
+	summary := &FeedbackSummary{PositiveCount: 1, Total: 1, PositivePercent: 100.0}
+	windowStart := time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	message := buildSlackMessage(summary, windowStart, windowEnd, "")
+
+	for _, block := range message.Blocks {
+		if block.Text != nil && strings.Contains(block.Text.Text, "Asana") {
+			t.Errorf("Expected no Asana link block, got %q", block.Text.Text)
+		}
+	}
+}
+
+func TestAsanaTaskURL(t *testing.T) {
+	tests := []struct {
+		name     string
+		project  string
+		taskGID  string
+		expected string
+	}{
+		{
+			name:     "task in project",
+			project:  "project-456",
+			taskGID:  "123",
+			expected: "https://app.asana.com/0/project-456/123",
+		},
+		{
+			name:     "task without project",
+			project:  "",
+			taskGID:  "123",
+			expected: "https://app.asana.com/0/0/123",
+		},
+		{
+			name:     "no task",
+			project:  "project-456",
+			taskGID:  "",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Aggregator{asanaProject: tt.project}
+
+			if result := a.asanaTaskURL(tt.taskGID); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSlackClient_PostMessage_Success(t *testing.T) {
+	var received SlackMessage
+
+	// Create a mock webhook server
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			t.Errorf("Expected POST request, got %s", r.Method)
+		}
+
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Expected Content-Type 'application/json', got %q", ct)
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write([]byte("ok")); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := &SlackClient{
+		webhookURL: server.URL,
+		httpClient: server.Client(),
+	}
+
+	message := SlackMessage{Text: "Test message"}
+	if err := client.postMessage(context.Background(), message); err != nil {
+		t.Fatalf("postMessage failed: %v", err)
+	}
+
+	if received.Text != "Test message" {
+		t.Errorf("Expected text 'Test message', got %q", received.Text)
+	}
+}
+
+func TestSlackClient_PostMessage_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		if _, err := w.Write([]byte("invalid_token")); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := &SlackClient{
+		webhookURL: server.URL,
+		httpClient: server.Client(),
+	}
+
+	err := client.postMessage(context.Background(), SlackMessage{Text: "Test message"})
+	if err == nil {
+		t.Fatal("Expected error, got nil")
+	}
+
+	if !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "invalid_token") {
+		t.Errorf("Expected error to contain status and body, got %q", err.Error())
+	}
+}
+
+func TestPostSlackSummary_SkipsWithoutWebhook(t *testing.T) {
+	a := &Aggregator{}
+	summary := &FeedbackSummary{PositiveCount: 1, Total: 1, PositivePercent: 100.0}
+
+	err := a.postSlackSummary(context.Background(), summary, time.Now(), time.Now(), "")
+	if err != nil {
+		t.Errorf("Expected no error when webhook is not configured, got %v", err)
+	}
+}
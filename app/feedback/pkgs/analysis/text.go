@@ -0,0 +1,103 @@
+package analysis
+
+import (
+	"bufio"
+	_ "embed"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed data/stopwords_en.txt
+var stopwordsFile string
+
+// stopwords are common words that carry no meaning on their own
+var stopwords = loadWordList(stopwordsFile)
+
+// loadWordList parses a bundled word list, skipping empty lines and # comments
+func loadWordList(content string) map[string]struct{} {
+	words := map[string]struct{}{}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		words[strings.ToLower(line)] = struct{}{}
+	}
+
+	return words
+}
+
+// tokenize splits a message into lowercase words.
+// Apostrophes inside words are kept ("don't"), other punctuation separates words.
+func tokenize(message string) []string {
+	message = strings.ToLower(strings.ReplaceAll(message, "’", "'"))
+
+	fields := strings.FieldsFunc(message, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+
+	tokens := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if token := strings.Trim(field, "'"); token != "" {
+			tokens = append(tokens, token)
+		}
+	}
+
+	return tokens
+}
+
+// splitClauses splits a message at punctuation that separates clauses
+func splitClauses(message string) []string {
+	return strings.FieldsFunc(message, func(r rune) bool {
+		return strings.ContainsRune(".,;:!?()[]\n", r)
+	})
+}
+
+// isStopword reports whether the token is a stopword
+func isStopword(token string) bool {
+	_, ok := stopwords[token]
+
+	return ok
+}
+
+// isKeywordToken reports whether the token may be part of a key term
+func isKeywordToken(token string) bool {
+	if utf8.RuneCountInString(token) < 2 || isStopword(token) {
+		return false
+	}
+
+	// Numbers alone (versions, counts) are rarely meaningful
+	return strings.ContainsFunc(token, unicode.IsLetter)
+}
+
+// messageTerms returns the distinct unigrams and bigrams of a message.
+// Bigrams are only formed from adjacent keyword tokens within the same clause.
+func messageTerms(message string) map[string]struct{} {
+	terms := map[string]struct{}{}
+
+	for _, clause := range splitClauses(message) {
+		previous := ""
+
+		for _, token := range tokenize(clause) {
+			if !isKeywordToken(token) {
+				previous = ""
+
+				continue
+			}
+
+			terms[token] = struct{}{}
+
+			if previous != "" {
+				terms[previous+" "+token] = struct{}{}
+			}
+
+			previous = token
+		}
+	}
+
+	return terms
+}
@@ -0,0 +1,36 @@
+package analysis
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected []string
+	}{
+		{message: "Login FAILS again!!", expected: []string{"login", "fails", "again"}},
+		{message: "I don’t like the new UI, v2.0", expected: []string{"i", "don't", "like", "the", "new", "ui", "v2", "0"}},
+		{message: "'quoted' words", expected: []string{"quoted", "words"}},
+		{message: "", expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.message, func(t *testing.T) {
+			if result := tokenize(tt.message); !slices.Equal(result, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMessageTerms(t *testing.T) {
+	terms := slices.Sorted(maps.Keys(messageTerms("The login page is broken, login page crashes 2024")))
+	expected := []string{"broken", "crashes", "login", "login page", "page", "page crashes"}
+
+	if !slices.Equal(terms, expected) {
+		t.Errorf("Expected %q, got %q", expected, terms)
+	}
+}
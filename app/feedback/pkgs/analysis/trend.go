@@ -0,0 +1,144 @@
+package analysis
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+)
+
+const (
+	trendArrowUp   = "↑"
+	trendArrowDown = "↓"
+	trendArrowFlat = "→"
+)
+
+// trendPeriod is an earlier report the current report is compared with
+type trendPeriod struct {
+	label string
+	days  int
+}
+
+// trendPeriods are the day-over-day and week-over-week comparisons
+var trendPeriods = []trendPeriod{
+	{label: "Day over day", days: 1},
+	{label: "Week over week", days: 7},
+}
+
+// Trend compares the report with an earlier report run
+type Trend struct {
+	Label string
+	// CompareDate is the report date of the earlier report
+	CompareDate time.Time
+	// Available is false when there is no earlier report, all deltas are zero then
+	Available bool
+
+	PositiveDelta int64
+	NegativeDelta int64
+	TotalDelta    int64
+	// Percentage point changes of the positive and negative share
+	PositivePercentDelta float64
+	NegativePercentDelta float64
+}
+
+// compareTrends compares the summary with the previous day and the same weekday last week.
+// Missing reports result in unavailable trends instead of an error.
+func compareTrends(summary *FeedbackSummary, history []db.ReportRun, reportDate time.Time) []Trend {
+	trends := make([]Trend, 0, len(trendPeriods))
+
+	for _, period := range trendPeriods {
+		trend := Trend{
+			Label:       period.label,
+			CompareDate: reportDate.AddDate(0, 0, -period.days),
+		}
+
+		if previous := findReportRun(history, trend.CompareDate); previous != nil {
+			prev := reportSummary(previous)
+
+			trend.Available = true
+			trend.PositiveDelta = summary.PositiveCount - prev.PositiveCount
+			trend.NegativeDelta = summary.NegativeCount - prev.NegativeCount
+			trend.TotalDelta = summary.Total - prev.Total
+			trend.PositivePercentDelta = summary.PositivePercent - prev.PositivePercent
+			trend.NegativePercentDelta = summary.NegativePercent - prev.NegativePercent
+		}
+
+		trends = append(trends, trend)
+	}
+
+	return trends
+}
+
+// findReportRun returns the report run of the given date or nil if there is none
+func findReportRun(history []db.ReportRun, reportDate time.Time) *db.ReportRun {
+	for i := range history {
+		if history[i].ReportDate.Valid && history[i].ReportDate.Time.Equal(reportDate) {
+			return &history[i]
+		}
+	}
+
+	return nil
+}
+
+// trendArrow returns an arrow showing the direction of a change
+func trendArrow(delta float64) string {
+	switch {
+	case delta > 0:
+		return trendArrowUp
+	case delta < 0:
+		return trendArrowDown
+	default:
+		return trendArrowFlat
+	}
+}
+
+// formatCountDelta formats a change of a count, e.g. "↑ +5"
+func formatCountDelta(delta int64) string {
+	return fmt.Sprintf("%s %+d", trendArrow(float64(delta)), delta)
+}
+
+// formatPercentDelta formats a change of a share in percentage points, e.g. "↓ -2.5 pp"
+func formatPercentDelta(delta float64) string {
+	// Round first so that tiny changes are shown as "→ +0.0 pp" instead of "↓ -0.0 pp"
+	delta = math.Round(delta*10) / 10
+	if delta == 0 {
+		delta = 0 // normalize negative zero
+	}
+
+	return fmt.Sprintf("%s %+.1f pp", trendArrow(delta), delta)
+}
+
+// formatTrends creates the trends section of the Asana task notes
+func formatTrends(trends []Trend) string {
+	if len(trends) == 0 {
+		return ""
+	}
+
+	var section strings.Builder
+
+	section.WriteString("\n\nTrends:")
+
+	for _, trend := range trends {
+		compareDate := trend.CompareDate.Format("2006-01-02")
+
+		if !trend.Available {
+			fmt.Fprintf(&section, "\n• %s: no report for %s", trend.Label, compareDate)
+
+			continue
+		}
+
+		fmt.Fprintf(&section, "\n• %s (vs. %s): Positive %s (%s), Negative %s (%s), Total %s",
+			trend.Label,
+			compareDate,
+			formatCountDelta(trend.PositiveDelta),
+			formatPercentDelta(trend.PositivePercentDelta),
+			formatCountDelta(trend.NegativeDelta),
+			formatPercentDelta(trend.NegativePercentDelta),
+			formatCountDelta(trend.TotalDelta),
+		)
+	}
+
+	return section.String()
+}
@@ -0,0 +1,123 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareTrends(t *testing.T) {
+	reportDate := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+	summary := testSummary(80, 20)
+
+	t.Run("previous day and last week available", func(t *testing.T) {
+		// testHistory starts with the previous day, the 7th entry is the same weekday last week
+		history := testHistory(
+			[2]int32{90, 10}, [2]int32{0, 0}, [2]int32{0, 0}, [2]int32{0, 0},
+			[2]int32{0, 0}, [2]int32{0, 0}, [2]int32{60, 40},
+		)
+
+		trends := compareTrends(summary, history, reportDate)
+		if len(trends) != 2 {
+			t.Fatalf("Expected 2 trends, got %d", len(trends))
+		}
+
+		day := trends[0]
+		if !day.Available || !day.CompareDate.Equal(reportDate.AddDate(0, 0, -1)) {
+			t.Fatalf("Expected day over day trend against 2024-06-14, got %+v", day)
+		}
+
+		if day.PositiveDelta != -10 || day.NegativeDelta != 10 || day.TotalDelta != 0 {
+			t.Errorf("Expected deltas -10/+10/0, got %+v", day)
+		}
+
+		if day.NegativePercentDelta != 10 {
+			t.Errorf("Expected negative percent delta 10, got %.1f", day.NegativePercentDelta)
+		}
+
+		week := trends[1]
+		if !week.Available || week.PositiveDelta != 20 || week.NegativeDelta != -20 {
+			t.Errorf("Expected week over week deltas +20/-20, got %+v", week)
+		}
+	})
+
+	t.Run("missing history", func(t *testing.T) {
+		trends := compareTrends(summary, nil, reportDate)
+		if len(trends) != 2 {
+			t.Fatalf("Expected 2 trends, got %d", len(trends))
+		}
+
+		for _, trend := range trends {
+			if trend.Available {
+				t.Errorf("Expected %q to be unavailable, got %+v", trend.Label, trend)
+			}
+		}
+	})
+}
+
+func TestFormatTrends(t *testing.T) {
+	trends := []Trend{
+		{
+			Label:                "Day over day",
+			CompareDate:          time.Date(2024, time.June, 14, 0, 0, 0, 0, time.UTC),
+			Available:            true,
+			PositiveDelta:        -10,
+			NegativeDelta:        10,
+			TotalDelta:           0,
+			PositivePercentDelta: -10,
+			NegativePercentDelta: 10,
+		},
+		{
+			Label:       "Week over week",
+			CompareDate: time.Date(2024, time.June, 8, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	expected := "\n\nTrends:" +
+		"\n• Day over day (vs. 2024-06-14): Positive ↓ -10 (↓ -10.0 pp), " +
+		"Negative ↑ +10 (↑ +10.0 pp), Total → +0" +
+		"\n• Week over week: no report for 2024-06-08"
+
+	if result := formatTrends(trends); result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	if result := formatTrends(nil); result != "" {
+		t.Errorf("Expected empty trends section, got %q", result)
+	}
+}
+
+func TestFormatPercentDelta(t *testing.T) {
+	tests := []struct {
+		delta    float64
+		expected string
+	}{
+		{delta: 2.54, expected: "↑ +2.5 pp"},
+		{delta: -2.54, expected: "↓ -2.5 pp"},
+		{delta: -0.01, expected: "→ +0.0 pp"},
+		{delta: 0, expected: "→ +0.0 pp"},
+	}
+
+	for _, tt := range tests {
+		if result := formatPercentDelta(tt.delta); result != tt.expected {
+			t.Errorf("delta %v: expected %q, got %q", tt.delta, tt.expected, result)
+		}
+	}
+}
+
+func TestFormatTaskNotes_Trends(t *testing.T) {
+	summary := testSummary(80, 20)
+	summary.Trends = compareTrends(summary, testHistory([2]int32{90, 10}), anomalyReportDate)
+
+	notes := formatTaskNotes(summary, anomalyReportDate.AddDate(0, 0, -1), anomalyReportDate)
+
+	for _, substr := range []string{
+		"• Total: 100\n\nTrends:",
+		"• Day over day (vs. 2024-06-14): Positive ↓ -10",
+		"• Week over week: no report for 2024-06-08",
+	} {
+		if !strings.Contains(notes, substr) {
+			t.Errorf("Expected notes to contain %q, got %s", substr, notes)
+		}
+	}
+}
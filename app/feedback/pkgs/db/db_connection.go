@@ -5,6 +5,12 @@ package db
 import (
 	"context"
 	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -12,26 +18,202 @@ import (
 
 // Config holds database connection configuration
 type Config struct {
-	Host            string
-	Port            int
-	User            string
-	Password        string
-	Database        string
-	SSLMode         string
+	// URL is a postgres:// connection URL or a key/value connection string (host=... dbname=...),
+	// Host, Port, User, Password, Database and SSLMode are ignored when set
+	URL string
+
+	// Host is a host name, an IP address or the directory of a Unix socket (/var/run/postgresql)
+
+	Host     string
+	Port     int
+	User     string
+	Password string
+	// PasswordFile is a file containing the password, takes precedence over Password and the password of URL
+	PasswordFile string
+	Database     string
+	SSLMode      string
+
+	// SSLRootCert, SSLCert and SSLKey are paths of the CA certificate, the client certificate and its key,
+	// they take precedence over the parameters of URL
+	SSLRootCert string
+	SSLCert     string
+	SSLKey      string
+
 	MaxConns        int32
 	MinConns        int32
 	MaxConnLifetime time.Duration
 	MaxConnIdleTime time.Duration
 }
 
+// passwordSetting matches the password of a key/value connection string
+var passwordSetting = regexp.MustCompile(`(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+
+// ConnString returns the connection string of the configuration passed to pgx.
+// A key/value connection string in URL is used as is, with the password of PasswordFile and the SSL
+// certificate paths appended, otherwise it's the connection URL of ConnURL.
+func (cfg Config) ConnString() (string, error) {
+	if cfg.URL == "" || !isKeywordValue(cfg.URL) {
+		u, err := cfg.ConnURL()
+		if err != nil {
+			return "", err
+		}
+
+		return u.String(), nil
+	}
+
+	settings := []string{cfg.URL}
+
+	// Later settings override earlier ones of the same key
+	if cfg.PasswordFile != "" {
+		password, err := readPasswordFile(cfg.PasswordFile)
+		if err != nil {
+			return "", err
+		}
+
+		settings = append(settings, "password="+quoteSetting(password))
+	}
+
+	for _, setting := range []struct{ key, value string }{
+		{"sslrootcert", cfg.SSLRootCert},
+		{"sslcert", cfg.SSLCert},
+		{"sslkey", cfg.SSLKey},
+	} {
+		if setting.value != "" {
+			settings = append(settings, setting.key+"="+quoteSetting(setting.value))
+		}
+	}
+
+	return strings.Join(settings, " "), nil
+}
+
+// RedactConnString returns the connection string with its password replaced by xxxxx, for logging
+func RedactConnString(connString string) string {
+	if isKeywordValue(connString) {
+		return passwordSetting.ReplaceAllString(connString, "${1}xxxxx")
+	}
+
+	u, err := url.Parse(connString)
+	if err != nil {
+		return "(invalid URL)"
+	}
+
+	return u.Redacted()
+}
+
+// isKeywordValue reports whether the connection string is a key/value one rather than a URL
+func isKeywordValue(connString string) bool {
+	return !strings.Contains(connString, "://")
+}
+
+// quoteSetting quotes a value of a key/value connection string
+func quoteSetting(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+
+	return "'" + value + "'"
+}
+
+// ConnURL returns the connection URL of the configuration with all values escaped.
+// URL is used as is when set, otherwise the URL is built from the individual fields. The password of
+// PasswordFile and the SSL certificate paths are applied to both.
+// Returns an error for a key/value connection string in URL, see ConnString.
+func (cfg Config) ConnURL() (*url.URL, error) {
+	var u *url.URL
+
+	if cfg.URL != "" {
+		if isKeywordValue(cfg.URL) {
+			return nil, fmt.Errorf("database URL must be a postgres:// URL, not a key/value connection string")
+		}
+
+		parsed, err := url.Parse(cfg.URL)
+		if err != nil {
+			// The error contains the URL including the password
+			return nil, fmt.Errorf("failed to parse database URL")
+		}
+
+		if parsed.Scheme != "postgres" && parsed.Scheme != "postgresql" {
+			return nil, fmt.Errorf("database URL must start with postgres:// or postgresql://")
+		}
+
+		u = parsed
+	} else {
+		u = &url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(cfg.User, cfg.Password),
+			Path:   "/" + cfg.Database,
+		}
+
+		query := u.Query()
+
+		// A Unix socket directory can't be the host of a URL
+		if strings.HasPrefix(cfg.Host, "/") {
+			query.Set("host", cfg.Host)
+			query.Set("port", strconv.Itoa(cfg.Port))
+		} else {
+			u.Host = net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))
+		}
+
+		if cfg.SSLMode != "" {
+			query.Set("sslmode", cfg.SSLMode)
+		}
+
+		u.RawQuery = query.Encode()
+	}
+
+	if cfg.PasswordFile != "" {
+		password, err := readPasswordFile(cfg.PasswordFile)
+		if err != nil {
+			return nil, err
+		}
+
+		user := ""
+		if u.User != nil {
+			user = u.User.Username()
+		}
+
+		u.User = url.UserPassword(user, password)
+	}
+
+	query := u.Query()
+
+	for key, value := range map[string]string{
+		"sslrootcert": cfg.SSLRootCert,
+		"sslcert":     cfg.SSLCert,
+		"sslkey":      cfg.SSLKey,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+
+	u.RawQuery = query.Encode()
+
+	return u, nil
+}
+
+// readPasswordFile reads a password from a file, trailing newlines are removed
+func readPasswordFile(path string) (string, error) {
+	data, err := os.ReadFile(path) // #nosec G304 - path is provided by the operator
+	if err != nil {
+		return "", fmt.Errorf("failed to read database password file: %w", err)
+	}
+
+	password := strings.TrimRight(string(data), "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("database password file %s is empty", path)
+	}
+
+	return password, nil
+}
+
 // NewPool creates a new connection pool with the given configuration
 func NewPool(ctx context.Context, cfg Config) (*pgxpool.Pool, error) {
-	dbConStr := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Database, cfg.SSLMode,
-	)
+	connString, err := cfg.ConnString()
+	if err != nil {
+		return nil, err
+	}
 
-	poolConfig, err := pgxpool.ParseConfig(dbConStr)
+	poolConfig, err := pgxpool.ParseConfig(connString)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse database config: %w", err)
 	}
@@ -0,0 +1,83 @@
+// This file is NOT generated by sqlc - it contains manual streaming variants of the export queries.
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+// StreamExportFeedback runs the ExportFeedback query and calls fn for every row as it's received,
+// in constant memory. The row passed to fn is reused, fn must not retain it.
+func (q *Queries) StreamExportFeedback(
+	ctx context.Context,
+	arg ExportFeedbackParams,
+	fn func(*ExportFeedbackRow) error,
+) error {
+	rows, err := q.db.Query(ctx, exportFeedback, arg.RangeStart, arg.RangeEnd)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var i ExportFeedbackRow
+
+	for rows.Next() {
+		// Columns listed by the ExportFeedback query, in the same order
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Sentiment,
+			&i.Message,
+			&i.TextScore,
+		); err != nil {
+			return err
+		}
+
+		if err := fn(&i); err != nil {
+			return fmt.Errorf("failed to process feedback %d: %w", i.ID, err)
+		}
+	}
+
+	return rows.Err()
+}
+
+// StreamExportReportRuns runs the ExportReportRuns query and calls fn for every row as it's received,
+// in constant memory. The row passed to fn is reused, fn must not retain it.
+func (q *Queries) StreamExportReportRuns(
+	ctx context.Context,
+	arg ExportReportRunsParams,
+	fn func(*ReportRun) error,
+) error {
+	rows, err := q.db.Query(ctx, exportReportRuns, arg.RangeStart, arg.RangeEnd)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var i ReportRun
+
+	for rows.Next() {
+		// Columns listed by the ExportReportRuns query, in the same order
+		if err := rows.Scan(
+			&i.ReportDate,
+			&i.WindowStart,
+			&i.WindowEnd,
+			&i.PositiveCount,
+			&i.NegativeCount,
+			&i.AsanaTaskGid,
+			&i.CreatedAt,
+			&i.Status,
+			&i.CompletedAt,
+			&i.IsAnomaly,
+			&i.AnomalyDetails,
+		); err != nil {
+			return err
+		}
+
+		if err := fn(&i); err != nil {
+			return fmt.Errorf("failed to process report run %s: %w", i.ReportDate.Time.Format("2006-01-02"), err)
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,47 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db/dbtest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestStreamExportReportRuns(t *testing.T) {
+	q := New(dbtest.New(t))
+	ctx := context.Background()
+
+	reportDate := time.Date(2024, time.June, 15, 0, 0, 0, 0, time.UTC)
+
+	created, err := q.CreateReportRun(ctx, CreateReportRunParams{
+		ReportDate:    pgtype.Date{Time: reportDate, Valid: true},
+		WindowStart:   pgtype.Timestamptz{Time: reportDate.AddDate(0, 0, -1), Valid: true},
+		WindowEnd:     pgtype.Timestamptz{Time: reportDate, Valid: true},
+		PositiveCount: 75,
+		NegativeCount: 25,
+		AsanaTaskGid:  pgtype.Text{String: "1234", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateReportRun failed: %v", err)
+	}
+
+	var exported []ReportRun
+
+	err = q.StreamExportReportRuns(ctx, ExportReportRunsParams{
+		RangeStart: pgtype.Date{Time: reportDate, Valid: true},
+		RangeEnd:   pgtype.Date{Time: reportDate.AddDate(0, 0, 1), Valid: true},
+	}, func(report *ReportRun) error {
+		exported = append(exported, *report)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamExportReportRuns failed: %v", err)
+	}
+
+	if len(exported) != 1 || exported[0] != created {
+		t.Errorf("Expected exported report run %+v, got %+v", created, exported)
+	}
+}
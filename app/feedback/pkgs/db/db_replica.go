@@ -0,0 +1,137 @@
+// This file is NOT generated by sqlc - it contains manual read replica routing code.
+package db
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// DefaultMaxReplicationLag is the replication lag above which reads fall back to the primary
+	DefaultMaxReplicationLag = 30 * time.Second
+
+	// replicaCheckInterval is how long the result of a replication lag check is reused
+	replicaCheckInterval = 10 * time.Second
+)
+
+// errReplicaNotStreaming is returned by the lag check when the replica doesn't receive WAL from the primary
+var errReplicaNotStreaming = errors.New("replica isn't streaming from the primary")
+
+// Router routes read-only queries to a replica while its replication lag is within the maximum.
+// Queries whose results drive writes, and all writes, must use Primary.
+type Router struct {
+	primary *Queries
+	replica *Queries
+	maxLag  time.Duration
+	now     func() time.Time
+
+	// lag returns the replication lag of the replica, replaced in tests
+	lag func(ctx context.Context) (time.Duration, error)
+
+	// mu guards the result of the last lag check, the check itself runs without holding it
+	mu         sync.Mutex
+	checkedAt  time.Time
+	useReplica bool
+}
+
+// NewRouter creates a router, all queries go to the primary when replica is nil
+func NewRouter(primary, replica *pgxpool.Pool, maxLag time.Duration) *Router {
+	if maxLag <= 0 {
+		maxLag = DefaultMaxReplicationLag
+	}
+
+	r := &Router{
+		primary: New(primary),
+		maxLag:  maxLag,
+		now:     time.Now,
+	}
+
+	if replica != nil {
+		r.replica = New(replica)
+		r.lag = func(ctx context.Context) (time.Duration, error) {
+			row, err := r.replica.GetReplicationLag(ctx)
+			if err != nil {
+				return 0, err
+			}
+
+			// The lag of a disconnected replica is unknown
+			if !row.Streaming {
+				return 0, errReplicaNotStreaming
+			}
+
+			return time.Duration(row.LagSeconds * float64(time.Second)), nil
+		}
+	}
+
+	return r
+}
+
+// Primary returns the queries of the primary
+func (r *Router) Primary() *Queries {
+	return r.primary
+}
+
+// Read returns the queries of the replica if it's within the maximum replication lag, the primary otherwise.
+// Concurrent reads while the lag is checked use the result of the previous check.
+func (r *Router) Read(ctx context.Context) *Queries {
+	if r.replica == nil {
+		return r.primary
+	}
+
+	r.mu.Lock()
+
+	now := r.now()
+	if !r.checkedAt.IsZero() && now.Sub(r.checkedAt) < replicaCheckInterval {
+		defer r.mu.Unlock()
+
+		return r.selected()
+	}
+
+	// Claim the check, a slow replica must not block the other reads
+	r.checkedAt = now
+	r.mu.Unlock()
+
+	lag, err := r.lag(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case errors.Is(err, errReplicaNotStreaming):
+		slog.Warn("Replica isn't streaming from the primary, reading from the primary")
+
+		r.useReplica = false
+	case err != nil:
+		slog.Warn("Failed to check replication lag, reading from the primary", "error", err)
+
+		r.useReplica = false
+	case lag > r.maxLag:
+		slog.Warn("Replica is lagging behind, reading from the primary",
+			"lag", lag,
+			"max_lag", r.maxLag)
+
+		r.useReplica = false
+	default:
+		if !r.useReplica {
+			slog.Debug("Reading from the replica", "lag", lag)
+		}
+
+		r.useReplica = true
+	}
+
+	return r.selected()
+}
+
+// selected returns the queries chosen by the last lag check
+func (r *Router) selected() *Queries {
+	if r.useReplica {
+		return r.replica
+	}
+
+	return r.primary
+}
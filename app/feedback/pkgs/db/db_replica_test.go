@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db/dbtest"
+)
+
+// newTestRouter returns a router with a replica whose lag is returned by lag
+func newTestRouter(now *time.Time, lag func() (time.Duration, error)) *Router {
+	return &Router{
+		primary: &Queries{},
+		replica: &Queries{},
+		maxLag:  30 * time.Second,
+		now:     func() time.Time { return *now },
+		lag: func(context.Context) (time.Duration, error) {
+			return lag()
+		},
+	}
+}
+
+func TestRouter_Read(t *testing.T) {
+	tests := []struct {
+		name          string
+		lag           time.Duration
+		err           error
+		expectReplica bool
+	}{
+		{name: "in sync", lag: 0, expectReplica: true},
+		{name: "lag at the maximum", lag: 30 * time.Second, expectReplica: true},
+		{name: "stale replica", lag: 31 * time.Second},
+		{name: "lag check error", err: errors.New("connection refused")},
+		{name: "not streaming", err: errReplicaNotStreaming},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Date(2024, time.June, 15, 10, 0, 0, 0, time.UTC)
+			r := newTestRouter(&now, func() (time.Duration, error) { return tt.lag, tt.err })
+
+			q := r.Read(context.Background())
+			if usesReplica := q == r.replica; usesReplica != tt.expectReplica {
+				t.Errorf("Expected replica %v, got %v", tt.expectReplica, usesReplica)
+			}
+		})
+	}
+}
+
+func TestRouter_ReadCachesLagCheck(t *testing.T) {
+	now := time.Date(2024, time.June, 15, 10, 0, 0, 0, time.UTC)
+	lag := time.Duration(0)
+	checks := 0
+
+	r := newTestRouter(&now, func() (time.Duration, error) {
+		checks++
+
+		return lag, nil
+	})
+
+	if q := r.Read(context.Background()); q != r.replica {
+		t.Fatal("Expected the replica")
+	}
+
+	// The replica falls behind, the previous check is reused within the check interval
+	lag = time.Minute
+	now = now.Add(replicaCheckInterval - time.Second)
+
+	if q := r.Read(context.Background()); q != r.replica {
+		t.Error("Expected the replica within the check interval")
+	}
+
+	now = now.Add(time.Second)
+
+	if q := r.Read(context.Background()); q != r.primary {
+		t.Error("Expected the primary once the replica is stale")
+	}
+
+	if checks != 2 {
+		t.Errorf("Expected 2 lag checks, got %d", checks)
+	}
+}
+
+func TestRouter_ReadDoesNotWaitForLagCheck(t *testing.T) {
+	now := time.Date(2024, time.June, 15, 10, 0, 0, 0, time.UTC)
+	checking := make(chan struct{})
+	release := make(chan struct{})
+
+	r := newTestRouter(&now, func() (time.Duration, error) {
+		close(checking)
+		<-release
+
+		return 0, nil
+	})
+
+	done := make(chan *Queries)
+	go func() {
+		done <- r.Read(context.Background())
+	}()
+
+	<-checking
+
+	// The check is still running, the result of the previous check is used
+	readDone := make(chan *Queries)
+	go func() {
+		readDone <- r.Read(context.Background())
+	}()
+
+	select {
+	case q := <-readDone:
+		if q != r.primary {
+			t.Error("Expected the primary before the first check finished")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Read is blocked by the running lag check")
+	}
+
+	close(release)
+
+	if q := <-done; q != r.replica {
+		t.Error("Expected the replica after the check")
+	}
+}
+
+func TestRouter_ReadWithoutReplica(t *testing.T) {
+	r := NewRouter(nil, nil, 0)
+
+	if q := r.Read(context.Background()); q != r.Primary() {
+		t.Error("Expected the primary without a replica")
+	}
+
+	if r.maxLag != DefaultMaxReplicationLag {
+		t.Errorf("Expected max lag %s, got %s", DefaultMaxReplicationLag, r.maxLag)
+	}
+}
+
+func TestRouter_ReadPrimaryAsReplica(t *testing.T) {
+	pool := dbtest.New(t)
+
+	// A primary isn't in recovery, it's never behind
+	r := NewRouter(pool, pool, 0)
+
+	if q := r.Read(context.Background()); q != r.replica {
+		t.Error("Expected the replica")
+	}
+}
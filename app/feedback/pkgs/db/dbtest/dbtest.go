@@ -0,0 +1,128 @@
+// Package dbtest provides a migrated PostgreSQL database for tests.
+//
+// Tests using it are skipped unless FEEDBACK_TEST_DATABASE_URL points to a database on a server
+// prepared like the development database: app/db/scripts/01-init-docker.sh creates the application
+// users the migrations grant permissions to. The tables are emptied before every test, never point
+// it to a database with data you want to keep.
+package dbtest
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/amacneil/dbmate/v2/pkg/dbmate"
+	_ "github.com/amacneil/dbmate/v2/pkg/driver/postgres"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EnvDatabaseURL is the environment variable holding the URL of the test database
+const EnvDatabaseURL = "FEEDBACK_TEST_DATABASE_URL"
+
+var (
+	migrateOnce sync.Once
+	migrateErr  error
+)
+
+// New returns a pool connected to the migrated test database with empty tables.
+// The test is skipped when no test database is configured. Tests of all packages share the
+// database, they are serialized by an advisory lock held until the test finishes.
+func New(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	rawURL := os.Getenv(EnvDatabaseURL)
+	if rawURL == "" {
+		t.Skipf("%s isn't set, skipping test that needs PostgreSQL", EnvDatabaseURL)
+	}
+
+	ctx := context.Background()
+
+	pool, err := pgxpool.New(ctx, rawURL)
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	// go test runs the packages in parallel
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Failed to acquire connection: %v", err)
+	}
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock(hashtext('dbtest'))"); err != nil {
+		conn.Release()
+		t.Fatalf("Failed to lock test database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock(hashtext('dbtest'))"); err != nil {
+			t.Errorf("Failed to unlock test database: %v", err)
+		}
+
+		conn.Release()
+	})
+
+	migrateOnce.Do(func() {
+		migrateErr = migrate(rawURL)
+	})
+
+	if migrateErr != nil {
+		t.Fatalf("Failed to migrate test database: %v", migrateErr)
+	}
+
+	if err := reset(ctx, pool); err != nil {
+		t.Fatalf("Failed to reset test database: %v", err)
+	}
+
+	return pool
+}
+
+// migrate applies all migrations of app/feedback/db/migrations
+func migrate(rawURL string) error {
+	dbURL, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", EnvDatabaseURL, err)
+	}
+
+	_, file, _, ok := runtime.Caller(0)
+	if !ok {
+		return fmt.Errorf("failed to locate the migrations directory")
+	}
+
+	db := dbmate.New(dbURL)
+	db.MigrationsDir = []string{filepath.Join(filepath.Dir(file), "..", "..", "..", "db", "migrations")}
+	db.AutoDumpSchema = false
+
+	return db.Migrate()
+}
+
+// reset empties all tables and makes sure the feedback partitions cover the previous, current and next month
+func reset(ctx context.Context, pool *pgxpool.Pool) error {
+	if _, err := pool.Exec(ctx, `TRUNCATE feedback, report_runs, feedback_hourly_stats, feedback_rollup_watermarks
+		RESTART IDENTITY`); err != nil {
+		return fmt.Errorf("failed to truncate tables: %w", err)
+	}
+
+	month := time.Now().UTC()
+	month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	for _, start := range []time.Time{month.AddDate(0, -1, 0), month, month.AddDate(0, 1, 0)} {
+		// Same naming scheme as the partition maintenance
+		sql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF feedback FOR VALUES FROM ('%s') TO ('%s')",
+			pgx.Identifier{"feedback_" + start.Format("y2006m01")}.Sanitize(),
+			start.Format(time.RFC3339), start.AddDate(0, 1, 0).Format(time.RFC3339))
+
+		if _, err := pool.Exec(ctx, sql); err != nil {
+			return fmt.Errorf("failed to create feedback partition: %w", err)
+		}
+	}
+
+	return nil
+}
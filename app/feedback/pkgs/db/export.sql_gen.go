@@ -0,0 +1,116 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: export.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const exportFeedback = `-- name: ExportFeedback :many
+SELECT id, created_at, sentiment, message, text_score FROM feedback
+WHERE created_at >= $1 AND created_at < $2
+ORDER BY created_at ASC, id ASC
+`
+
+type ExportFeedbackParams struct {
+	RangeStart pgtype.Timestamptz
+	RangeEnd   pgtype.Timestamptz
+}
+
+type ExportFeedbackRow struct {
+	ID        int32
+	CreatedAt pgtype.Timestamptz
+	Sentiment SentimentType
+	Message   pgtype.Text
+	TextScore pgtype.Float4
+}
+
+// Retrieves all feedback records within a time range, oldest first.
+// Used by the export command through StreamExportFeedback, which scans the same columns.
+// Parameters: range_start (inclusive), range_end (exclusive)
+//
+// Deprecated: Loads all rows into memory, use StreamExportFeedback. The query is only
+// generated for its SQL and row type.
+func (q *Queries) ExportFeedback(ctx context.Context, arg ExportFeedbackParams) ([]ExportFeedbackRow, error) {
+	rows, err := q.db.Query(ctx, exportFeedback, arg.RangeStart, arg.RangeEnd)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ExportFeedbackRow
+	for rows.Next() {
+		var i ExportFeedbackRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Sentiment,
+			&i.Message,
+			&i.TextScore,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const exportReportRuns = `-- name: ExportReportRuns :many
+SELECT
+    report_date, window_start, window_end, positive_count, negative_count, asana_task_gid,
+    created_at, status, completed_at, is_anomaly, anomaly_details
+FROM report_runs
+WHERE report_date >= $1 AND report_date < $2
+ORDER BY report_date ASC
+`
+
+type ExportReportRunsParams struct {
+	RangeStart pgtype.Date
+	RangeEnd   pgtype.Date
+}
+
+// Retrieves all report runs whose report date is within a date range, oldest first.
+// Used by the export command through StreamExportReportRuns, which scans the same columns.
+// The columns are listed explicitly, a column added to report_runs doesn't change the scanned row.
+// Parameters: range_start (inclusive), range_end (exclusive)
+//
+// Deprecated: Loads all rows into memory, use StreamExportReportRuns. The query is only
+// generated for its SQL.
+func (q *Queries) ExportReportRuns(ctx context.Context, arg ExportReportRunsParams) ([]ReportRun, error) {
+	rows, err := q.db.Query(ctx, exportReportRuns, arg.RangeStart, arg.RangeEnd)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReportRun
+	for rows.Next() {
+		var i ReportRun
+		if err := rows.Scan(
+			&i.ReportDate,
+			&i.WindowStart,
+			&i.WindowEnd,
+			&i.PositiveCount,
+			&i.NegativeCount,
+			&i.AsanaTaskGid,
+			&i.CreatedAt,
+			&i.Status,
+			&i.CompletedAt,
+			&i.IsAnomaly,
+			&i.AnomalyDetails,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -12,7 +12,7 @@ import (
 )
 
 const countFeedbackBySentiment = `-- name: CountFeedbackBySentiment :one
-SELECT 
+SELECT
     COUNT(*) FILTER (WHERE sentiment = 'positive') AS positive_count,
     COUNT(*) FILTER (WHERE sentiment = 'negative') AS negative_count
 FROM feedback
@@ -37,12 +37,13 @@ func (q *Queries) CountFeedbackBySentiment(ctx context.Context, arg CountFeedbac
 }
 
 const createFeedback = `-- name: CreateFeedback :one
+
 INSERT INTO feedback (
     sentiment,
     message
 ) VALUES (
     $1, $2
-) RETURNING id, created_at, sentiment, message
+) RETURNING id, created_at, sentiment, message, text_score
 `
 
 type CreateFeedbackParams struct {
@@ -50,47 +51,85 @@ type CreateFeedbackParams struct {
 	Message   pgtype.Text
 }
 
-func (q *Queries) CreateFeedback(ctx context.Context, arg CreateFeedbackParams) (Feedback, error) {
+type CreateFeedbackRow struct {
+	ID        int32
+	CreatedAt pgtype.Timestamptz
+	Sentiment SentimentType
+	Message   pgtype.Text
+	TextScore pgtype.Float4
+}
+
+// The feedback queries list their columns instead of using *, pgx can't scan the
+// message_search tsvector column and it's only needed by the search queries
+func (q *Queries) CreateFeedback(ctx context.Context, arg CreateFeedbackParams) (CreateFeedbackRow, error) {
 	row := q.db.QueryRow(ctx, createFeedback, arg.Sentiment, arg.Message)
-	var i Feedback
+	var i CreateFeedbackRow
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
 		&i.Sentiment,
 		&i.Message,
+		&i.TextScore,
 	)
 	return i, err
 }
 
-const deleteOldFeedback = `-- name: DeleteOldFeedback :exec
+const deleteOldFeedback = `-- name: DeleteOldFeedback :execrows
 DELETE FROM feedback
-WHERE created_at < $1
+WHERE feedback.created_at < $1
+  AND (feedback.id, feedback.created_at) IN (
+    SELECT old.id, old.created_at FROM feedback AS old
+    WHERE old.created_at < $1
+    ORDER BY old.created_at ASC
+    LIMIT $2
+)
 `
 
-func (q *Queries) DeleteOldFeedback(ctx context.Context, createdAt pgtype.Timestamptz) error {
-	_, err := q.db.Exec(ctx, deleteOldFeedback, createdAt)
-	return err
+type DeleteOldFeedbackParams struct {
+	Cutoff    pgtype.Timestamptz
+	BatchSize int32
+}
+
+// Deletes a batch of feedback records created before a cutoff, oldest first.
+// Used by the retention command, bounded batches keep row locks and transactions short.
+// Parameters: $1 = cutoff (exclusive), $2 = maximum number of rows
+// The outer created_at condition lets PostgreSQL prune the partitions after the cutoff
+func (q *Queries) DeleteOldFeedback(ctx context.Context, arg DeleteOldFeedbackParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteOldFeedback, arg.Cutoff, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const getFeedback = `-- name: GetFeedback :one
-SELECT id, created_at, sentiment, message FROM feedback
+SELECT id, created_at, sentiment, message, text_score FROM feedback
 WHERE id = $1
 `
 
-func (q *Queries) GetFeedback(ctx context.Context, id int32) (Feedback, error) {
+type GetFeedbackRow struct {
+	ID        int32
+	CreatedAt pgtype.Timestamptz
+	Sentiment SentimentType
+	Message   pgtype.Text
+	TextScore pgtype.Float4
+}
+
+func (q *Queries) GetFeedback(ctx context.Context, id int32) (GetFeedbackRow, error) {
 	row := q.db.QueryRow(ctx, getFeedback, id)
-	var i Feedback
+	var i GetFeedbackRow
 	err := row.Scan(
 		&i.ID,
 		&i.CreatedAt,
 		&i.Sentiment,
 		&i.Message,
+		&i.TextScore,
 	)
 	return i, err
 }
 
 const getFeedbackInTimeRange = `-- name: GetFeedbackInTimeRange :many
-SELECT id, created_at, sentiment, message FROM feedback
+SELECT id, created_at, sentiment, message, text_score FROM feedback
 WHERE created_at >= $1 AND created_at < $2
 ORDER BY created_at DESC
 `
@@ -100,20 +139,29 @@ type GetFeedbackInTimeRangeParams struct {
 	CreatedAt_2 pgtype.Timestamptz
 }
 
-func (q *Queries) GetFeedbackInTimeRange(ctx context.Context, arg GetFeedbackInTimeRangeParams) ([]Feedback, error) {
+type GetFeedbackInTimeRangeRow struct {
+	ID        int32
+	CreatedAt pgtype.Timestamptz
+	Sentiment SentimentType
+	Message   pgtype.Text
+	TextScore pgtype.Float4
+}
+
+func (q *Queries) GetFeedbackInTimeRange(ctx context.Context, arg GetFeedbackInTimeRangeParams) ([]GetFeedbackInTimeRangeRow, error) {
 	rows, err := q.db.Query(ctx, getFeedbackInTimeRange, arg.CreatedAt, arg.CreatedAt_2)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Feedback
+	var items []GetFeedbackInTimeRangeRow
 	for rows.Next() {
-		var i Feedback
+		var i GetFeedbackInTimeRangeRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
 			&i.Sentiment,
 			&i.Message,
+			&i.TextScore,
 		); err != nil {
 			return nil, err
 		}
@@ -125,8 +173,22 @@ func (q *Queries) GetFeedbackInTimeRange(ctx context.Context, arg GetFeedbackInT
 	return items, nil
 }
 
+const getOldestFeedbackTime = `-- name: GetOldestFeedbackTime :one
+SELECT MIN(created_at)::timestamptz AS created_at FROM feedback
+`
+
+// Retrieves the creation time of the oldest feedback record, NULL if there is no feedback.
+// Used by the retention command to find the first period to check for report runs,
+// and by the rollup verification to skip hours whose feedback was deleted.
+func (q *Queries) GetOldestFeedbackTime(ctx context.Context) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, getOldestFeedbackTime)
+	var created_at pgtype.Timestamptz
+	err := row.Scan(&created_at)
+	return created_at, err
+}
+
 const listFeedback = `-- name: ListFeedback :many
-SELECT id, created_at, sentiment, message FROM feedback
+SELECT id, created_at, sentiment, message, text_score FROM feedback
 ORDER BY created_at DESC
 LIMIT $1 OFFSET $2
 `
@@ -136,20 +198,29 @@ type ListFeedbackParams struct {
 	Offset int32
 }
 
-func (q *Queries) ListFeedback(ctx context.Context, arg ListFeedbackParams) ([]Feedback, error) {
+type ListFeedbackRow struct {
+	ID        int32
+	CreatedAt pgtype.Timestamptz
+	Sentiment SentimentType
+	Message   pgtype.Text
+	TextScore pgtype.Float4
+}
+
+func (q *Queries) ListFeedback(ctx context.Context, arg ListFeedbackParams) ([]ListFeedbackRow, error) {
 	rows, err := q.db.Query(ctx, listFeedback, arg.Limit, arg.Offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Feedback
+	var items []ListFeedbackRow
 	for rows.Next() {
-		var i Feedback
+		var i ListFeedbackRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.CreatedAt,
 			&i.Sentiment,
 			&i.Message,
+			&i.TextScore,
 		); err != nil {
 			return nil, err
 		}
@@ -160,3 +231,63 @@ func (q *Queries) ListFeedback(ctx context.Context, arg ListFeedbackParams) ([]F
 	}
 	return items, nil
 }
+
+const listFeedbackMessagesInTimeRange = `-- name: ListFeedbackMessagesInTimeRange :many
+SELECT id, sentiment, message FROM feedback
+WHERE created_at >= $1 AND created_at < $2
+  AND message IS NOT NULL AND message <> ''
+ORDER BY id ASC
+LIMIT $3
+`
+
+type ListFeedbackMessagesInTimeRangeParams struct {
+	CreatedAt   pgtype.Timestamptz
+	CreatedAt_2 pgtype.Timestamptz
+	Limit       int32
+}
+
+type ListFeedbackMessagesInTimeRangeRow struct {
+	ID        int32
+	Sentiment SentimentType
+	Message   pgtype.Text
+}
+
+// Retrieves non-empty feedback messages within a time window for text analysis.
+// Parameters: $1 = window start (inclusive), $2 = window end (exclusive), $3 = maximum number of rows
+func (q *Queries) ListFeedbackMessagesInTimeRange(ctx context.Context, arg ListFeedbackMessagesInTimeRangeParams) ([]ListFeedbackMessagesInTimeRangeRow, error) {
+	rows, err := q.db.Query(ctx, listFeedbackMessagesInTimeRange, arg.CreatedAt, arg.CreatedAt_2, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListFeedbackMessagesInTimeRangeRow
+	for rows.Next() {
+		var i ListFeedbackMessagesInTimeRangeRow
+		if err := rows.Scan(&i.ID, &i.Sentiment, &i.Message); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateFeedbackTextScores = `-- name: UpdateFeedbackTextScores :exec
+UPDATE feedback SET text_score = scores.score
+FROM (SELECT unnest($1::int[]) AS id, unnest($2::real[]) AS score) AS scores
+WHERE feedback.id = scores.id
+`
+
+type UpdateFeedbackTextScoresParams struct {
+	Ids    []int32
+	Scores []float32
+}
+
+// Stores the text sentiment scores computed by the analysis job.
+// Parameters: ids and scores are arrays of the same length
+func (q *Queries) UpdateFeedbackTextScores(ctx context.Context, arg UpdateFeedbackTextScoresParams) error {
+	_, err := q.db.Exec(ctx, updateFeedbackTextScores, arg.Ids, arg.Scores)
+	return err
+}
@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db/dbtest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestFeedback_RoundTrip(t *testing.T) {
+	q := New(dbtest.New(t))
+	ctx := context.Background()
+
+	created, err := q.CreateFeedback(ctx, CreateFeedbackParams{
+		Sentiment: SentimentTypeNegative,
+		Message:   pgtype.Text{String: "Sync is slow", Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("CreateFeedback failed: %v", err)
+	}
+
+	if created.ID == 0 || !created.CreatedAt.Valid || created.Message.String != "Sync is slow" {
+		t.Fatalf("Expected the stored feedback, got %+v", created)
+	}
+
+	got, err := q.GetFeedback(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetFeedback failed: %v", err)
+	}
+
+	if GetFeedbackRow(created) != got {
+		t.Errorf("Expected %+v, got %+v", created, got)
+	}
+
+	list, err := q.ListFeedback(ctx, ListFeedbackParams{Limit: 10})
+	if err != nil {
+		t.Fatalf("ListFeedback failed: %v", err)
+	}
+
+	if len(list) != 1 || list[0].ID != created.ID {
+		t.Errorf("Expected feedback %d, got %+v", created.ID, list)
+	}
+
+	window := GetFeedbackInTimeRangeParams{
+		CreatedAt:   pgtype.Timestamptz{Time: created.CreatedAt.Time.Add(-time.Minute), Valid: true},
+		CreatedAt_2: pgtype.Timestamptz{Time: created.CreatedAt.Time.Add(time.Minute), Valid: true},
+	}
+
+	inRange, err := q.GetFeedbackInTimeRange(ctx, window)
+	if err != nil {
+		t.Fatalf("GetFeedbackInTimeRange failed: %v", err)
+	}
+
+	if len(inRange) != 1 || inRange[0].ID != created.ID {
+		t.Errorf("Expected feedback %d, got %+v", created.ID, inRange)
+	}
+
+	var exported []ExportFeedbackRow
+
+	err = q.StreamExportFeedback(ctx, ExportFeedbackParams{
+		RangeStart: window.CreatedAt,
+		RangeEnd:   window.CreatedAt_2,
+	}, func(feedback *ExportFeedbackRow) error {
+		exported = append(exported, *feedback)
+
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamExportFeedback failed: %v", err)
+	}
+
+	if len(exported) != 1 || ExportFeedbackRow(created) != exported[0] {
+		t.Errorf("Expected exported feedback %+v, got %+v", created, exported)
+	}
+}
@@ -11,6 +11,48 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type ReportStatus string
+
+const (
+	ReportStatusPending   ReportStatus = "pending"
+	ReportStatusCompleted ReportStatus = "completed"
+)
+
+func (e *ReportStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = ReportStatus(s)
+	case string:
+		*e = ReportStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for ReportStatus: %T", src)
+	}
+	return nil
+}
+
+type NullReportStatus struct {
+	ReportStatus ReportStatus
+	Valid        bool // Valid is true if ReportStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullReportStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.ReportStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.ReportStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullReportStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.ReportStatus), nil
+}
+
 type SentimentType string
 
 const (
@@ -54,18 +96,37 @@ func (ns NullSentimentType) Value() (driver.Value, error) {
 }
 
 type Feedback struct {
-	ID        int32
-	CreatedAt pgtype.Timestamptz
-	Sentiment SentimentType
-	Message   pgtype.Text
+	ID            int32
+	CreatedAt     pgtype.Timestamptz
+	Sentiment     SentimentType
+	Message       pgtype.Text
+	TextScore     pgtype.Float4
+	MessageSearch interface{}
 }
 
-type ReportRun struct {
-	ReportDate    pgtype.Date
-	WindowStart   pgtype.Timestamptz
-	WindowEnd     pgtype.Timestamptz
+type FeedbackHourlyStat struct {
+	Hour          pgtype.Timestamptz
 	PositiveCount int32
 	NegativeCount int32
-	AsanaTaskGid  pgtype.Text
-	CreatedAt     pgtype.Timestamptz
+	UpdatedAt     pgtype.Timestamptz
+}
+
+type FeedbackRollupWatermark struct {
+	Name      string
+	Watermark pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+}
+
+type ReportRun struct {
+	ReportDate     pgtype.Date
+	WindowStart    pgtype.Timestamptz
+	WindowEnd      pgtype.Timestamptz
+	PositiveCount  int32
+	NegativeCount  int32
+	AsanaTaskGid   pgtype.Text
+	CreatedAt      pgtype.Timestamptz
+	Status         ReportStatus
+	CompletedAt    pgtype.Timestamptz
+	IsAnomaly      bool
+	AnomalyDetails pgtype.Text
 }
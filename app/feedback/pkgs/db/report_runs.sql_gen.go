@@ -21,7 +21,7 @@ INSERT INTO report_runs (
     asana_task_gid
 ) VALUES (
     $1, $2, $3, $4, $5, $6
-) RETURNING report_date, window_start, window_end, positive_count, negative_count, asana_task_gid, created_at
+) RETURNING report_date, window_start, window_end, positive_count, negative_count, asana_task_gid, created_at, status, completed_at, is_anomaly, anomaly_details
 `
 
 type CreateReportRunParams struct {
@@ -51,12 +51,16 @@ func (q *Queries) CreateReportRun(ctx context.Context, arg CreateReportRunParams
 		&i.NegativeCount,
 		&i.AsanaTaskGid,
 		&i.CreatedAt,
+		&i.Status,
+		&i.CompletedAt,
+		&i.IsAnomaly,
+		&i.AnomalyDetails,
 	)
 	return i, err
 }
 
 const getLatestReportRun = `-- name: GetLatestReportRun :one
-SELECT report_date, window_start, window_end, positive_count, negative_count, asana_task_gid, created_at FROM report_runs
+SELECT report_date, window_start, window_end, positive_count, negative_count, asana_task_gid, created_at, status, completed_at, is_anomaly, anomaly_details FROM report_runs
 ORDER BY report_date DESC
 LIMIT 1
 `
@@ -72,12 +76,16 @@ func (q *Queries) GetLatestReportRun(ctx context.Context) (ReportRun, error) {
 		&i.NegativeCount,
 		&i.AsanaTaskGid,
 		&i.CreatedAt,
+		&i.Status,
+		&i.CompletedAt,
+		&i.IsAnomaly,
+		&i.AnomalyDetails,
 	)
 	return i, err
 }
 
 const getReportRun = `-- name: GetReportRun :one
-SELECT report_date, window_start, window_end, positive_count, negative_count, asana_task_gid, created_at FROM report_runs
+SELECT report_date, window_start, window_end, positive_count, negative_count, asana_task_gid, created_at, status, completed_at, is_anomaly, anomaly_details FROM report_runs
 WHERE report_date = $1
 `
 
@@ -92,12 +100,132 @@ func (q *Queries) GetReportRun(ctx context.Context, reportDate pgtype.Date) (Rep
 		&i.NegativeCount,
 		&i.AsanaTaskGid,
 		&i.CreatedAt,
+		&i.Status,
+		&i.CompletedAt,
+		&i.IsAnomaly,
+		&i.AnomalyDetails,
 	)
 	return i, err
 }
 
+const getReportRunForUpdate = `-- name: GetReportRunForUpdate :one
+SELECT report_date, window_start, window_end, positive_count, negative_count, asana_task_gid, created_at, status, completed_at, is_anomaly, anomaly_details FROM report_runs
+WHERE report_date = $1
+FOR UPDATE
+`
+
+// Retrieves a report run and locks the row until the end of the transaction.
+// Used to claim a report date before the Asana task is created.
+// Parameter: $1 = report_date (DATE)
+func (q *Queries) GetReportRunForUpdate(ctx context.Context, reportDate pgtype.Date) (ReportRun, error) {
+	row := q.db.QueryRow(ctx, getReportRunForUpdate, reportDate)
+	var i ReportRun
+	err := row.Scan(
+		&i.ReportDate,
+		&i.WindowStart,
+		&i.WindowEnd,
+		&i.PositiveCount,
+		&i.NegativeCount,
+		&i.AsanaTaskGid,
+		&i.CreatedAt,
+		&i.Status,
+		&i.CompletedAt,
+		&i.IsAnomaly,
+		&i.AnomalyDetails,
+	)
+	return i, err
+}
+
+const listCompletedReportRunsInTimeRange = `-- name: ListCompletedReportRunsInTimeRange :many
+SELECT report_date, window_start, window_end, positive_count, negative_count, asana_task_gid, created_at, status, completed_at, is_anomaly, anomaly_details FROM report_runs
+WHERE status = 'completed'
+  AND window_end > $1
+  AND window_start < $2
+ORDER BY window_start ASC
+`
+
+type ListCompletedReportRunsInTimeRangeParams struct {
+	RangeStart pgtype.Timestamptz
+	RangeEnd   pgtype.Timestamptz
+}
+
+// Retrieves completed report runs whose window overlaps a time range, ordered by window start.
+// Used by the retention command to find periods that haven't been reported yet.
+// Parameters: $1 = range start (inclusive), $2 = range end (exclusive)
+func (q *Queries) ListCompletedReportRunsInTimeRange(ctx context.Context, arg ListCompletedReportRunsInTimeRangeParams) ([]ReportRun, error) {
+	rows, err := q.db.Query(ctx, listCompletedReportRunsInTimeRange, arg.RangeStart, arg.RangeEnd)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReportRun
+	for rows.Next() {
+		var i ReportRun
+		if err := rows.Scan(
+			&i.ReportDate,
+			&i.WindowStart,
+			&i.WindowEnd,
+			&i.PositiveCount,
+			&i.NegativeCount,
+			&i.AsanaTaskGid,
+			&i.CreatedAt,
+			&i.Status,
+			&i.CompletedAt,
+			&i.IsAnomaly,
+			&i.AnomalyDetails,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPendingReportRuns = `-- name: ListPendingReportRuns :many
+SELECT report_date, window_start, window_end, positive_count, negative_count, asana_task_gid, created_at, status, completed_at, is_anomaly, anomaly_details FROM report_runs
+WHERE status = 'pending'
+ORDER BY report_date ASC
+`
+
+// Retrieves report runs whose Asana task hasn't been created yet, oldest first.
+// Used to resume runs interrupted between recording the run and creating the task.
+func (q *Queries) ListPendingReportRuns(ctx context.Context) ([]ReportRun, error) {
+	rows, err := q.db.Query(ctx, listPendingReportRuns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReportRun
+	for rows.Next() {
+		var i ReportRun
+		if err := rows.Scan(
+			&i.ReportDate,
+			&i.WindowStart,
+			&i.WindowEnd,
+			&i.PositiveCount,
+			&i.NegativeCount,
+			&i.AsanaTaskGid,
+			&i.CreatedAt,
+			&i.Status,
+			&i.CompletedAt,
+			&i.IsAnomaly,
+			&i.AnomalyDetails,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listReportRuns = `-- name: ListReportRuns :many
-SELECT report_date, window_start, window_end, positive_count, negative_count, asana_task_gid, created_at FROM report_runs
+SELECT report_date, window_start, window_end, positive_count, negative_count, asana_task_gid, created_at, status, completed_at, is_anomaly, anomaly_details FROM report_runs
 ORDER BY report_date DESC
 LIMIT $1 OFFSET $2
 `
@@ -107,6 +235,9 @@ type ListReportRunsParams struct {
 	Offset int32
 }
 
+// Retrieves a paginated list of report runs, ordered by most recent first.
+// Used for displaying historical analysis reports with pagination support.
+// Parameters: $1 = limit (number of records), $2 = offset (for pagination)
 func (q *Queries) ListReportRuns(ctx context.Context, arg ListReportRunsParams) ([]ReportRun, error) {
 	rows, err := q.db.Query(ctx, listReportRuns, arg.Limit, arg.Offset)
 	if err != nil {
@@ -124,6 +255,56 @@ func (q *Queries) ListReportRuns(ctx context.Context, arg ListReportRunsParams)
 			&i.NegativeCount,
 			&i.AsanaTaskGid,
 			&i.CreatedAt,
+			&i.Status,
+			&i.CompletedAt,
+			&i.IsAnomaly,
+			&i.AnomalyDetails,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listReportRunsBetween = `-- name: ListReportRunsBetween :many
+SELECT report_date, window_start, window_end, positive_count, negative_count, asana_task_gid, created_at, status, completed_at, is_anomaly, anomaly_details FROM report_runs
+WHERE report_date >= $1 AND report_date < $2
+ORDER BY report_date ASC
+`
+
+type ListReportRunsBetweenParams struct {
+	ReportDate   pgtype.Date
+	ReportDate_2 pgtype.Date
+}
+
+// Retrieves report runs within a date range, oldest first.
+// Used to compute the trailing baseline for anomaly detection.
+// Parameters: $1 = first report_date (inclusive), $2 = last report_date (exclusive)
+func (q *Queries) ListReportRunsBetween(ctx context.Context, arg ListReportRunsBetweenParams) ([]ReportRun, error) {
+	rows, err := q.db.Query(ctx, listReportRunsBetween, arg.ReportDate, arg.ReportDate_2)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReportRun
+	for rows.Next() {
+		var i ReportRun
+		if err := rows.Scan(
+			&i.ReportDate,
+			&i.WindowStart,
+			&i.WindowEnd,
+			&i.PositiveCount,
+			&i.NegativeCount,
+			&i.AsanaTaskGid,
+			&i.CreatedAt,
+			&i.Status,
+			&i.CompletedAt,
+			&i.IsAnomaly,
+			&i.AnomalyDetails,
 		); err != nil {
 			return nil, err
 		}
@@ -135,6 +316,59 @@ func (q *Queries) ListReportRuns(ctx context.Context, arg ListReportRunsParams)
 	return items, nil
 }
 
+const listReportRunsSince = `-- name: ListReportRunsSince :many
+SELECT report_date, window_start, window_end, positive_count, negative_count, asana_task_gid, created_at, status, completed_at, is_anomaly, anomaly_details FROM report_runs
+WHERE report_date >= $1
+ORDER BY report_date ASC
+`
+
+// Retrieves report runs on or after a given date, oldest first.
+// Used to verify Asana tasks referenced by report runs.
+// Parameter: $1 = report_date (DATE)
+func (q *Queries) ListReportRunsSince(ctx context.Context, reportDate pgtype.Date) ([]ReportRun, error) {
+	rows, err := q.db.Query(ctx, listReportRunsSince, reportDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ReportRun
+	for rows.Next() {
+		var i ReportRun
+		if err := rows.Scan(
+			&i.ReportDate,
+			&i.WindowStart,
+			&i.WindowEnd,
+			&i.PositiveCount,
+			&i.NegativeCount,
+			&i.AsanaTaskGid,
+			&i.CreatedAt,
+			&i.Status,
+			&i.CompletedAt,
+			&i.IsAnomaly,
+			&i.AnomalyDetails,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const releaseReportRunLock = `-- name: ReleaseReportRunLock :one
+SELECT pg_advisory_unlock(hashtext('report_runs')) AS unlocked
+`
+
+// Releases the advisory lock taken by TryReportRunLock on the same connection.
+func (q *Queries) ReleaseReportRunLock(ctx context.Context) (bool, error) {
+	row := q.db.QueryRow(ctx, releaseReportRunLock)
+	var unlocked bool
+	err := row.Scan(&unlocked)
+	return unlocked, err
+}
+
 const reportRunExists = `-- name: ReportRunExists :one
 SELECT EXISTS(
     SELECT 1 FROM report_runs
@@ -142,6 +376,10 @@ SELECT EXISTS(
 )
 `
 
+// Checks if a report run already exists for a specific date.
+// Returns true if a report exists, false otherwise.
+// Used to prevent duplicate report generation (idempotency check).
+// Parameter: $1 = report_date (DATE)
 func (q *Queries) ReportRunExists(ctx context.Context, reportDate pgtype.Date) (bool, error) {
 	row := q.db.QueryRow(ctx, reportRunExists, reportDate)
 	var exists bool
@@ -149,9 +387,26 @@ func (q *Queries) ReportRunExists(ctx context.Context, reportDate pgtype.Date) (
 	return exists, err
 }
 
+const tryReportRunLock = `-- name: TryReportRunLock :one
+SELECT pg_try_advisory_lock(hashtext('report_runs')) AS locked
+`
+
+// Takes the session-level advisory lock of the reports without waiting.
+// Used to allow only one run to create or resume reports at a time, a run completes
+// pending reports of any date so the lock isn't scoped to a report date. The lock is
+// held by the connection until it's released or the session ends.
+func (q *Queries) TryReportRunLock(ctx context.Context) (bool, error) {
+	row := q.db.QueryRow(ctx, tryReportRunLock)
+	var locked bool
+	err := row.Scan(&locked)
+	return locked, err
+}
+
 const updateAsanaTaskGid = `-- name: UpdateAsanaTaskGid :exec
 UPDATE report_runs
-SET asana_task_gid = $2
+SET asana_task_gid = $2,
+    status = 'completed',
+    completed_at = NOW()
 WHERE report_date = $1
 `
 
@@ -160,7 +415,29 @@ type UpdateAsanaTaskGidParams struct {
 	AsanaTaskGid pgtype.Text
 }
 
+// Stores the Asana task GID and marks the report run as completed.
+// Parameters: $1 = report_date (DATE), $2 = asana_task_gid (NULL if no task was created)
 func (q *Queries) UpdateAsanaTaskGid(ctx context.Context, arg UpdateAsanaTaskGidParams) error {
 	_, err := q.db.Exec(ctx, updateAsanaTaskGid, arg.ReportDate, arg.AsanaTaskGid)
 	return err
 }
+
+const updateReportRunAnomaly = `-- name: UpdateReportRunAnomaly :exec
+UPDATE report_runs
+SET is_anomaly = $2,
+    anomaly_details = $3
+WHERE report_date = $1
+`
+
+type UpdateReportRunAnomalyParams struct {
+	ReportDate     pgtype.Date
+	IsAnomaly      bool
+	AnomalyDetails pgtype.Text
+}
+
+// Marks a report run as anomalous (or clears the mark).
+// Parameters: $1 = report_date (DATE), $2 = is_anomaly, $3 = anomaly_details
+func (q *Queries) UpdateReportRunAnomaly(ctx context.Context, arg UpdateReportRunAnomalyParams) error {
+	_, err := q.db.Exec(ctx, updateReportRunAnomaly, arg.ReportDate, arg.IsAnomaly, arg.AnomalyDetails)
+	return err
+}
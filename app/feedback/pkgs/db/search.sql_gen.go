@@ -0,0 +1,163 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: search.sql
+
+package db
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const searchFeedback = `-- name: SearchFeedback :many
+SELECT
+    id,
+    created_at,
+    sentiment,
+    ts_rank_cd(message_search, query)::real AS rank,
+    ts_headline('english'::regconfig, COALESCE(message, ''), query,
+        'StartSel=**, StopSel=**, MaxFragments=2, MaxWords=20, MinWords=5, FragmentDelimiter=" ... "')::text AS snippet
+FROM feedback, websearch_to_tsquery('english'::regconfig, $1::text) AS query
+WHERE message_search @@ query
+  AND ($2::sentiment_type IS NULL OR sentiment = $2::sentiment_type)
+  AND ($3::timestamptz IS NULL OR created_at >= $3::timestamptz)
+  AND ($4::timestamptz IS NULL OR created_at < $4::timestamptz)
+ORDER BY rank DESC, created_at DESC
+LIMIT $5
+`
+
+type SearchFeedbackParams struct {
+	Query      string
+	Sentiment  NullSentimentType
+	RangeStart pgtype.Timestamptz
+	RangeEnd   pgtype.Timestamptz
+	MaxResults int32
+}
+
+type SearchFeedbackRow struct {
+	ID        int32
+	CreatedAt pgtype.Timestamptz
+	Sentiment SentimentType
+	Rank      float32
+	Snippet   string
+}
+
+// Full-text search over feedback messages using the indexed english vector, best matches first.
+// The query uses the websearch syntax: words, "quoted phrases", OR and -excluded words.
+// Matched words are wrapped in ** in the snippet, which holds up to 2 fragments of the message.
+// Parameters: sentiment, range_start (inclusive) and range_end (exclusive) are optional filters
+func (q *Queries) SearchFeedback(ctx context.Context, arg SearchFeedbackParams) ([]SearchFeedbackRow, error) {
+	rows, err := q.db.Query(ctx, searchFeedback,
+		arg.Query,
+		arg.Sentiment,
+		arg.RangeStart,
+		arg.RangeEnd,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchFeedbackRow
+	for rows.Next() {
+		var i SearchFeedbackRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Sentiment,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchFeedbackWithConfig = `-- name: SearchFeedbackWithConfig :many
+SELECT
+    id,
+    created_at,
+    sentiment,
+    ts_rank_cd(to_tsvector($1::text::regconfig, COALESCE(message, '')), query)::real AS rank,
+    ts_headline($1::text::regconfig, COALESCE(message, ''), query,
+        'StartSel=**, StopSel=**, MaxFragments=2, MaxWords=20, MinWords=5, FragmentDelimiter=" ... "')::text AS snippet
+FROM feedback, websearch_to_tsquery($1::text::regconfig, $2::text) AS query
+WHERE to_tsvector($1::text::regconfig, COALESCE(message, '')) @@ query
+  AND ($3::sentiment_type IS NULL OR sentiment = $3::sentiment_type)
+  AND ($4::timestamptz IS NULL OR created_at >= $4::timestamptz)
+  AND ($5::timestamptz IS NULL OR created_at < $5::timestamptz)
+ORDER BY rank DESC, created_at DESC
+LIMIT $6
+`
+
+type SearchFeedbackWithConfigParams struct {
+	Config     string
+	Query      string
+	Sentiment  NullSentimentType
+	RangeStart pgtype.Timestamptz
+	RangeEnd   pgtype.Timestamptz
+	MaxResults int32
+}
+
+type SearchFeedbackWithConfigRow struct {
+	ID        int32
+	CreatedAt pgtype.Timestamptz
+	Sentiment SentimentType
+	Rank      float32
+	Snippet   string
+}
+
+// Full-text search like SearchFeedback with another text search configuration (e.g., german).
+// The message is parsed on the fly, which can't use the GIN index, narrow the search with the date filters.
+func (q *Queries) SearchFeedbackWithConfig(ctx context.Context, arg SearchFeedbackWithConfigParams) ([]SearchFeedbackWithConfigRow, error) {
+	rows, err := q.db.Query(ctx, searchFeedbackWithConfig,
+		arg.Config,
+		arg.Query,
+		arg.Sentiment,
+		arg.RangeStart,
+		arg.RangeEnd,
+		arg.MaxResults,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchFeedbackWithConfigRow
+	for rows.Next() {
+		var i SearchFeedbackWithConfigRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.Sentiment,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const textSearchConfigExists = `-- name: TextSearchConfigExists :one
+SELECT (to_regconfig($1::text) IS NOT NULL)::boolean AS config_exists
+`
+
+// Checks whether a text search configuration (e.g., english, german, simple) is installed.
+// Used to reject unknown configurations before they are cast to regconfig, which fails the query.
+func (q *Queries) TextSearchConfigExists(ctx context.Context, name string) (bool, error) {
+	row := q.db.QueryRow(ctx, textSearchConfigExists, name)
+	var config_exists bool
+	err := row.Scan(&config_exists)
+	return config_exists, err
+}
@@ -0,0 +1,93 @@
+package partition
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db/dbtest"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// createOldPartitions creates an attached partition of 2020-01 and a detached one of 2020-02
+func createOldPartitions(t *testing.T, pool *pgxpool.Pool) {
+	t.Helper()
+
+	ctx := context.Background()
+
+	for _, sql := range []string{
+		`CREATE TABLE feedback_y2020m01 PARTITION OF feedback
+			FOR VALUES FROM ('2020-01-01T00:00:00Z') TO ('2020-02-01T00:00:00Z')`,
+		// Left behind by an earlier run that detached it without dropping it
+		`CREATE TABLE feedback_y2020m02 PARTITION OF feedback
+			FOR VALUES FROM ('2020-02-01T00:00:00Z') TO ('2020-03-01T00:00:00Z')`,
+		`ALTER TABLE feedback DETACH PARTITION feedback_y2020m02`,
+	} {
+		if _, err := pool.Exec(ctx, sql); err != nil {
+			t.Fatalf("Failed to create old partitions: %v", err)
+		}
+	}
+
+	t.Cleanup(func() {
+		if _, err := pool.Exec(context.Background(),
+			"DROP TABLE IF EXISTS feedback_y2020m01, feedback_y2020m02"); err != nil {
+			t.Errorf("Failed to drop old partitions: %v", err)
+		}
+	})
+}
+
+// tableExists reports whether a table with the name exists
+func tableExists(t *testing.T, pool *pgxpool.Pool, name string) bool {
+	t.Helper()
+
+	var exists bool
+	if err := pool.QueryRow(context.Background(),
+		"SELECT to_regclass($1) IS NOT NULL", name).Scan(&exists); err != nil {
+		t.Fatalf("Failed to check table %s: %v", name, err)
+	}
+
+	return exists
+}
+
+func TestMaintainer_Maintain_DropsExpired(t *testing.T) {
+	tests := []struct {
+		name         string
+		keepDetached bool
+		expectKept   bool
+	}{
+		{name: "drops expired and left over partitions"},
+		{name: "keeps detached partitions", keepDetached: true, expectKept: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := dbtest.New(t)
+			createOldPartitions(t, pool)
+
+			m, err := New(Config{Pool: pool, OlderThan: 365 * 24 * time.Hour, KeepDetached: tt.keepDetached})
+			if err != nil {
+				t.Fatalf("New failed: %v", err)
+			}
+
+			result, err := m.Maintain(context.Background())
+			if err != nil {
+				t.Fatalf("Maintain failed: %v", err)
+			}
+
+			if !slices.Contains(result.Detached, "feedback_y2020m01") {
+				t.Errorf("Expected feedback_y2020m01 to be detached, got %+v", result)
+			}
+
+			for _, name := range []string{"feedback_y2020m01", "feedback_y2020m02"} {
+				if exists := tableExists(t, pool, name); exists != tt.expectKept {
+					t.Errorf("Expected table %s to exist %v, got %v", name, tt.expectKept, exists)
+				}
+
+				if dropped := slices.Contains(result.Dropped, name); dropped == tt.expectKept {
+					t.Errorf("Expected %s in dropped partitions %v, got %+v", name, !tt.expectKept, result.Dropped)
+				}
+			}
+		})
+	}
+}
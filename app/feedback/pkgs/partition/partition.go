@@ -0,0 +1,380 @@
+// Package partition maintains the monthly partitions of the feedback table.
+package partition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/findmyname666/ddg3/feedback/pkgs/retention"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// DefaultPremake is the number of future months with a partition
+	DefaultPremake = 3
+
+	// DefaultLockTimeout bounds how long a DDL statement waits for its lock on the feedback table
+	DefaultLockTimeout = 5 * time.Second
+
+	// namePrefix and nameLayout form partition names such as feedback_y2026m03
+	namePrefix = "feedback_"
+	nameLayout = `feedback_y2006m01`
+)
+
+// ErrUnreportedPeriods is returned by Maintain when an expired partition contains unreported feedback
+var ErrUnreportedPeriods = errors.New("partitions with unreported feedback were kept")
+
+// Partition is a monthly partition of the feedback table covering [Start, End)
+type Partition struct {
+	Name  string
+	Start time.Time
+	End   time.Time
+}
+
+// Config holds the configuration of the partition maintenance
+type Config struct {
+	Pool *pgxpool.Pool
+
+	// Premake is the number of months after the current one with a partition
+	// Defaults to DefaultPremake when zero
+	Premake int
+
+	// OlderThan is the retention period, partitions ending before now minus OlderThan expire
+	// Zero keeps all partitions
+	OlderThan time.Duration
+
+	// KeepDetached only detaches expired partitions and keeps them as standalone tables, e.g., for archiving.
+	// They are never dropped by later runs, archiving and dropping them is up to the operator.
+	// Expired partitions are dropped by default.
+	KeepDetached bool
+
+	// LockTimeout bounds how long a DDL statement waits for its lock on the feedback table
+	// Defaults to DefaultLockTimeout when zero
+	LockTimeout time.Duration
+
+	// DryRun only logs the planned changes
+	DryRun bool
+}
+
+// Result describes the changes made by a maintenance run
+type Result struct {
+	Created  []string
+	Detached []string
+	Dropped  []string
+	// Kept lists expired partitions that contain feedback without a completed report run
+	Kept []string
+}
+
+// Maintainer creates future partitions and detaches or drops expired ones
+type Maintainer struct {
+	pool         *pgxpool.Pool
+	queries      *db.Queries
+	premake      int
+	olderThan    time.Duration
+	keepDetached bool
+	lockTimeout  time.Duration
+	dryRun       bool
+	now          func() time.Time
+}
+
+// New creates a new partition maintainer instance
+func New(cfg Config) (*Maintainer, error) {
+	premake := cfg.Premake
+	if premake == 0 {
+		premake = DefaultPremake
+	}
+
+	if premake < 0 {
+		return nil, fmt.Errorf("premake must not be negative, got %d", premake)
+	}
+
+	if cfg.OlderThan < 0 {
+		return nil, fmt.Errorf("retention period must not be negative, got %s", cfg.OlderThan)
+	}
+
+	lockTimeout := cfg.LockTimeout
+	if lockTimeout <= 0 {
+		lockTimeout = DefaultLockTimeout
+	}
+
+	return &Maintainer{
+		pool:         cfg.Pool,
+		queries:      db.New(cfg.Pool),
+		premake:      premake,
+		olderThan:    cfg.OlderThan,
+		keepDetached: cfg.KeepDetached,
+		lockTimeout:  lockTimeout,
+		dryRun:       cfg.DryRun,
+		now:          time.Now,
+	}, nil
+}
+
+// Maintain creates the partitions of the current and the next Premake months and detaches and drops
+// partitions that ended before the retention cutoff. Expired partitions detached by an earlier run but not
+// dropped are dropped as well, unless detached partitions are kept.
+// Partitions containing feedback without a completed report run are kept, they are listed in the result
+// and ErrUnreportedPeriods is returned after all other changes were made.
+func (m *Maintainer) Maintain(ctx context.Context) (*Result, error) {
+	rows, err := m.queries.ListFeedbackPartitions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions: %w", err)
+	}
+
+	attached := make(map[string]bool, len(rows))
+
+	var existing []Partition
+
+	for _, row := range rows {
+		attached[row.Name] = true
+
+		p, ok := parseName(row.Name)
+		if !ok {
+			slog.Warn("Ignoring feedback partition with unexpected name", "partition", row.Name)
+
+			continue
+		}
+
+		// A previous concurrent detach was interrupted, it must be finalized before anything else
+		if row.DetachPending {
+			if err := m.finalizeDetach(ctx, p); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		existing = append(existing, p)
+	}
+
+	now := m.now().UTC()
+	result := &Result{}
+
+	for _, p := range missing(attached, now, m.premake) {
+		if err := m.create(ctx, p); err != nil {
+			return result, err
+		}
+
+		result.Created = append(result.Created, p.Name)
+	}
+
+	if m.olderThan == 0 {
+		return result, nil
+	}
+
+	cutoff := now.Add(-m.olderThan)
+
+	if err := m.dropDetached(ctx, cutoff, result); err != nil {
+		return result, err
+	}
+
+	expiredPartitions := expired(existing, cutoff)
+	if len(expiredPartitions) == 0 {
+		return result, nil
+	}
+
+	unreported, err := retention.UnreportedPeriods(ctx, m.queries, cutoff)
+	if err != nil {
+		return result, err
+	}
+
+	for _, p := range expiredPartitions {
+		if overlaps(p, unreported) {
+			slog.Warn("Keeping expired partition with feedback without a completed report run",
+				"partition", p.Name)
+
+			result.Kept = append(result.Kept, p.Name)
+
+			continue
+		}
+
+		if err := m.detach(ctx, p); err != nil {
+			return result, err
+		}
+
+		result.Detached = append(result.Detached, p.Name)
+
+		if m.keepDetached {
+			continue
+		}
+
+		if err := m.dropTable(ctx, p); err != nil {
+			return result, err
+		}
+
+		result.Dropped = append(result.Dropped, p.Name)
+	}
+
+	if len(result.Kept) > 0 {
+		return result, fmt.Errorf("%w: %v", ErrUnreportedPeriods, result.Kept)
+	}
+
+	return result, nil
+}
+
+// dropDetached drops the expired partitions that were detached but not dropped
+func (m *Maintainer) dropDetached(ctx context.Context, cutoff time.Time, result *Result) error {
+	if m.keepDetached {
+		return nil
+	}
+
+	names, err := m.queries.ListDetachedFeedbackPartitions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list detached partitions: %w", err)
+	}
+
+	for _, name := range names {
+		p, ok := parseName(name)
+		if !ok || len(expired([]Partition{p}, cutoff)) == 0 {
+			continue
+		}
+
+		if err := m.dropTable(ctx, p); err != nil {
+			return err
+		}
+
+		result.Dropped = append(result.Dropped, p.Name)
+	}
+
+	return nil
+}
+
+// create creates the partition unless it already exists
+func (m *Maintainer) create(ctx context.Context, p Partition) error {
+	sql := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF feedback FOR VALUES FROM ('%s') TO ('%s')",
+		pgx.Identifier{p.Name}.Sanitize(), p.Start.Format(time.RFC3339), p.End.Format(time.RFC3339))
+
+	return m.exec(ctx, "Creating partition", p, sql)
+}
+
+// detach detaches the partition without blocking queries on the feedback table
+func (m *Maintainer) detach(ctx context.Context, p Partition) error {
+	sql := fmt.Sprintf("ALTER TABLE feedback DETACH PARTITION %s CONCURRENTLY", pgx.Identifier{p.Name}.Sanitize())
+
+	return m.exec(ctx, "Detaching partition", p, sql)
+}
+
+// finalizeDetach completes an interrupted concurrent detach
+func (m *Maintainer) finalizeDetach(ctx context.Context, p Partition) error {
+	sql := fmt.Sprintf("ALTER TABLE feedback DETACH PARTITION %s FINALIZE", pgx.Identifier{p.Name}.Sanitize())
+
+	return m.exec(ctx, "Finalizing interrupted partition detach", p, sql)
+}
+
+// dropTable drops a detached partition
+func (m *Maintainer) dropTable(ctx context.Context, p Partition) error {
+	sql := fmt.Sprintf("DROP TABLE IF EXISTS %s", pgx.Identifier{p.Name}.Sanitize())
+
+	return m.exec(ctx, "Dropping partition", p, sql)
+}
+
+// exec runs a DDL statement on a dedicated connection with the lock timeout, or only logs it in dry-run mode.
+// DETACH PARTITION CONCURRENTLY can't run in a transaction, so the timeout is set on the session.
+func (m *Maintainer) exec(ctx context.Context, msg string, p Partition, sql string) error {
+	slog.Info(msg,
+		"partition", p.Name,
+		"start", p.Start.Format(time.RFC3339),
+		"end", p.End.Format(time.RFC3339),
+		"dry_run", m.dryRun)
+
+	if m.dryRun {
+		return nil
+	}
+
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, fmt.Sprintf("SET lock_timeout = %d", m.lockTimeout.Milliseconds())); err != nil {
+		return fmt.Errorf("failed to set lock timeout: %w", err)
+	}
+
+	// Don't return the connection to the pool with the modified setting
+	defer func() {
+		if _, err := conn.Exec(context.WithoutCancel(ctx), "RESET lock_timeout"); err != nil {
+			slog.Warn("Failed to reset lock timeout, closing connection", "error", err)
+
+			if err := conn.Conn().Close(context.WithoutCancel(ctx)); err != nil {
+				slog.Warn("Failed to close connection", "error", err)
+			}
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, sql); err != nil {
+		return fmt.Errorf("failed to execute %q: %w", sql, err)
+	}
+
+	return nil
+}
+
+// monthPartition returns the partition containing t
+func monthPartition(t time.Time) Partition {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	return Partition{
+		Name:  start.Format(nameLayout),
+		Start: start,
+		End:   start.AddDate(0, 1, 0),
+	}
+}
+
+// parseName returns the partition with the given name, false if the name doesn't follow the naming scheme
+func parseName(name string) (Partition, bool) {
+	if len(name) != len(nameLayout) || name[:len(namePrefix)] != namePrefix {
+		return Partition{}, false
+	}
+
+	start, err := time.Parse(nameLayout, name)
+	if err != nil {
+		return Partition{}, false
+	}
+
+	return monthPartition(start), true
+}
+
+// missing returns the partitions of the current and the next premake months that aren't attached
+func missing(attached map[string]bool, now time.Time, premake int) []Partition {
+	var partitions []Partition
+
+	current := monthPartition(now)
+
+	for i := range premake + 1 {
+		p := monthPartition(current.Start.AddDate(0, i, 0))
+		if !attached[p.Name] {
+			partitions = append(partitions, p)
+		}
+	}
+
+	return partitions
+}
+
+// expired returns the partitions that ended on or before the cutoff
+func expired(partitions []Partition, cutoff time.Time) []Partition {
+	var result []Partition
+
+	for _, p := range partitions {
+		if !p.End.After(cutoff) {
+			result = append(result, p)
+		}
+	}
+
+	return result
+}
+
+// overlaps reports whether the partition overlaps any of the periods
+func overlaps(p Partition, periods []retention.Period) bool {
+	for _, period := range periods {
+		if period.Start.Before(p.End) && period.End.After(p.Start) {
+			return true
+		}
+	}
+
+	return false
+}
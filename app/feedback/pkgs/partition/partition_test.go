@@ -0,0 +1,199 @@
+package partition
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/retention"
+)
+
+func TestMonthPartition(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    time.Time
+		expected Partition
+	}{
+		{
+			name:  "middle of the month",
+			input: time.Date(2026, time.March, 15, 10, 30, 0, 0, time.UTC),
+			expected: Partition{
+				Name:  "feedback_y2026m03",
+				Start: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "december",
+			input: time.Date(2026, time.December, 31, 23, 59, 59, 0, time.UTC),
+			expected: Partition{
+				Name:  "feedback_y2026m12",
+				Start: time.Date(2026, time.December, 1, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			// Partitions cover UTC months, 2026-04-01 01:00 in UTC+2 is still March
+			name:  "non-UTC time",
+			input: time.Date(2026, time.April, 1, 1, 0, 0, 0, time.FixedZone("CEST", 2*60*60)),
+			expected: Partition{
+				Name:  "feedback_y2026m03",
+				Start: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2026, time.April, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if p := monthPartition(tt.input); !reflect.DeepEqual(p, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, p)
+			}
+		})
+	}
+}
+
+func TestParseName(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		ok       bool
+	}{
+		{name: "feedback_y2026m03", expected: "2026-03-01", ok: true},
+		{name: "feedback_y1999m12", expected: "1999-12-01", ok: true},
+		{name: "feedback_y2026m13"},
+		{name: "feedback_y2026m3"},
+		{name: "feedback_default"},
+		{name: "feedback_unpartitioned"},
+		{name: "other_y2026m03"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := parseName(tt.name)
+			if ok != tt.ok {
+				t.Fatalf("Expected ok %v, got %v", tt.ok, ok)
+			}
+
+			if !ok {
+				return
+			}
+
+			if start := p.Start.Format("2006-01-02"); start != tt.expected {
+				t.Errorf("Expected start %q, got %q", tt.expected, start)
+			}
+
+			if p.Name != tt.name {
+				t.Errorf("Expected name %q, got %q", tt.name, p.Name)
+			}
+		})
+	}
+}
+
+func TestMissing(t *testing.T) {
+	now := time.Date(2026, time.November, 20, 0, 0, 0, 0, time.UTC)
+	attached := map[string]bool{
+		"feedback_y2026m10": true,
+		"feedback_y2026m11": true,
+		"feedback_y2027m01": true,
+	}
+
+	var names []string
+	for _, p := range missing(attached, now, 3) {
+		names = append(names, p.Name)
+	}
+
+	expected := []string{"feedback_y2026m12", "feedback_y2027m02"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	partitions := []Partition{
+		monthPartition(time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)),
+		monthPartition(time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)),
+		monthPartition(time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)),
+	}
+
+	// The March partition still contains feedback newer than the cutoff
+	cutoff := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	var names []string
+	for _, p := range expired(partitions, cutoff) {
+		names = append(names, p.Name)
+	}
+
+	expected := []string{"feedback_y2026m01", "feedback_y2026m02"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("Expected %v, got %v", expected, names)
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	p := monthPartition(time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name     string
+		period   retention.Period
+		expected bool
+	}{
+		{
+			name: "inside",
+			period: retention.Period{
+				Start: time.Date(2026, time.February, 10, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2026, time.February, 11, 0, 0, 0, 0, time.UTC),
+			},
+			expected: true,
+		},
+		{
+			name: "ends at the partition start",
+			period: retention.Period{
+				Start: time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "starts at the partition end",
+			period: retention.Period{
+				Start: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name: "spans the partition",
+			period: retention.Period{
+				Start: time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC),
+				End:   time.Date(2026, time.March, 2, 0, 0, 0, 0, time.UTC),
+			},
+			expected: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overlaps(p, []retention.Period{tt.period}); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	if _, err := New(Config{Premake: -1}); err == nil {
+		t.Error("Expected error for negative premake")
+	}
+
+	if _, err := New(Config{OlderThan: -time.Hour}); err == nil {
+		t.Error("Expected error for negative retention period")
+	}
+
+	m, err := New(Config{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if m.premake != DefaultPremake || m.lockTimeout != DefaultLockTimeout {
+		t.Errorf("Expected defaults, got premake %d and lock timeout %s", m.premake, m.lockTimeout)
+	}
+}
@@ -0,0 +1,334 @@
+// Package retention deletes feedback older than the retention period.
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultBatchSize is the maximum number of rows deleted per statement
+const DefaultBatchSize = 1000
+
+// ErrUnreportedPeriods is returned by Run when feedback older than the cutoff wasn't reported yet
+var ErrUnreportedPeriods = errors.New("feedback from unreported periods was not deleted")
+
+// Config holds the configuration of the retention job
+type Config struct {
+	Pool *pgxpool.Pool
+
+	// OlderThan is the retention period, feedback created before now minus OlderThan is deleted
+	OlderThan time.Duration
+
+	// BatchSize is the maximum number of rows deleted per statement
+	// Defaults to DefaultBatchSize when zero
+	BatchSize int32
+
+	// BatchPause is the delay between two batches, gives autovacuum and replicas time to catch up
+	BatchPause time.Duration
+
+	// DryRun only counts the feedback that would be deleted
+	DryRun bool
+}
+
+// Period is a time range [Start, End)
+type Period struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// Result describes the outcome of a retention run
+type Result struct {
+	// Cutoff is the requested cutoff, now minus the retention period
+	Cutoff time.Time
+	// EffectiveCutoff is the cutoff used for deletion, the start of the first unreported period when
+	// older feedback wasn't reported yet
+	EffectiveCutoff time.Time
+	// Unreported lists periods before the cutoff with feedback but without a completed report run
+	Unreported []Period
+
+	PositiveCount int64
+	NegativeCount int64
+	Deleted       int64
+	Batches       int
+}
+
+// Total returns the number of feedback records eligible for deletion
+func (r *Result) Total() int64 {
+	return r.PositiveCount + r.NegativeCount
+}
+
+// Retention deletes old feedback in bounded batches
+type Retention struct {
+	queries    *db.Queries
+	olderThan  time.Duration
+	batchSize  int32
+	batchPause time.Duration
+	dryRun     bool
+	now        func() time.Time
+}
+
+// New creates a new retention job instance
+func New(cfg Config) (*Retention, error) {
+	if cfg.OlderThan <= 0 {
+		return nil, fmt.Errorf("retention period must be positive, got %s", cfg.OlderThan)
+	}
+
+	batchSize := cfg.BatchSize
+	if batchSize == 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	if batchSize < 0 {
+		return nil, fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+
+	return &Retention{
+		queries:    db.New(cfg.Pool),
+		olderThan:  cfg.OlderThan,
+		batchSize:  batchSize,
+		batchPause: cfg.BatchPause,
+		dryRun:     cfg.DryRun,
+		now:        time.Now,
+	}, nil
+}
+
+// Run deletes feedback created before the cutoff.
+// Feedback is only deleted up to the first period without a completed report run, in that case the
+// periods are listed in the result and ErrUnreportedPeriods is returned after the deletion.
+func (r *Retention) Run(ctx context.Context) (*Result, error) {
+	cutoff := r.now().UTC().Add(-r.olderThan)
+	result := &Result{Cutoff: cutoff, EffectiveCutoff: cutoff}
+
+	oldest, err := r.queries.GetOldestFeedbackTime(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oldest feedback: %w", err)
+	}
+
+	if !oldest.Valid || !oldest.Time.Before(cutoff) {
+		slog.Info("No feedback older than the cutoff",
+			"cutoff", cutoff.Format(time.RFC3339))
+
+		return result, nil
+	}
+
+	unreported, err := unreportedPeriods(ctx, r.queries, oldest.Time, cutoff)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Unreported = unreported
+	if len(unreported) > 0 {
+		result.EffectiveCutoff = unreported[0].Start
+	}
+
+	for _, period := range unreported {
+		slog.Warn("Keeping feedback from a period without a completed report run",
+			"start", period.Start.Format(time.RFC3339),
+			"end", period.End.Format(time.RFC3339))
+	}
+
+	counts, err := r.queries.CountFeedbackBySentiment(ctx, db.CountFeedbackBySentimentParams{
+		CreatedAt:   pgtype.Timestamptz{Time: oldest.Time, Valid: true},
+		CreatedAt_2: pgtype.Timestamptz{Time: result.EffectiveCutoff, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count feedback: %w", err)
+	}
+
+	result.PositiveCount = counts.PositiveCount
+	result.NegativeCount = counts.NegativeCount
+
+	slog.Info("Feedback eligible for deletion",
+		"cutoff", result.EffectiveCutoff.Format(time.RFC3339),
+		"oldest", oldest.Time.Format(time.RFC3339),
+		"positive_count", result.PositiveCount,
+		"negative_count", result.NegativeCount,
+		"dry_run", r.dryRun)
+
+	if !r.dryRun && result.Total() > 0 {
+		if err := r.deleteBatches(ctx, result); err != nil {
+			return result, err
+		}
+	}
+
+	if len(unreported) > 0 {
+		return result, fmt.Errorf("%w: %d periods, the oldest starts at %s", ErrUnreportedPeriods,
+			len(unreported), unreported[0].Start.Format(time.RFC3339))
+	}
+
+	return result, nil
+}
+
+// UnreportedPeriods returns the periods before the cutoff that contain feedback but aren't covered by a
+// completed report run, oldest first
+func UnreportedPeriods(ctx context.Context, queries *db.Queries, cutoff time.Time) ([]Period, error) {
+	oldest, err := queries.GetOldestFeedbackTime(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oldest feedback: %w", err)
+	}
+
+	if !oldest.Valid || !oldest.Time.Before(cutoff) {
+		return nil, nil
+	}
+
+	return unreportedPeriods(ctx, queries, oldest.Time, cutoff)
+}
+
+// unreportedPeriods returns the periods in [from, to) that contain feedback but aren't covered by a completed
+// report run
+func unreportedPeriods(ctx context.Context, queries *db.Queries, from, to time.Time) ([]Period, error) {
+	runs, err := queries.ListCompletedReportRunsInTimeRange(ctx, db.ListCompletedReportRunsInTimeRangeParams{
+		RangeStart: pgtype.Timestamptz{Time: from, Valid: true},
+		RangeEnd:   pgtype.Timestamptz{Time: to, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list report runs: %w", err)
+	}
+
+	var unreported []Period
+
+	for _, gap := range uncoveredPeriods(runs, from, to) {
+		counts, err := queries.CountFeedbackBySentiment(ctx, db.CountFeedbackBySentimentParams{
+			CreatedAt:   pgtype.Timestamptz{Time: gap.Start, Valid: true},
+			CreatedAt_2: pgtype.Timestamptz{Time: gap.End, Valid: true},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to count feedback: %w", err)
+		}
+
+		// Periods without feedback, e.g. days skipped by the analysis job, don't block the deletion
+		if counts.PositiveCount+counts.NegativeCount > 0 {
+			unreported = append(unreported, gap)
+		}
+	}
+
+	return unreported, nil
+}
+
+// deleteBatches deletes feedback created before the effective cutoff, one batch per statement
+func (r *Retention) deleteBatches(ctx context.Context, result *Result) error {
+	params := db.DeleteOldFeedbackParams{
+		Cutoff:    pgtype.Timestamptz{Time: result.EffectiveCutoff, Valid: true},
+		BatchSize: r.batchSize,
+	}
+
+	for {
+		deleted, err := r.queries.DeleteOldFeedback(ctx, params)
+		if err != nil {
+			return fmt.Errorf("failed to delete feedback after %d rows: %w", result.Deleted, err)
+		}
+
+		result.Deleted += deleted
+		result.Batches++
+
+		slog.Debug("Deleted feedback batch",
+			"batch", result.Batches,
+			"rows", deleted,
+			"total", result.Deleted)
+
+		if deleted < int64(r.batchSize) {
+			return nil
+		}
+
+		if err := sleep(ctx, r.batchPause); err != nil {
+			return fmt.Errorf("retention interrupted after %d rows: %w", result.Deleted, err)
+		}
+	}
+}
+
+// uncoveredPeriods returns the parts of [from, to) not covered by the windows of the report runs.
+// The runs must be ordered by window start.
+func uncoveredPeriods(runs []db.ReportRun, from, to time.Time) []Period {
+	var periods []Period
+
+	cursor := from
+
+	for _, run := range runs {
+		if !cursor.Before(to) {
+			break
+		}
+
+		if !run.WindowStart.Valid || !run.WindowEnd.Valid {
+			continue
+		}
+
+		if start := run.WindowStart.Time; start.After(cursor) {
+			periods = append(periods, Period{Start: cursor, End: minTime(start, to)})
+		}
+
+		if end := run.WindowEnd.Time; end.After(cursor) {
+			cursor = end
+		}
+	}
+
+	if cursor.Before(to) {
+		periods = append(periods, Period{Start: cursor, End: to})
+	}
+
+	return periods
+}
+
+// ParseOlderThan parses a retention period, either a number of days with a "d" suffix (e.g., "180d")
+// or a Go duration (e.g., "36h")
+func ParseOlderThan(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+
+	var (
+		d   time.Duration
+		err error
+	)
+
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		var n int
+
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		d, err = time.ParseDuration(s)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid retention period %q, expected e.g. 180d or 36h", s)
+	}
+
+	if d <= 0 {
+		return 0, fmt.Errorf("retention period must be positive, got %q", s)
+	}
+
+	return d, nil
+}
+
+// minTime returns the earlier of two times
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+
+	return b
+}
+
+// sleep waits for d or until ctx is canceled
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
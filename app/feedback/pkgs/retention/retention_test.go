@@ -0,0 +1,162 @@
+package retention
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// day returns midnight UTC of the given day in June 2024
+func day(d int) time.Time {
+	return time.Date(2024, time.June, d, 0, 0, 0, 0, time.UTC)
+}
+
+// reportRun returns a completed report run covering [start, end)
+func reportRun(start, end time.Time) db.ReportRun {
+	return db.ReportRun{
+		WindowStart: pgtype.Timestamptz{Time: start, Valid: true},
+		WindowEnd:   pgtype.Timestamptz{Time: end, Valid: true},
+		Status:      db.ReportStatusCompleted,
+	}
+}
+
+func TestUncoveredPeriods(t *testing.T) {
+	tests := []struct {
+		name     string
+		runs     []db.ReportRun
+		from     time.Time
+		to       time.Time
+		expected []Period
+	}{
+		{
+			name:     "no report runs",
+			from:     day(1),
+			to:       day(5),
+			expected: []Period{{Start: day(1), End: day(5)}},
+		},
+		{
+			name: "fully covered",
+			runs: []db.ReportRun{
+				reportRun(day(1), day(2)),
+				reportRun(day(2), day(3)),
+				reportRun(day(3), day(4)),
+			},
+			from: day(1).Add(6 * time.Hour),
+			to:   day(4),
+		},
+		{
+			name: "missing day in between",
+			runs: []db.ReportRun{
+				reportRun(day(1), day(2)),
+				reportRun(day(3), day(4)),
+			},
+			from:     day(1),
+			to:       day(4),
+			expected: []Period{{Start: day(2), End: day(3)}},
+		},
+		{
+			name: "before the first and after the last run",
+			runs: []db.ReportRun{
+				reportRun(day(2), day(3)),
+			},
+			from: day(1).Add(12 * time.Hour),
+			to:   day(4).Add(12 * time.Hour),
+			expected: []Period{
+				{Start: day(1).Add(12 * time.Hour), End: day(2)},
+				{Start: day(3), End: day(4).Add(12 * time.Hour)},
+			},
+		},
+		{
+			name: "cutoff inside a reported window",
+			runs: []db.ReportRun{
+				reportRun(day(1), day(2)),
+				reportRun(day(2), day(3)),
+			},
+			from: day(1),
+			to:   day(2).Add(6 * time.Hour),
+		},
+		{
+			name: "multi-day catch up window overlapping a daily window",
+			runs: []db.ReportRun{
+				reportRun(day(1), day(4)),
+				reportRun(day(2), day(3)),
+				reportRun(day(5), day(6)),
+			},
+			from:     day(1),
+			to:       day(6),
+			expected: []Period{{Start: day(4), End: day(5)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			periods := uncoveredPeriods(tt.runs, tt.from, tt.to)
+			if !reflect.DeepEqual(periods, tt.expected) {
+				t.Errorf("Expected periods %v, got %v", tt.expected, periods)
+			}
+		})
+	}
+}
+
+func TestParseOlderThan(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{input: "180d", expected: 180 * 24 * time.Hour},
+		{input: "1d", expected: 24 * time.Hour},
+		{input: " 30d ", expected: 30 * 24 * time.Hour},
+		{input: "36h", expected: 36 * time.Hour},
+		{input: "0d", wantErr: true},
+		{input: "-5d", wantErr: true},
+		{input: "-1h", wantErr: true},
+		{input: "d", wantErr: true},
+		{input: "1.5d", wantErr: true},
+		{input: "six months", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d, err := ParseOlderThan(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %s", tt.input, d)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if d != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, d)
+			}
+		})
+	}
+}
+
+func TestNew_Validation(t *testing.T) {
+	if _, err := New(Config{OlderThan: 0}); err == nil {
+		t.Error("Expected error for zero retention period")
+	}
+
+	if _, err := New(Config{OlderThan: time.Hour, BatchSize: -1}); err == nil {
+		t.Error("Expected error for negative batch size")
+	}
+
+	r, err := New(Config{OlderThan: time.Hour})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if r.batchSize != DefaultBatchSize {
+		t.Errorf("Expected default batch size %d, got %d", DefaultBatchSize, r.batchSize)
+	}
+}
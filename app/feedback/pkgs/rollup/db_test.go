@@ -0,0 +1,83 @@
+package rollup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/findmyname666/ddg3/feedback/pkgs/db/dbtest"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestRollup_VerifyKeepsDeletedHours(t *testing.T) {
+	pool := dbtest.New(t)
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Hour)
+	deleted := now.AddDate(0, 0, -3)
+	oldest := now.AddDate(0, 0, -2)
+	dropped := now.AddDate(0, 0, -1).Add(-6 * time.Hour)
+	recent := now.AddDate(0, 0, -1)
+
+	for _, createdAt := range []time.Time{deleted, oldest, dropped, recent, recent} {
+		if _, err := pool.Exec(ctx, "INSERT INTO feedback (created_at, sentiment) VALUES ($1, 'positive')",
+			createdAt.Add(10*time.Minute)); err != nil {
+			t.Fatalf("Failed to insert feedback: %v", err)
+		}
+	}
+
+	r := New(Config{Pool: pool})
+
+	if _, err := r.Run(ctx); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	// Retention deleted the oldest hour, a dropped partition a later one, the recent hour was rolled up wrongly
+	if _, err := pool.Exec(ctx,
+		"DELETE FROM feedback WHERE created_at < $1 OR date_trunc('hour', created_at, 'UTC') = $2",
+		oldest, dropped); err != nil {
+		t.Fatalf("Failed to delete feedback: %v", err)
+	}
+
+	if _, err := pool.Exec(ctx,
+		"UPDATE feedback_hourly_stats SET positive_count = 99 WHERE hour = $1", recent); err != nil {
+		t.Fatalf("Failed to update hourly stats: %v", err)
+	}
+
+	mismatches, err := r.Verify(ctx, deleted.AddDate(0, 0, -1), now, true)
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+
+	if len(mismatches) != 1 || !mismatches[0].Hour.Equal(recent) {
+		t.Fatalf("Expected a mismatch of hour %s only, got %+v", recent, mismatches)
+	}
+
+	stats, err := r.queries.ListFeedbackHourlyStats(ctx, db.ListFeedbackHourlyStatsParams{
+		Hour:   pgtype.Timestamptz{Time: deleted, Valid: true},
+		Hour_2: pgtype.Timestamptz{Time: now, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("Failed to list hourly stats: %v", err)
+	}
+
+	// The counts of the deleted hours are kept
+	expected := []struct {
+		hour     time.Time
+		positive int32
+	}{{deleted, 1}, {oldest, 1}, {dropped, 1}, {recent, 2}}
+	if len(stats) != len(expected) {
+		t.Fatalf("Expected %d hours, got %+v", len(expected), stats)
+	}
+
+	for i, e := range expected {
+		if !stats[i].Hour.Time.Equal(e.hour) || stats[i].PositiveCount != e.positive {
+			t.Errorf("Expected %d positive in hour %s, got %+v", e.positive, e.hour, stats[i])
+		}
+	}
+
+	if mismatches, err := r.Verify(ctx, deleted.AddDate(0, 0, -1), now, false); err != nil || mismatches != nil {
+		t.Errorf("Expected the rollup to match after the fix, got %+v (err: %v)", mismatches, err)
+	}
+}
@@ -0,0 +1,484 @@
+// Package rollup maintains the hourly feedback counts in feedback_hourly_stats.
+package rollup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// Name identifies the hourly rollup in feedback_rollup_watermarks
+	Name = "feedback_hourly_stats"
+
+	// DefaultSettle is how long after the end of an hour it's rolled up. Feedback committed later isn't
+	// counted by the rollup until the hour is recomputed by Refresh or Verify with fix.
+	DefaultSettle = 5 * time.Minute
+
+	// DefaultChunk is the time range rolled up per transaction
+	DefaultChunk = 7 * 24 * time.Hour
+)
+
+// ErrMismatch is returned by Verify when the rollup doesn't match the raw feedback
+var ErrMismatch = errors.New("rollup doesn't match the raw feedback")
+
+// Config holds the configuration of the rollup
+type Config struct {
+	Pool *pgxpool.Pool
+
+	// Settle delays rolling up an hour, feedback is inserted with created_at = NOW() at transaction start,
+	// so rows of an hour may become visible shortly after it ended
+	// Defaults to DefaultSettle when zero
+	Settle time.Duration
+
+	// Chunk is the time range rolled up per transaction, bounding the duration of a catch-up transaction
+	// Defaults to DefaultChunk when zero
+	Chunk time.Duration
+}
+
+// Result describes the outcome of a rollup run
+type Result struct {
+	From  time.Time
+	To    time.Time
+	Hours int64
+}
+
+// Mismatch is an hour whose rollup counts differ from the raw feedback
+type Mismatch struct {
+	Hour             time.Time
+	RollupPositive   int64
+	RollupNegative   int64
+	FeedbackPositive int64
+	FeedbackNegative int64
+}
+
+// Rollup maintains feedback_hourly_stats incrementally from a high-watermark
+type Rollup struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+	settle  time.Duration
+	chunk   time.Duration
+	now     func() time.Time
+}
+
+// New creates a new rollup instance
+func New(cfg Config) *Rollup {
+	settle := cfg.Settle
+	if settle <= 0 {
+		settle = DefaultSettle
+	}
+
+	chunk := cfg.Chunk
+	if chunk < time.Hour {
+		chunk = DefaultChunk
+	}
+
+	return &Rollup{
+		pool:    cfg.Pool,
+		queries: db.New(cfg.Pool),
+		settle:  settle,
+		chunk:   chunk.Truncate(time.Hour),
+		now:     time.Now,
+	}
+}
+
+// Run rolls up all hours between the watermark and the last settled hour and advances the watermark.
+// The first run starts at the hour of the oldest feedback. Each chunk is recomputed and committed in its
+// own transaction, so an interrupted run continues where it stopped and rerunning is safe.
+func (r *Rollup) Run(ctx context.Context) (*Result, error) {
+	target := r.settledUntil()
+	result := &Result{To: target}
+
+	for {
+		done, err := r.runChunk(ctx, target, result)
+		if err != nil {
+			return result, err
+		}
+
+		if done {
+			break
+		}
+	}
+
+	if result.From.IsZero() {
+		slog.Debug("Hourly rollup is up to date",
+			"watermark", target.Format(time.RFC3339))
+
+		return result, nil
+	}
+
+	slog.Info("Rolled up hourly feedback counts",
+		"from", result.From.Format(time.RFC3339),
+		"to", result.To.Format(time.RFC3339),
+		"hours_with_feedback", result.Hours)
+
+	return result, nil
+}
+
+// runChunk rolls up the next chunk after the watermark, returns true once the watermark reached target
+func (r *Rollup) runChunk(ctx context.Context, target time.Time, result *Result) (bool, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			slog.Warn("Failed to rollback transaction", "error", err)
+		}
+	}()
+
+	queries := r.queries.WithTx(tx)
+
+	from, err := r.lockWatermark(ctx, queries)
+	if err != nil {
+		return false, err
+	}
+
+	// No feedback yet, or another run already caught up
+	if from.IsZero() || !from.Before(target) {
+		return true, nil
+	}
+
+	to := from.Add(r.chunk)
+	if to.After(target) {
+		to = target
+	}
+
+	hours, err := recompute(ctx, queries, from, to)
+	if err != nil {
+		return false, err
+	}
+
+	if err := queries.UpsertRollupWatermark(ctx, db.UpsertRollupWatermarkParams{
+		Name:      Name,
+		Watermark: pgtype.Timestamptz{Time: to, Valid: true},
+	}); err != nil {
+		return false, fmt.Errorf("failed to store rollup watermark: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit rollup: %w", err)
+	}
+
+	if result.From.IsZero() {
+		result.From = from
+	}
+
+	result.Hours += hours
+
+	slog.Debug("Rolled up chunk",
+		"from", from.Format(time.RFC3339),
+		"to", to.Format(time.RFC3339),
+		"hours_with_feedback", hours)
+
+	return !to.Before(target), nil
+}
+
+// lockWatermark returns the watermark and locks it until the end of the transaction.
+// Without a watermark the hour of the oldest feedback is returned, zero if there is no feedback.
+func (r *Rollup) lockWatermark(ctx context.Context, queries *db.Queries) (time.Time, error) {
+	watermark, err := queries.GetRollupWatermarkForUpdate(ctx, Name)
+	if err == nil {
+		return watermark.Time.UTC(), nil
+	}
+
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, fmt.Errorf("failed to get rollup watermark: %w", err)
+	}
+
+	oldest, err := queries.GetOldestFeedbackTime(ctx)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get oldest feedback: %w", err)
+	}
+
+	if !oldest.Valid {
+		return time.Time{}, nil
+	}
+
+	// Concurrent first runs both start here, the recomputation is idempotent
+	return oldest.Time.UTC().Truncate(time.Hour), nil
+}
+
+// Watermark returns the end of the rolled up range, zero if nothing was rolled up yet
+func (r *Rollup) Watermark(ctx context.Context) (time.Time, error) {
+	watermark, err := r.queries.GetRollupWatermark(ctx, Name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	}
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get rollup watermark: %w", err)
+	}
+
+	return watermark.Time.UTC(), nil
+}
+
+// Verify compares the rollup with the raw feedback counts of every hour in [from, to), limited to the rolled
+// up range. With fix, mismatched hours are recomputed from the raw feedback.
+// The rollup keeps the counts of feedback deleted by retention or a dropped partition, so hours up to the one
+// of the oldest raw feedback and rolled up hours without any raw feedback are skipped and never recomputed.
+// A dropped partition can be newer than the oldest raw feedback, partitions with unreported feedback are kept.
+func (r *Rollup) Verify(ctx context.Context, from, to time.Time, fix bool) ([]Mismatch, error) {
+	watermark, err := r.Watermark(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	oldest, err := r.queries.GetOldestFeedbackTime(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get oldest feedback: %w", err)
+	}
+
+	from = from.UTC().Truncate(time.Hour)
+	to = to.UTC().Truncate(time.Hour)
+
+	if watermark.Before(to) {
+		to = watermark
+	}
+
+	// Retention may have deleted only a part of the oldest hour
+	if !oldest.Valid {
+		to = from
+	} else if first := oldest.Time.UTC().Truncate(time.Hour).Add(time.Hour); from.Before(first) {
+		from = first
+	}
+
+	if !from.Before(to) {
+		slog.Info("Nothing to verify, the range isn't rolled up yet or has no raw feedback",
+			"watermark", watermark.Format(time.RFC3339),
+			"oldest_feedback", oldest.Time.UTC().Format(time.RFC3339))
+
+		return nil, nil
+	}
+
+	start := pgtype.Timestamptz{Time: from, Valid: true}
+	end := pgtype.Timestamptz{Time: to, Valid: true}
+
+	stats, err := r.queries.ListFeedbackHourlyStats(ctx, db.ListFeedbackHourlyStatsParams{
+		Hour:   start,
+		Hour_2: end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hourly stats: %w", err)
+	}
+
+	raw, err := r.queries.CountFeedbackByHour(ctx, db.CountFeedbackByHourParams{
+		RangeStart: start,
+		RangeEnd:   end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count feedback by hour: %w", err)
+	}
+
+	mismatches, deleted := withoutDeleted(compare(stats, raw))
+
+	slog.Info("Verified hourly rollup",
+		"from", from.Format(time.RFC3339),
+		"to", to.Format(time.RFC3339),
+		"mismatches", len(mismatches),
+		"hours_without_raw_feedback", deleted)
+
+	for _, m := range mismatches {
+		slog.Warn("Hourly rollup mismatch",
+			"hour", m.Hour.Format(time.RFC3339),
+			"rollup_positive", m.RollupPositive,
+			"rollup_negative", m.RollupNegative,
+			"feedback_positive", m.FeedbackPositive,
+			"feedback_negative", m.FeedbackNegative)
+	}
+
+	if len(mismatches) > 0 && fix {
+		if err := r.recomputeHours(ctx, mismatches); err != nil {
+			return mismatches, err
+		}
+
+		slog.Info("Recomputed mismatched hours", "hours", len(mismatches))
+
+		return mismatches, nil
+	}
+
+	if len(mismatches) > 0 {
+		return mismatches, fmt.Errorf("%w: %d hours differ", ErrMismatch, len(mismatches))
+	}
+
+	return nil, nil
+}
+
+// recomputeHours recomputes the hours of the mismatches in one transaction
+func (r *Rollup) recomputeHours(ctx context.Context, mismatches []Mismatch) error {
+	return r.withWatermarkLock(ctx, func(queries *db.Queries, _ time.Time) error {
+		for _, m := range mismatches {
+			if _, err := recompute(ctx, queries, m.Hour, m.Hour.Add(time.Hour)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Refresh recomputes the rolled up hours in [from, to) from the raw feedback, so feedback committed after
+// its hour was rolled up is counted. Hours after the watermark are left to Run, from and to must be full hours.
+// The counts of hours whose feedback was deleted are removed, refresh recent ranges only.
+func (r *Rollup) Refresh(ctx context.Context, from, to time.Time) error {
+	return r.withWatermarkLock(ctx, func(queries *db.Queries, watermark time.Time) error {
+		if watermark.Before(to) {
+			to = watermark
+		}
+
+		if !from.Before(to) {
+			return nil
+		}
+
+		hours, err := recompute(ctx, queries, from, to)
+		if err != nil {
+			return err
+		}
+
+		slog.Debug("Refreshed hourly rollup",
+			"from", from.Format(time.RFC3339),
+			"to", to.Format(time.RFC3339),
+			"hours_with_feedback", hours)
+
+		return nil
+	})
+}
+
+// withWatermarkLock runs fn in a transaction holding the watermark lock, so a concurrent run can't roll up
+// the same hours. fn gets the watermark, zero if nothing was rolled up yet.
+func (r *Rollup) withWatermarkLock(ctx context.Context, fn func(queries *db.Queries, watermark time.Time) error) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+			slog.Warn("Failed to rollback transaction", "error", err)
+		}
+	}()
+
+	queries := r.queries.WithTx(tx)
+
+	var watermark time.Time
+
+	locked, err := queries.GetRollupWatermarkForUpdate(ctx, Name)
+	switch {
+	case err == nil:
+		watermark = locked.Time.UTC()
+	case !errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("failed to lock rollup watermark: %w", err)
+	}
+
+	if err := fn(queries, watermark); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit recomputed hours: %w", err)
+	}
+
+	return nil
+}
+
+// settledUntil returns the end of the last hour that ended at least the settle delay ago
+func (r *Rollup) settledUntil() time.Time {
+	return r.now().UTC().Add(-r.settle).Truncate(time.Hour)
+}
+
+// recompute replaces the hourly counts in [from, to) with the counts of the raw feedback
+func recompute(ctx context.Context, queries *db.Queries, from, to time.Time) (int64, error) {
+	start := pgtype.Timestamptz{Time: from, Valid: true}
+	end := pgtype.Timestamptz{Time: to, Valid: true}
+
+	// Hours without feedback have no row, remove counts of feedback deleted since the last rollup
+	if err := queries.DeleteFeedbackHourlyStats(ctx, db.DeleteFeedbackHourlyStatsParams{
+		Hour:   start,
+		Hour_2: end,
+	}); err != nil {
+		return 0, fmt.Errorf("failed to delete hourly stats: %w", err)
+	}
+
+	hours, err := queries.RollupFeedbackHourlyStats(ctx, db.RollupFeedbackHourlyStatsParams{
+		RangeStart: start,
+		RangeEnd:   end,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to roll up hourly stats: %w", err)
+	}
+
+	return hours, nil
+}
+
+// withoutDeleted removes the hours without any raw feedback from the mismatches and returns their number.
+// Their feedback was deleted, recomputing them would replace the rolled up history with zeros.
+func withoutDeleted(mismatches []Mismatch) ([]Mismatch, int) {
+	kept := mismatches[:0]
+
+	for _, m := range mismatches {
+		if m.FeedbackPositive != 0 || m.FeedbackNegative != 0 {
+			kept = append(kept, m)
+		}
+	}
+
+	deleted := len(mismatches) - len(kept)
+	if len(kept) == 0 {
+		kept = nil
+	}
+
+	return kept, deleted
+}
+
+// compare returns the hours whose rollup counts differ from the raw counts, both ordered by hour
+func compare(stats []db.ListFeedbackHourlyStatsRow, raw []db.CountFeedbackByHourRow) []Mismatch {
+	byHour := make(map[int64]*Mismatch, len(stats)+len(raw))
+
+	var hours []int64
+
+	get := func(hour time.Time) *Mismatch {
+		key := hour.Unix()
+		if m, ok := byHour[key]; ok {
+			return m
+		}
+
+		m := &Mismatch{Hour: hour.UTC()}
+		byHour[key] = m
+		hours = append(hours, key)
+
+		return m
+	}
+
+	for _, s := range stats {
+		m := get(s.Hour.Time)
+		m.RollupPositive = int64(s.PositiveCount)
+		m.RollupNegative = int64(s.NegativeCount)
+	}
+
+	for _, c := range raw {
+		m := get(c.Hour.Time)
+		m.FeedbackPositive = c.PositiveCount
+		m.FeedbackNegative = c.NegativeCount
+	}
+
+	var mismatches []Mismatch
+
+	for _, key := range hours {
+		m := byHour[key]
+		if m.RollupPositive != m.FeedbackPositive || m.RollupNegative != m.FeedbackNegative {
+			mismatches = append(mismatches, *m)
+		}
+	}
+
+	slices.SortFunc(mismatches, func(a, b Mismatch) int {
+		return a.Hour.Compare(b.Hour)
+	})
+
+	return mismatches
+}
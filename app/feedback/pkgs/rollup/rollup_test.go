@@ -0,0 +1,146 @@
+package rollup
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// hour returns the given hour of 2024-06-15 in UTC
+func hour(h int) time.Time {
+	return time.Date(2024, time.June, 15, h, 0, 0, 0, time.UTC)
+}
+
+func stat(h int, positive, negative int32) db.ListFeedbackHourlyStatsRow {
+	return db.ListFeedbackHourlyStatsRow{
+		Hour:          pgtype.Timestamptz{Time: hour(h), Valid: true},
+		PositiveCount: positive,
+		NegativeCount: negative,
+	}
+}
+
+func raw(h int, positive, negative int64) db.CountFeedbackByHourRow {
+	return db.CountFeedbackByHourRow{
+		Hour:          pgtype.Timestamptz{Time: hour(h), Valid: true},
+		PositiveCount: positive,
+		NegativeCount: negative,
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		stats    []db.ListFeedbackHourlyStatsRow
+		raw      []db.CountFeedbackByHourRow
+		expected []Mismatch
+	}{
+		{
+			name:  "matching",
+			stats: []db.ListFeedbackHourlyStatsRow{stat(1, 3, 1), stat(2, 0, 4)},
+			raw:   []db.CountFeedbackByHourRow{raw(1, 3, 1), raw(2, 0, 4)},
+		},
+		{
+			name:  "different counts",
+			stats: []db.ListFeedbackHourlyStatsRow{stat(1, 3, 1), stat(2, 0, 4)},
+			raw:   []db.CountFeedbackByHourRow{raw(1, 3, 1), raw(2, 1, 4)},
+			expected: []Mismatch{
+				{Hour: hour(2), RollupNegative: 4, FeedbackPositive: 1, FeedbackNegative: 4},
+			},
+		},
+		{
+			name:  "hour missing in the rollup",
+			stats: []db.ListFeedbackHourlyStatsRow{stat(3, 1, 0)},
+			raw:   []db.CountFeedbackByHourRow{raw(1, 2, 0), raw(3, 1, 0)},
+			expected: []Mismatch{
+				{Hour: hour(1), FeedbackPositive: 2},
+			},
+		},
+		{
+			name:  "hour without raw feedback",
+			stats: []db.ListFeedbackHourlyStatsRow{stat(1, 2, 0), stat(5, 0, 1)},
+			raw:   []db.CountFeedbackByHourRow{raw(1, 2, 0)},
+			expected: []Mismatch{
+				{Hour: hour(5), RollupNegative: 1},
+			},
+		},
+		{
+			name:  "ordered by hour",
+			stats: []db.ListFeedbackHourlyStatsRow{stat(4, 1, 0)},
+			raw:   []db.CountFeedbackByHourRow{raw(2, 1, 0), raw(6, 1, 0)},
+			expected: []Mismatch{
+				{Hour: hour(2), FeedbackPositive: 1},
+				{Hour: hour(4), RollupPositive: 1},
+				{Hour: hour(6), FeedbackPositive: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mismatches := compare(tt.stats, tt.raw)
+			if !reflect.DeepEqual(mismatches, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, mismatches)
+			}
+		})
+	}
+}
+
+func TestWithoutDeleted(t *testing.T) {
+	mismatches := []Mismatch{
+		{Hour: hour(1), RollupPositive: 3, FeedbackPositive: 2},
+		// The raw feedback of the hour was deleted
+		{Hour: hour(2), RollupPositive: 5, RollupNegative: 1},
+		{Hour: hour(3), FeedbackNegative: 1},
+	}
+
+	kept, deleted := withoutDeleted(mismatches)
+
+	expected := []Mismatch{
+		{Hour: hour(1), RollupPositive: 3, FeedbackPositive: 2},
+		{Hour: hour(3), FeedbackNegative: 1},
+	}
+	if !reflect.DeepEqual(kept, expected) || deleted != 1 {
+		t.Errorf("Expected %+v and 1 deleted hour, got %+v and %d", expected, kept, deleted)
+	}
+
+	if kept, deleted := withoutDeleted([]Mismatch{{Hour: hour(2), RollupPositive: 5}}); kept != nil || deleted != 1 {
+		t.Errorf("Expected no mismatches and 1 deleted hour, got %+v and %d", kept, deleted)
+	}
+}
+
+func TestRollup_SettledUntil(t *testing.T) {
+	tests := []struct {
+		now      time.Time
+		expected time.Time
+	}{
+		{now: hour(10).Add(4 * time.Minute), expected: hour(9)},
+		{now: hour(10).Add(5 * time.Minute), expected: hour(10)},
+		{now: hour(10).Add(59 * time.Minute), expected: hour(10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.now.Format(time.RFC3339), func(t *testing.T) {
+			r := New(Config{})
+			r.now = func() time.Time { return tt.now }
+
+			if until := r.settledUntil(); !until.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, until)
+			}
+		})
+	}
+}
+
+func TestNew_Defaults(t *testing.T) {
+	r := New(Config{Chunk: 90 * time.Minute})
+	if r.settle != DefaultSettle {
+		t.Errorf("Expected settle %s, got %s", DefaultSettle, r.settle)
+	}
+
+	// Chunks are whole hours, rolled up ranges always end on a full hour
+	if r.chunk != time.Hour {
+		t.Errorf("Expected chunk of 1h, got %s", r.chunk)
+	}
+}
@@ -0,0 +1,128 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSchedule_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+	}{
+		{name: "empty", expr: ""},
+		{name: "too few fields", expr: "0 1 * *"},
+		{name: "too many fields", expr: "0 0 1 * * *"},
+		{name: "minute out of range", expr: "60 * * * *"},
+		{name: "hour out of range", expr: "0 24 * * *"},
+		{name: "day of month zero", expr: "0 0 0 * *"},
+		{name: "unknown month name", expr: "0 0 1 foo *"},
+		{name: "inverted range", expr: "0 5-1 * * *"},
+		{name: "zero step", expr: "*/0 * * * *"},
+		{name: "invalid step", expr: "*/x * * * *"},
+		{name: "never runs", expr: "0 0 30 2 *"},
+		{name: "unknown macro", expr: "@fortnightly"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseSchedule(tt.expr); err == nil {
+				t.Errorf("Expected error for %q, got nil", tt.expr)
+			}
+		})
+	}
+}
+
+func TestSchedule_Next(t *testing.T) {
+	// Saturday
+	from := time.Date(2024, time.June, 15, 10, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		expr     string
+		from     time.Time
+		expected time.Time
+	}{
+		{
+			expr:     "0 1 * * *",
+			expected: time.Date(2024, time.June, 16, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			expr:     "@daily",
+			expected: time.Date(2024, time.June, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			expr:     "@hourly",
+			expected: time.Date(2024, time.June, 15, 11, 0, 0, 0, time.UTC),
+		},
+		{
+			expr:     "* * * * *",
+			expected: time.Date(2024, time.June, 15, 10, 31, 0, 0, time.UTC),
+		},
+		{
+			expr:     "*/15 * * * *",
+			expected: time.Date(2024, time.June, 15, 10, 45, 0, 0, time.UTC),
+		},
+		{
+			expr:     "5/20 * * * *",
+			expected: time.Date(2024, time.June, 15, 10, 45, 0, 0, time.UTC),
+		},
+		{
+			expr:     "0 9-17/4 * * *",
+			expected: time.Date(2024, time.June, 15, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			expr:     "0,30 8,10 * * *",
+			expected: time.Date(2024, time.June, 16, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			expr:     "0 1 * * mon-fri",
+			expected: time.Date(2024, time.June, 17, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			// Sunday written as 7
+			expr:     "0 1 * * 7",
+			expected: time.Date(2024, time.June, 16, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			expr:     "0 0 1 jan *",
+			expected: time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// Day of month and day of week both restricted: either matches (the 20th is a Thursday)
+			expr:     "0 0 20 * 1",
+			expected: time.Date(2024, time.June, 17, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			expr:     "0 0 29 2 *",
+			expected: time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			expr:     "0 0 31 * *",
+			expected: time.Date(2024, time.July, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			// An exact match is skipped, the next run is strictly after the given time
+			expr:     "0 1 * * *",
+			from:     time.Date(2024, time.June, 15, 1, 0, 0, 0, time.UTC),
+			expected: time.Date(2024, time.June, 16, 1, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseSchedule failed: %v", err)
+			}
+
+			start := from
+			if !tt.from.IsZero() {
+				start = tt.from
+			}
+
+			if next := schedule.Next(start); !next.Equal(tt.expected) {
+				t.Errorf("Expected next run %v, got %v", tt.expected, next)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+// Package scheduler runs a job on a cron schedule in a long-lived process.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultShutdownTimeout is how long a running job may continue after a shutdown was requested
+const DefaultShutdownTimeout = 30 * time.Second
+
+// Job is the work executed on every scheduled run
+type Job func(ctx context.Context) error
+
+// Config holds the configuration for the scheduler
+type Config struct {
+	Schedule *Schedule
+	Job      Job
+
+	// Jitter delays every run by a random duration up to the given value
+	Jitter time.Duration
+
+	// ShutdownTimeout is how long a running job may continue after a shutdown was requested
+	// Defaults to DefaultShutdownTimeout when zero
+	ShutdownTimeout time.Duration
+}
+
+// Status describes the state of the scheduler
+type Status struct {
+	StartedAt   time.Time  `json:"started_at"`
+	Running     bool       `json:"running"`
+	NextRun     *time.Time `json:"next_run,omitempty"`
+	LastRun     *time.Time `json:"last_run,omitempty"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	Runs        int        `json:"runs"`
+	Failures    int        `json:"failures"`
+}
+
+// Scheduler runs a job on a cron schedule until its context is canceled
+type Scheduler struct {
+	schedule        *Schedule
+	job             Job
+	jitter          time.Duration
+	shutdownTimeout time.Duration
+	now             func() time.Time
+
+	mu     sync.Mutex
+	status Status
+}
+
+// New creates a new scheduler instance
+func New(cfg Config) *Scheduler {
+	shutdownTimeout := cfg.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = DefaultShutdownTimeout
+	}
+
+	return &Scheduler{
+		schedule:        cfg.Schedule,
+		job:             cfg.Job,
+		jitter:          cfg.Jitter,
+		shutdownTimeout: shutdownTimeout,
+		now:             time.Now,
+	}
+}
+
+// Run executes the job on every scheduled time until ctx is canceled.
+// Failed runs are logged and recorded in the status, the next run happens as scheduled.
+// On cancellation a running job gets up to the shutdown timeout to finish.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	s.status.StartedAt = s.now().UTC()
+	s.mu.Unlock()
+
+	for {
+		next := s.nextRun()
+		if next.IsZero() {
+			return fmt.Errorf("schedule %q has no next run", s.schedule)
+		}
+
+		slog.Info("Next run scheduled",
+			"schedule", s.schedule.String(),
+			"next_run", next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			slog.Info("Scheduler stopped")
+
+			return nil
+		case <-timer.C:
+		}
+
+		// Don't start a run when the shutdown raced with the timer
+		if ctx.Err() != nil {
+			slog.Info("Scheduler stopped")
+
+			return nil
+		}
+
+		s.runJob(ctx)
+	}
+}
+
+// nextRun computes the next run time including jitter and records it in the status
+func (s *Scheduler) nextRun() time.Time {
+	next := s.schedule.Next(s.now().UTC())
+	if next.IsZero() {
+		return next
+	}
+
+	if s.jitter > 0 {
+		next = next.Add(rand.N(s.jitter)) // #nosec G404 -- jitter doesn't need a secure random source
+	}
+
+	s.mu.Lock()
+	s.status.NextRun = &next
+	s.mu.Unlock()
+
+	return next
+}
+
+// runJob executes the job once and records the result.
+// The job isn't canceled together with ctx but only after the shutdown timeout.
+func (s *Scheduler) runJob(ctx context.Context) {
+	started := s.now().UTC()
+
+	s.mu.Lock()
+	s.status.Running = true
+	s.status.NextRun = nil
+	s.status.LastRun = &started
+	s.mu.Unlock()
+
+	jobCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-ctx.Done():
+		}
+
+		slog.Info("Shutdown requested, waiting for the running job to finish",
+			"timeout", s.shutdownTimeout)
+
+		timer := time.NewTimer(s.shutdownTimeout)
+		defer timer.Stop()
+
+		select {
+		case <-done:
+		case <-timer.C:
+			slog.Warn("Running job didn't finish within the shutdown timeout, canceling it")
+			cancel()
+		}
+	}()
+
+	slog.Info("Starting scheduled run")
+
+	err := s.job(jobCtx)
+	finished := s.now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status.Running = false
+	s.status.Runs++
+
+	if err != nil {
+		s.status.Failures++
+		s.status.LastError = err.Error()
+
+		slog.Error("Scheduled run failed",
+			"duration", finished.Sub(started),
+			"error", err)
+
+		return
+	}
+
+	s.status.LastSuccess = &finished
+	s.status.LastError = ""
+
+	slog.Info("Scheduled run completed",
+		"duration", finished.Sub(started))
+}
+
+// Status returns a snapshot of the scheduler state
+func (s *Scheduler) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.status
+}
+
+// Handler serves the liveness check on /health and the scheduler status as JSON on /status
+func (s *Scheduler) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if _, err := fmt.Fprintf(w, "OK\n"); err != nil {
+			slog.Warn("Failed to write health check response", "error", err)
+		}
+	})
+
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(s.Status()); err != nil {
+			slog.Warn("Failed to write status response", "error", err)
+		}
+	})
+
+	return mux
+}
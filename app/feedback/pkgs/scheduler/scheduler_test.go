@@ -0,0 +1,173 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestScheduler creates a daily scheduler running the job with a fixed clock
+func newTestScheduler(t *testing.T, job Job, cfg Config) *Scheduler {
+	t.Helper()
+
+	schedule, err := ParseSchedule("0 1 * * *")
+	if err != nil {
+		t.Fatalf("ParseSchedule failed: %v", err)
+	}
+
+	cfg.Schedule = schedule
+	cfg.Job = job
+
+	s := New(cfg)
+	s.now = func() time.Time {
+		return time.Date(2024, time.June, 15, 10, 0, 0, 0, time.UTC)
+	}
+
+	return s
+}
+
+func TestScheduler_RunJob_Status(t *testing.T) {
+	fail := true
+	s := newTestScheduler(t, func(ctx context.Context) error {
+		if fail {
+			return errors.New("database unavailable")
+		}
+
+		return nil
+	}, Config{})
+
+	s.runJob(context.Background())
+
+	status := s.Status()
+	if status.Runs != 1 || status.Failures != 1 || status.LastError != "database unavailable" {
+		t.Errorf("Expected 1 failed run, got %+v", status)
+	}
+
+	if status.LastRun == nil || status.LastSuccess != nil {
+		t.Errorf("Expected last run without last success, got %+v", status)
+	}
+
+	fail = false
+	s.runJob(context.Background())
+
+	status = s.Status()
+	if status.Runs != 2 || status.Failures != 1 || status.LastError != "" || status.LastSuccess == nil {
+		t.Errorf("Expected a successful second run, got %+v", status)
+	}
+
+	if status.Running {
+		t.Error("Expected no running job")
+	}
+}
+
+func TestScheduler_NextRun_Jitter(t *testing.T) {
+	s := newTestScheduler(t, nil, Config{Jitter: 10 * time.Minute})
+	scheduled := time.Date(2024, time.June, 16, 1, 0, 0, 0, time.UTC)
+
+	for range 100 {
+		next := s.nextRun()
+		if next.Before(scheduled) || !next.Before(scheduled.Add(10*time.Minute)) {
+			t.Fatalf("Expected next run within 10 minutes after %v, got %v", scheduled, next)
+		}
+	}
+
+	if status := s.Status(); status.NextRun == nil {
+		t.Error("Expected next run in status")
+	}
+}
+
+func TestScheduler_Run_StopsOnCancel(t *testing.T) {
+	ran := false
+	s := newTestScheduler(t, func(ctx context.Context) error {
+		ran = true
+
+		return nil
+	}, Config{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := s.Run(ctx); err != nil {
+		t.Errorf("Expected no error on shutdown, got %v", err)
+	}
+
+	if ran {
+		t.Error("Expected no run after shutdown")
+	}
+}
+
+func TestScheduler_RunJob_GracefulShutdown(t *testing.T) {
+	t.Run("running job finishes", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		s := newTestScheduler(t, func(jobCtx context.Context) error {
+			cancel()
+			time.Sleep(20 * time.Millisecond)
+
+			// The shutdown doesn't cancel the job within the timeout
+			return jobCtx.Err()
+		}, Config{ShutdownTimeout: time.Second})
+
+		s.runJob(ctx)
+
+		if status := s.Status(); status.LastSuccess == nil {
+			t.Errorf("Expected the job to finish, got %+v", status)
+		}
+	})
+
+	t.Run("job is canceled after the timeout", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		s := newTestScheduler(t, func(jobCtx context.Context) error {
+			cancel()
+
+			select {
+			case <-jobCtx.Done():
+				return jobCtx.Err()
+			case <-time.After(5 * time.Second):
+				return nil
+			}
+		}, Config{ShutdownTimeout: 10 * time.Millisecond})
+
+		s.runJob(ctx)
+
+		if status := s.Status(); status.LastError != context.Canceled.Error() {
+			t.Errorf("Expected the job to be canceled, got %+v", status)
+		}
+	})
+}
+
+func TestScheduler_Handler(t *testing.T) {
+	s := newTestScheduler(t, func(ctx context.Context) error { return nil }, Config{})
+	s.runJob(context.Background())
+
+	handler := s.Handler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected status 200 from /health, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+
+	if contentType := rec.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", contentType)
+	}
+
+	var status Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to parse status: %v", err)
+	}
+
+	expected := time.Date(2024, time.June, 15, 10, 0, 0, 0, time.UTC)
+	if status.Runs != 1 || status.LastSuccess == nil || !status.LastSuccess.Equal(expected) {
+		t.Errorf("Expected 1 run with last success %v, got %+v", expected, status)
+	}
+}
@@ -0,0 +1,236 @@
+// Package search provides full-text search over feedback messages.
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// DefaultConfig is the text search configuration of the indexed feedback.message_search column
+	DefaultConfig = "english"
+
+	// DefaultLimit is the number of results returned when no limit is given
+	DefaultLimit = 50
+
+	// MaxLimit is the maximum number of results of a search
+	MaxLimit = 500
+
+	// MaxQueryLength is the maximum length of a search query in characters
+	MaxQueryLength = 500
+)
+
+var (
+	// ErrInvalidParams is returned by Search for invalid search parameters
+	ErrInvalidParams = errors.New("invalid search parameters")
+
+	// ErrUnknownConfig is returned by Search when the text search configuration isn't installed
+	ErrUnknownConfig = errors.New("unknown text search configuration")
+)
+
+// Params holds the search query and its filters
+type Params struct {
+	// Query uses the websearch syntax: words, "quoted phrases", OR and -excluded words
+	Query string
+
+	// Config is the text search configuration of the query's language (e.g., german)
+	// Defaults to the searcher's configuration when empty
+	Config string
+
+	// Sentiment limits the results to positive or negative feedback, empty for both
+	Sentiment string
+
+	// From and To limit the results to feedback created in [From, To), zero for no limit
+	From time.Time
+	To   time.Time
+
+	// Limit is the maximum number of results, defaults to DefaultLimit when zero
+	Limit int
+}
+
+// Result is a matching feedback message
+type Result struct {
+	ID        int32     `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	Sentiment string    `json:"sentiment"`
+	Rank      float32   `json:"rank"`
+
+	// Snippet holds fragments of the message with matched words wrapped in **
+	Snippet string `json:"snippet"`
+}
+
+// Searcher runs full-text searches over feedback messages
+type Searcher struct {
+	queries *db.Queries
+	config  string
+}
+
+// New creates a searcher using config for queries without a configuration, DefaultConfig when empty
+func New(pool *pgxpool.Pool, config string) *Searcher {
+	if config == "" {
+		config = DefaultConfig
+	}
+
+	return &Searcher{
+		queries: db.New(pool),
+		config:  config,
+	}
+}
+
+// Search returns the feedback matching the query, best matches first.
+// Only the DefaultConfig search uses the GIN index, other configurations parse every message in the date range.
+func (s *Searcher) Search(ctx context.Context, params Params) ([]Result, error) {
+	params.Query = strings.TrimSpace(params.Query)
+
+	if params.Config == "" {
+		params.Config = s.config
+	}
+
+	if params.Limit == 0 {
+		params.Limit = DefaultLimit
+	}
+
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	var (
+		rows []db.SearchFeedbackRow
+		err  error
+	)
+
+	if params.Config == DefaultConfig {
+		rows, err = s.searchIndexed(ctx, params)
+	} else {
+		rows, err = s.searchWithConfig(ctx, params)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(rows))
+	for _, row := range rows {
+		results = append(results, Result{
+			ID:        row.ID,
+			CreatedAt: row.CreatedAt.Time.UTC(),
+			Sentiment: string(row.Sentiment),
+			Rank:      row.Rank,
+			Snippet:   row.Snippet,
+		})
+	}
+
+	return results, nil
+}
+
+// searchIndexed searches the indexed message_search column
+func (s *Searcher) searchIndexed(ctx context.Context, params Params) ([]db.SearchFeedbackRow, error) {
+	rows, err := s.queries.SearchFeedback(ctx, db.SearchFeedbackParams{
+		Query:      params.Query,
+		Sentiment:  sentimentParam(params.Sentiment),
+		RangeStart: timeParam(params.From),
+		RangeEnd:   timeParam(params.To),
+		MaxResults: int32(params.Limit), // #nosec G115 - limited to MaxLimit
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search feedback: %w", err)
+	}
+
+	return rows, nil
+}
+
+// searchWithConfig searches with a configuration other than the one of the indexed column
+func (s *Searcher) searchWithConfig(ctx context.Context, params Params) ([]db.SearchFeedbackRow, error) {
+	exists, err := s.queries.TextSearchConfigExists(ctx, params.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check text search configuration: %w", err)
+	}
+
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownConfig, params.Config)
+	}
+
+	rows, err := s.queries.SearchFeedbackWithConfig(ctx, db.SearchFeedbackWithConfigParams{
+		Config:     params.Config,
+		Query:      params.Query,
+		Sentiment:  sentimentParam(params.Sentiment),
+		RangeStart: timeParam(params.From),
+		RangeEnd:   timeParam(params.To),
+		MaxResults: int32(params.Limit), // #nosec G115 - limited to MaxLimit
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search feedback: %w", err)
+	}
+
+	// Both queries return the same columns
+	converted := make([]db.SearchFeedbackRow, 0, len(rows))
+	for _, row := range rows {
+		converted = append(converted, db.SearchFeedbackRow(row))
+	}
+
+	return converted, nil
+}
+
+// validate checks the search parameters after the defaults were applied
+func (p Params) validate() error {
+	if p.Query == "" {
+		return fmt.Errorf("%w: query must not be empty", ErrInvalidParams)
+	}
+
+	if len([]rune(p.Query)) > MaxQueryLength {
+		return fmt.Errorf("%w: query is longer than %d characters", ErrInvalidParams, MaxQueryLength)
+	}
+
+	if p.Sentiment != "" && p.Sentiment != "positive" && p.Sentiment != "negative" {
+		return fmt.Errorf("%w: sentiment must be positive or negative, got %q", ErrInvalidParams, p.Sentiment)
+	}
+
+	if !p.From.IsZero() && !p.To.IsZero() && !p.From.Before(p.To) {
+		return fmt.Errorf("%w: from must be before to", ErrInvalidParams)
+	}
+
+	if p.Limit < 1 || p.Limit > MaxLimit {
+		return fmt.Errorf("%w: limit must be between 1 and %d, got %d", ErrInvalidParams, MaxLimit, p.Limit)
+	}
+
+	return nil
+}
+
+// ParseTime parses a date (2006-01-02, midnight UTC) or an RFC 3339 timestamp, empty returns zero
+func ParseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: expected a date (2006-01-02) or RFC 3339 time, got %q",
+			ErrInvalidParams, value)
+	}
+
+	return t, nil
+}
+
+// sentimentParam converts an optional sentiment filter
+func sentimentParam(sentiment string) db.NullSentimentType {
+	return db.NullSentimentType{
+		SentimentType: db.SentimentType(sentiment),
+		Valid:         sentiment != "",
+	}
+}
+
+// timeParam converts an optional time filter
+func timeParam(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: !t.IsZero()}
+}
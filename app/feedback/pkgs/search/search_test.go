@@ -0,0 +1,99 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParams_Validate(t *testing.T) {
+	day := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		params  Params
+		wantErr bool
+	}{
+		{name: "query only", params: Params{Query: "bookmarks", Limit: 10}},
+		{
+			name: "all filters",
+			params: Params{
+				Query:     `"sync bookmarks" -firefox`,
+				Sentiment: "negative",
+				From:      day,
+				To:        day.AddDate(0, 0, 7),
+				Limit:     MaxLimit,
+			},
+		},
+		{name: "empty query", params: Params{Limit: 10}, wantErr: true},
+		{name: "query too long", params: Params{Query: strings.Repeat("a", MaxQueryLength+1), Limit: 10}, wantErr: true},
+		{name: "unknown sentiment", params: Params{Query: "a", Sentiment: "neutral", Limit: 10}, wantErr: true},
+		{name: "from after to", params: Params{Query: "a", From: day, To: day, Limit: 10}, wantErr: true},
+		{name: "limit too high", params: Params{Query: "a", Limit: MaxLimit + 1}, wantErr: true},
+		{name: "negative limit", params: Params{Query: "a", Limit: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.params.validate()
+			if tt.wantErr && !errors.Is(err, ErrInvalidParams) {
+				t.Errorf("Expected ErrInvalidParams, got %v", err)
+			}
+
+			if !tt.wantErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestSearcher_SearchRejectsInvalidParams(t *testing.T) {
+	s := New(nil, "")
+
+	// Validated before the database is queried, the query is trimmed first
+	if _, err := s.Search(context.Background(), Params{Query: "   "}); !errors.Is(err, ErrInvalidParams) {
+		t.Errorf("Expected ErrInvalidParams, got %v", err)
+	}
+
+	if s.config != DefaultConfig {
+		t.Errorf("Expected config %q, got %q", DefaultConfig, s.config)
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Time
+		wantErr  bool
+	}{
+		{input: ""},
+		{input: "2026-03-01", expected: time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)},
+		{input: "2026-03-01T12:30:00Z", expected: time.Date(2026, time.March, 1, 12, 30, 0, 0, time.UTC)},
+		{input: "2026-03-01T14:30:00+02:00", expected: time.Date(2026, time.March, 1, 12, 30, 0, 0, time.UTC)},
+		{input: "03/01/2026", wantErr: true},
+		{input: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			parsed, err := ParseTime(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidParams) {
+					t.Errorf("Expected ErrInvalidParams, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if !parsed.Equal(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, parsed)
+			}
+		})
+	}
+}
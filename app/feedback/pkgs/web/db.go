@@ -9,7 +9,7 @@ import (
 )
 
 // dbSaveFeedback saves feedback to the database
-func (s *Server) dbSaveFeedback(ctx context.Context, sentiment, message string) (*db.Feedback, error) {
+func (s *Server) dbSaveFeedback(ctx context.Context, sentiment, message string) (*db.CreateFeedbackRow, error) {
 	slog.Debug("Saving feedback to database")
 
 	// Convert sentiment to enum type
@@ -0,0 +1,122 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// notifyChannel is the channel notified by the feedback_notify_insert trigger
+	notifyChannel = "feedback_inserted"
+
+	listenInitialBackoff = 1 * time.Second
+	listenMaxBackoff     = 30 * time.Second
+)
+
+// listen receives feedback notifications on a dedicated connection and publishes them to the broker until
+// ctx is cancelled. A dropped connection is reopened with exponential backoff, notifications sent while
+// disconnected are lost.
+func (s *Server) listen(ctx context.Context) {
+	attempt := 0
+
+	for {
+		received, err := s.listenOnce(ctx)
+		if ctx.Err() != nil {
+			slog.Debug("Feedback listener stopped")
+
+			return
+		}
+
+		// The connection worked, start the backoff over
+		if received {
+			attempt = 0
+		}
+
+		delay := listenBackoff(attempt)
+		attempt++
+
+		slog.Warn("Feedback listener disconnected, reconnecting",
+			"error", err,
+			"delay", delay)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+	}
+}
+
+// listenOnce connects, listens and publishes notifications until the connection fails or ctx is cancelled.
+// Returns true if the connection was established and listening.
+func (s *Server) listenOnce(ctx context.Context) (bool, error) {
+	// Why not a pooled connection: LISTEN is bound to the session, which must outlive any request
+	conn, err := pgx.ConnectConfig(ctx, s.pool.Config().ConnConfig.Copy())
+	if err != nil {
+		return false, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer func() {
+		closeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := conn.Close(closeCtx); err != nil {
+			slog.Debug("Failed to close listener connection", "error", err)
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{notifyChannel}.Sanitize()); err != nil {
+		return false, fmt.Errorf("failed to listen on %s: %w", notifyChannel, err)
+	}
+
+	slog.Info("Listening for new feedback", "channel", notifyChannel)
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return true, fmt.Errorf("failed to wait for notification: %w", err)
+		}
+
+		event, err := parseNotification(notification.Payload)
+		if err != nil {
+			slog.Warn("Ignoring invalid feedback notification", "payload", notification.Payload, "error", err)
+
+			continue
+		}
+
+		s.broker.Publish(event)
+	}
+}
+
+// parseNotification decodes the payload sent by the feedback_notify_insert trigger
+func parseNotification(payload string) (StreamEvent, error) {
+	var event StreamEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return StreamEvent{}, fmt.Errorf("failed to decode payload: %w", err)
+	}
+
+	if event.ID <= 0 || (event.Sentiment != "positive" && event.Sentiment != "negative") {
+		return StreamEvent{}, errors.New("missing id or sentiment")
+	}
+
+	return event, nil
+}
+
+// listenBackoff returns the delay before the next connection attempt using exponential backoff with
+// "equal jitter": half of the delay is fixed, the other half is random
+func listenBackoff(attempt int) time.Duration {
+	delay := listenInitialBackoff << min(attempt, 16)
+	if delay > listenMaxBackoff {
+		delay = listenMaxBackoff
+	}
+
+	half := delay / 2
+
+	return half + rand.N(half+1) // #nosec G404 - jitter doesn't need a secure random source
+}
@@ -0,0 +1,102 @@
+package web
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/search"
+)
+
+// searchResponse is the JSON response of /api/v1/search
+type searchResponse struct {
+	Results []search.Result `json:"results"`
+}
+
+// handleSearch runs a full-text search over the feedback messages, requires the search token
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	// The messages must never be exposed to the public visitors of the feedback form
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.searchToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeJSONError(w, http.StatusUnauthorized, "missing or invalid token")
+
+		return
+	}
+
+	params, err := searchParams(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	results, err := s.searcher.Search(r.Context(), params)
+	if errors.Is(err, search.ErrInvalidParams) || errors.Is(err, search.ErrUnknownConfig) {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+
+		return
+	}
+
+	if err != nil {
+		slog.Error("Failed to search feedback", "error", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal server error")
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(searchResponse{Results: results}); err != nil {
+		slog.Warn("Failed to write search response", "error", err)
+	}
+}
+
+// searchParams reads the search parameters from the query string
+func searchParams(r *http.Request) (search.Params, error) {
+	query := r.URL.Query()
+
+	params := search.Params{
+		Query:     query.Get("q"),
+		Config:    query.Get("config"),
+		Sentiment: query.Get("sentiment"),
+	}
+
+	var err error
+
+	if params.From, err = search.ParseTime(query.Get("from")); err != nil {
+		return search.Params{}, err
+	}
+
+	if params.To, err = search.ParseTime(query.Get("to")); err != nil {
+		return search.Params{}, err
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		if params.Limit, err = strconv.Atoi(limit); err != nil {
+			return search.Params{}, errors.New("limit must be a number")
+		}
+	}
+
+	return params, nil
+}
+
+// writeJSONError writes an error response as {"error": message}
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(map[string]string{"error": message}); err != nil {
+		slog.Warn("Failed to write error response", "error", err)
+	}
+}
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/findmyname666/ddg3/feedback/pkgs/search"
+)
+
+func TestHandleSearch_Rejects(t *testing.T) {
+	s := &Server{
+		searcher:    search.New(nil, ""),
+		searchToken: "secret",
+	}
+
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		token    string
+		expected int
+	}{
+		{name: "missing token", url: "/api/v1/search?q=bookmarks", expected: http.StatusUnauthorized},
+		{name: "wrong token", url: "/api/v1/search?q=bookmarks", token: "guess", expected: http.StatusUnauthorized},
+		{
+			name:     "wrong method",
+			method:   http.MethodPost,
+			url:      "/api/v1/search?q=bookmarks",
+			token:    "secret",
+			expected: http.StatusMethodNotAllowed,
+		},
+		{name: "missing query", url: "/api/v1/search", token: "secret", expected: http.StatusBadRequest},
+		{
+			name:     "invalid limit",
+			url:      "/api/v1/search?q=bookmarks&limit=all",
+			token:    "secret",
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid date",
+			url:      "/api/v1/search?q=bookmarks&from=yesterday",
+			token:    "secret",
+			expected: http.StatusBadRequest,
+		},
+		{
+			name:     "invalid sentiment",
+			url:      "/api/v1/search?q=bookmarks&sentiment=neutral",
+			token:    "secret",
+			expected: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			req := httptest.NewRequest(method, tt.url, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			rec := httptest.NewRecorder()
+			s.handleSearch(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d: %s", tt.expected, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
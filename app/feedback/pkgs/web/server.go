@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/findmyname666/ddg3/feedback/pkgs/db"
+	"github.com/findmyname666/ddg3/feedback/pkgs/search"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -24,6 +25,9 @@ type Server struct {
 	server           *http.Server
 	staticPath       string
 	maxMessageLength int
+	broker           *broker
+	searcher         *search.Searcher
+	searchToken      string
 }
 
 // Config holds the configuration for the web server
@@ -33,6 +37,14 @@ type Config struct {
 	Pool             *pgxpool.Pool
 	StaticPath       string
 	MaxMessageLength int
+
+	// StreamMaxSubscribers limits the concurrent /api/v1/stream subscribers, 0 disables the stream
+	StreamMaxSubscribers int
+
+	// SearchToken is the bearer token required by /api/v1/search, empty disables the search
+	SearchToken string
+	// SearchConfig is the default text search configuration of searches, defaults to search.DefaultConfig
+	SearchConfig string
 }
 
 // secureFileSystem wraps http.Dir to prevent directory traversal and hidden file access
@@ -77,6 +89,11 @@ func NewServer(cfg Config) (*Server, error) {
 		return nil, fmt.Errorf("failed to initialize templates: %w", err)
 	}
 
+	var b *broker
+	if cfg.StreamMaxSubscribers > 0 {
+		b = newBroker(cfg.StreamMaxSubscribers)
+	}
+
 	return &Server{
 		host:             cfg.Host,
 		port:             cfg.Port,
@@ -84,6 +101,9 @@ func NewServer(cfg Config) (*Server, error) {
 		queries:          db.New(cfg.Pool),
 		staticPath:       cfg.StaticPath,
 		maxMessageLength: cfg.MaxMessageLength,
+		broker:           b,
+		searcher:         search.New(cfg.Pool, cfg.SearchConfig),
+		searchToken:      cfg.SearchToken,
 	}, nil
 }
 
@@ -104,6 +124,18 @@ func (s *Server) Start(ctx context.Context) error {
 	mux.HandleFunc("/submit", s.handleFeedbackSubmit)
 	mux.HandleFunc("/thanks", s.handleThanks)
 
+	// Handle the live feedback stream, fed by the listener until ctx is cancelled
+	if s.broker != nil {
+		mux.HandleFunc("/api/v1/stream", s.handleStream)
+
+		go s.listen(ctx)
+	}
+
+	// Handle the feedback search, only with a token as it exposes the messages
+	if s.searchToken != "" {
+		mux.HandleFunc("/api/v1/search", s.handleSearch)
+	}
+
 	// Handle health check
 	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -120,6 +152,11 @@ func (s *Server) Start(ctx context.Context) error {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Disconnect stream subscribers, the shutdown waits for their requests otherwise
+	if s.broker != nil {
+		s.server.RegisterOnShutdown(s.broker.Close)
+	}
+
 	slog.Info("Starting HTTP server", "addr", s.server.Addr)
 
 	// Start server in a goroutine
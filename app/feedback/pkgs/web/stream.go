@@ -0,0 +1,186 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultStreamMaxSubscribers is the default number of concurrent /api/v1/stream subscribers
+	DefaultStreamMaxSubscribers = 100
+
+	// streamBuffer is the number of events buffered per subscriber, events for a full buffer are dropped
+	streamBuffer = 64
+
+	// streamHeartbeat keeps idle streams open through proxies
+	streamHeartbeat = 15 * time.Second
+
+	// streamRetry is the reconnect delay sent to EventSource clients
+	streamRetry = 5 * time.Second
+)
+
+// ErrTooManySubscribers is returned by Subscribe when the subscriber limit is reached
+var ErrTooManySubscribers = errors.New("too many stream subscribers")
+
+// StreamEvent is a new feedback entry announced to stream subscribers
+type StreamEvent struct {
+	ID        int64  `json:"id"`
+	Sentiment string `json:"sentiment"`
+}
+
+// broker fans out stream events to a limited number of subscribers
+type broker struct {
+	mu          sync.Mutex
+	subscribers map[chan StreamEvent]struct{}
+	limit       int
+	closed      bool
+}
+
+// newBroker creates a broker accepting up to limit subscribers
+func newBroker(limit int) *broker {
+	return &broker{
+		subscribers: make(map[chan StreamEvent]struct{}),
+		limit:       limit,
+	}
+}
+
+// Subscribe registers a subscriber, the channel is closed by unsubscribe or when the broker is closed
+func (b *broker) Subscribe() (<-chan StreamEvent, func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed || len(b.subscribers) >= b.limit {
+		return nil, nil, ErrTooManySubscribers
+	}
+
+	ch := make(chan StreamEvent, streamBuffer)
+	b.subscribers[ch] = struct{}{}
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe, nil
+}
+
+// Publish sends the event to all subscribers without blocking, slow subscribers miss the event
+func (b *broker) Publish(event StreamEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			slog.Debug("Stream subscriber is too slow, dropping event", "id", event.ID)
+		}
+	}
+}
+
+// Close disconnects all subscribers and rejects new ones
+func (b *broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closed = true
+
+	for ch := range b.subscribers {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+// handleStream streams new feedback as Server-Sent Events
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	events, unsubscribe, err := s.broker.Subscribe()
+	if err != nil {
+		slog.Warn("Rejecting stream subscriber", "error", err)
+		w.Header().Set("Retry-After", fmt.Sprintf("%.0f", streamRetry.Seconds()))
+		http.Error(w, "Too many subscribers", http.StatusServiceUnavailable)
+
+		return
+	}
+	defer unsubscribe()
+
+	// The stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		slog.Error("Failed to disable write deadline for stream", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds()); err != nil {
+		return
+	}
+
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	slog.Debug("Stream subscriber connected", "remote_addr", r.RemoteAddr)
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			slog.Debug("Stream subscriber disconnected", "remote_addr", r.RemoteAddr)
+
+			return
+		case event, ok := <-events:
+			// Server shutdown
+			if !ok {
+				return
+			}
+
+			if err := writeStreamEvent(w, event); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// writeStreamEvent writes a feedback event in the SSE format
+func writeStreamEvent(w http.ResponseWriter, event StreamEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: feedback\ndata: %s\n\n", event.ID, data)
+
+	return err
+}
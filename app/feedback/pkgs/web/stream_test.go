@@ -0,0 +1,196 @@
+package web
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBroker_SubscriberLimit(t *testing.T) {
+	b := newBroker(2)
+
+	_, unsubscribe, err := b.Subscribe()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, _, err := b.Subscribe(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, _, err := b.Subscribe(); !errors.Is(err, ErrTooManySubscribers) {
+		t.Errorf("Expected ErrTooManySubscribers, got %v", err)
+	}
+
+	// Unsubscribing frees a slot, a second call is a no-op
+	unsubscribe()
+	unsubscribe()
+
+	if _, _, err := b.Subscribe(); err != nil {
+		t.Errorf("Expected a free slot after unsubscribe, got %v", err)
+	}
+}
+
+func TestBroker_PublishAndClose(t *testing.T) {
+	b := newBroker(10)
+
+	events, _, err := b.Subscribe()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A full buffer drops events instead of blocking the listener
+	for i := range streamBuffer + 5 {
+		b.Publish(StreamEvent{ID: int64(i + 1), Sentiment: "positive"})
+	}
+
+	if event := <-events; event.ID != 1 {
+		t.Errorf("Expected event 1, got %d", event.ID)
+	}
+
+	b.Close()
+
+	// The buffered events are still delivered before the channel is closed
+	received := 0
+	for range events {
+		received++
+	}
+
+	if received != streamBuffer-1 {
+		t.Errorf("Expected %d events after close, got %d", streamBuffer-1, received)
+	}
+
+	if _, _, err := b.Subscribe(); err == nil {
+		t.Error("Expected error when subscribing to a closed broker")
+	}
+}
+
+func TestParseNotification(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected StreamEvent
+		wantErr  bool
+	}{
+		{
+			name:     "positive",
+			payload:  `{"id" : 42, "sentiment" : "positive"}`,
+			expected: StreamEvent{ID: 42, Sentiment: "positive"},
+		},
+		{
+			name:     "negative",
+			payload:  `{"id" : 7, "sentiment" : "negative"}`,
+			expected: StreamEvent{ID: 7, Sentiment: "negative"},
+		},
+		{name: "invalid json", payload: `{"id":`, wantErr: true},
+		{name: "missing id", payload: `{"sentiment" : "negative"}`, wantErr: true},
+		{name: "unknown sentiment", payload: `{"id" : 1, "sentiment" : "neutral"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := parseNotification(tt.payload)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", event)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			if event != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, event)
+			}
+		})
+	}
+}
+
+func TestListenBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{attempt: 0, min: 500 * time.Millisecond, max: time.Second},
+		{attempt: 3, min: 4 * time.Second, max: 8 * time.Second},
+		{attempt: 10, min: listenMaxBackoff / 2, max: listenMaxBackoff},
+		{attempt: 100, min: listenMaxBackoff / 2, max: listenMaxBackoff},
+	}
+
+	for _, tt := range tests {
+		delay := listenBackoff(tt.attempt)
+		if delay < tt.min || delay > tt.max {
+			t.Errorf("Attempt %d: expected delay in [%s, %s], got %s", tt.attempt, tt.min, tt.max, delay)
+		}
+	}
+}
+
+func TestHandleStream(t *testing.T) {
+	s := &Server{broker: newBroker(1)}
+
+	ts := httptest.NewServer(http.HandlerFunc(s.handleStream))
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL) // #nosec G107 - test server URL
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			t.Errorf("Failed to close response body: %v", err)
+		}
+	}()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected text/event-stream, got %q", ct)
+	}
+
+	// The only slot is taken
+	rejected, err := http.Get(ts.URL) // #nosec G107 - test server URL
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := rejected.Body.Close(); err != nil {
+		t.Errorf("Failed to close response body: %v", err)
+	}
+
+	if rejected.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d, got %d", http.StatusServiceUnavailable, rejected.StatusCode)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+
+	// Skip the retry line, the subscriber is registered once the headers are sent
+	if _, err := reader.ReadString('\n'); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	s.broker.Publish(StreamEvent{ID: 42, Sentiment: "negative"})
+
+	var lines []string
+
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	expected := []string{"id: 42", "event: feedback", `data: {"id":42,"sentiment":"negative"}`}
+	if strings.Join(lines, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected %q, got %q", expected, lines)
+	}
+}
@@ -79,6 +79,16 @@ data "template_cloudinit_config" "this" {
       analysis_timer = indent(6, templatefile("${path.module}/templates/analysis.timer.tftpl", {
         app_name = var.app_name
       }))
+
+      # Systemd partition maintenance service
+      partitions_service = indent(6, templatefile("${path.module}/templates/partitions.service.tftpl", {
+        app_name = var.app_name
+      }))
+
+      # Systemd partition maintenance timer
+      partitions_timer = indent(6, templatefile("${path.module}/templates/partitions.timer.tftpl", {
+        app_name = var.app_name
+      }))
     })
   }
 }
@@ -231,6 +231,11 @@ systemctl enable --now "${app_name}-analysis.timer"
 
 log_success "Analysis timer configured (will start on boot, runs every 4 hours)"
 
+log_info "Enabling ${app_name}-partitions.timer..."
+systemctl enable --now "${app_name}-partitions.timer"
+
+log_success "Partitions timer configured (will start on boot, runs daily)"
+
 # ============================================================================
 # 8. Run Provision Script as Ubuntu User
 # ============================================================================
@@ -66,6 +66,8 @@ WEB_APP_PASSWORD=$(echo "$DB_PASSWORDS_JSON" | \
   jq -r '.web_app_password')
 ANALYSIS_APP_PASSWORD=$(echo "$DB_PASSWORDS_JSON" | \
   jq -r '.analysis_app_password')
+RETENTION_APP_PASSWORD=$(echo "$DB_PASSWORDS_JSON" | \
+  jq -r '.retention_app_password')
 
 log_success "All secrets retrieved successfully"
 
@@ -82,12 +84,14 @@ cat > "$APP_DIR/.env" <<EOF
 DB_USER_MIGRATION_NAME='migration_user'
 DB_USER_WEB_NAME='web_app'
 DB_USER_ANALYSIS_NAME='feedback_analysis_app'
+DB_USER_RETENTION_NAME='feedback_retention_app'
 
 # Database Passwords (wrapped in single quotes to prevent $ interpolation)
 DB_USER_POSTGRES_PASSWORD='$POSTGRES_PASSWORD'
 DB_USER_MIGRATION_PASSWORD='$MIGRATION_PASSWORD'
 DB_USER_WEB_PASSWORD='$WEB_APP_PASSWORD'
 DB_USER_ANALYSIS_PASSWORD='$ANALYSIS_APP_PASSWORD'
+DB_USER_RETENTION_PASSWORD='$RETENTION_APP_PASSWORD'
 
 # Asana Configuration (wrapped in single quotes to prevent $ interpolation)
 ASANA_TOKEN='$ASANA_TOKEN'
@@ -21,6 +21,11 @@ ephemeral "random_password" "analysis_app_password" {
   special = true
 }
 
+ephemeral "random_password" "retention_app_password" {
+  length  = 32
+  special = true
+}
+
 # Store Asana credentials in a separate secret (updated by Terraform)
 resource "azurerm_key_vault_secret" "asana_credentials" {
   name         = "${var.app_name}-asana-credentials"
@@ -43,10 +48,11 @@ resource "azurerm_key_vault_secret" "database_passwords" {
   key_vault_id = var.key_vault_id
 
   value_wo = jsonencode({
-    postgres_password     = ephemeral.random_password.postgres_password.result
-    migration_password    = ephemeral.random_password.migration_password.result
-    web_app_password      = ephemeral.random_password.web_app_password.result
-    analysis_app_password = ephemeral.random_password.analysis_app_password.result
+    postgres_password      = ephemeral.random_password.postgres_password.result
+    migration_password     = ephemeral.random_password.migration_password.result
+    web_app_password       = ephemeral.random_password.web_app_password.result
+    analysis_app_password  = ephemeral.random_password.analysis_app_password.result
+    retention_app_password = ephemeral.random_password.retention_app_password.result
   })
 
   value_wo_version = 1